@@ -1,6 +1,8 @@
 package acl
 
 import (
+	"context"
+	"fmt"
 	"sync"
 
 	"github.com/uhppoted/uhppote-core/types"
@@ -8,6 +10,12 @@ import (
 )
 
 func GetACL(u uhppote.IUHPPOTE, devices []uhppote.Device) (ACL, []error) {
+	return GetACLWithContext(context.Background(), u, devices)
+}
+
+// Retrieves the cards from the devices, abandoning any outstanding requests if the context
+// is cancelled. Cancellation errors wrap the context error.
+func GetACLWithContext(ctx context.Context, u uhppote.IUHPPOTE, devices []uhppote.Device) (ACL, []error) {
 	acl := sync.Map{}
 	errors := []error{}
 	guard := sync.RWMutex{}
@@ -22,7 +30,7 @@ func GetACL(u uhppote.IUHPPOTE, devices []uhppote.Device) (ACL, []error) {
 		device := d
 		wg.Add(1)
 		go func() {
-			if cards, err := getACL(u, device.DeviceID); err != nil {
+			if cards, err := getACL(ctx, u, device.DeviceID); err != nil {
 				guard.Lock()
 				errors = append(errors, err)
 				guard.Unlock()
@@ -45,9 +53,13 @@ func GetACL(u uhppote.IUHPPOTE, devices []uhppote.Device) (ACL, []error) {
 	return a, errors
 }
 
-func getACL(u uhppote.IUHPPOTE, deviceID uint32) (map[uint32]types.Card, error) {
+func getACL(ctx context.Context, u uhppote.IUHPPOTE, deviceID uint32) (map[uint32]types.Card, error) {
 	cards := map[uint32]types.Card{}
 
+	if err := cancelled(ctx, deviceID); err != nil {
+		return cards, err
+	}
+
 	N, err := u.GetCards(deviceID)
 	if err != nil {
 		return cards, err
@@ -55,6 +67,10 @@ func getACL(u uhppote.IUHPPOTE, deviceID uint32) (map[uint32]types.Card, error)
 
 	var index uint32 = 1
 	for count := 0; count < int(N); {
+		if err := cancelled(ctx, deviceID); err != nil {
+			return nil, err
+		}
+
 		card, err := u.GetCardByIndex(deviceID, index)
 		if err != nil {
 			return nil, err
@@ -70,3 +86,11 @@ func getACL(u uhppote.IUHPPOTE, deviceID uint32) (map[uint32]types.Card, error)
 
 	return cards, nil
 }
+
+func cancelled(ctx context.Context, deviceID uint32) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%v: %w", deviceID, err)
+	}
+
+	return nil
+}
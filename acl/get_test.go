@@ -1,6 +1,8 @@
 package acl
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -267,3 +269,34 @@ func TestGetACLWithDeviceError(t *testing.T) {
 		}
 	}
 }
+
+func TestGetACLWithCancelledContext(t *testing.T) {
+	requests := 0
+
+	u := mock{
+		getCards: func(deviceID uint32) (uint32, error) {
+			requests++
+			return 3, nil
+		},
+		getCardByIndex: func(deviceID, index uint32) (*types.Card, error) {
+			requests++
+			return nil, nil
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := GetACLWithContext(ctx, &u, []uhppote.Device{deviceA})
+	if len(err) != 1 {
+		t.Fatalf("Expected 'cancelled' error, got %v", err)
+	}
+
+	if !errors.Is(err[0], context.Canceled) {
+		t.Errorf("Expected 'context cancelled' error, got %v", err[0])
+	}
+
+	if requests != 0 {
+		t.Errorf("Expected no device requests after context cancelled, got %v", requests)
+	}
+}
@@ -1,6 +1,7 @@
 package acl
 
 import (
+	"context"
 	"fmt"
 	"sync"
 
@@ -9,6 +10,12 @@ import (
 )
 
 func PutACL(u uhppote.IUHPPOTE, acl ACL, dryrun bool) (map[uint32]Report, []error) {
+	return PutACLWithContext(context.Background(), u, acl, dryrun)
+}
+
+// Updates the devices to match the ACL, stopping as soon as the context is cancelled. The
+// report for a cancelled device includes the changes applied before the cancellation.
+func PutACLWithContext(ctx context.Context, u uhppote.IUHPPOTE, acl ACL, dryrun bool) (map[uint32]Report, []error) {
 	report := sync.Map{}
 	errors := []error{}
 	guard := sync.RWMutex{}
@@ -37,9 +44,9 @@ func PutACL(u uhppote.IUHPPOTE, acl ACL, dryrun bool) (map[uint32]Report, []erro
 			var err error
 
 			if dryrun {
-				rpt, err = fakePutACL(u, id, cards)
+				rpt, err = fakePutACL(ctx, u, id, cards)
 			} else {
-				rpt, err = putACL(u, id, cards)
+				rpt, err = putACL(ctx, u, id, cards)
 			}
 
 			if rpt != nil {
@@ -67,8 +74,8 @@ func PutACL(u uhppote.IUHPPOTE, acl ACL, dryrun bool) (map[uint32]Report, []erro
 	return r, errors
 }
 
-func putACL(u uhppote.IUHPPOTE, deviceID uint32, cards map[uint32]types.Card) (*Report, error) {
-	current, err := getACL(u, deviceID)
+func putACL(ctx context.Context, u uhppote.IUHPPOTE, deviceID uint32, cards map[uint32]types.Card) (*Report, error) {
+	current, err := getACL(ctx, u, deviceID)
 	if err != nil {
 		return nil, err
 	}
@@ -90,7 +97,11 @@ func putACL(u uhppote.IUHPPOTE, deviceID uint32, cards map[uint32]types.Card) (*
 	}
 
 	for _, card := range diff.Updated {
-		if err := validate(u, deviceID, card); err != nil {
+		if err := cancelled(ctx, deviceID); err != nil {
+			return &report, err
+		}
+
+		if err := validate(ctx, u, deviceID, card); err != nil {
 			report.Errored = append(report.Errored, card.CardNumber)
 			report.Errors = append(report.Errors, err)
 		} else {
@@ -106,7 +117,11 @@ func putACL(u uhppote.IUHPPOTE, deviceID uint32, cards map[uint32]types.Card) (*
 	}
 
 	for _, card := range diff.Added {
-		if err := validate(u, deviceID, card); err != nil {
+		if err := cancelled(ctx, deviceID); err != nil {
+			return &report, err
+		}
+
+		if err := validate(ctx, u, deviceID, card); err != nil {
 			report.Errored = append(report.Errored, card.CardNumber)
 			report.Errors = append(report.Errors, err)
 		} else {
@@ -122,6 +137,10 @@ func putACL(u uhppote.IUHPPOTE, deviceID uint32, cards map[uint32]types.Card) (*
 	}
 
 	for _, card := range diff.Deleted {
+		if err := cancelled(ctx, deviceID); err != nil {
+			return &report, err
+		}
+
 		if ok, err := u.DeleteCard(deviceID, card.CardNumber); err != nil {
 			report.Errored = append(report.Errored, card.CardNumber)
 			report.Errors = append(report.Errors, err)
@@ -135,8 +154,8 @@ func putACL(u uhppote.IUHPPOTE, deviceID uint32, cards map[uint32]types.Card) (*
 	return &report, nil
 }
 
-func fakePutACL(u uhppote.IUHPPOTE, deviceID uint32, cards map[uint32]types.Card) (*Report, error) {
-	current, err := getACL(u, deviceID)
+func fakePutACL(ctx context.Context, u uhppote.IUHPPOTE, deviceID uint32, cards map[uint32]types.Card) (*Report, error) {
+	current, err := getACL(ctx, u, deviceID)
 	if err != nil {
 		return nil, err
 	}
@@ -172,10 +191,12 @@ func fakePutACL(u uhppote.IUHPPOTE, deviceID uint32, cards map[uint32]types.Card
 	return &report, nil
 }
 
-func validate(u uhppote.IUHPPOTE, deviceID uint32, card types.Card) error {
+func validate(ctx context.Context, u uhppote.IUHPPOTE, deviceID uint32, card types.Card) error {
 	for _, door := range []uint8{1, 2, 3, 4} {
 		if v, ok := card.Doors[door]; ok && v >= 2 && v <= 254 {
-			if profile, err := u.GetTimeProfile(deviceID, uint8(v)); err != nil {
+			if err := cancelled(ctx, deviceID); err != nil {
+				return err
+			} else if profile, err := u.GetTimeProfile(deviceID, uint8(v)); err != nil {
 				return err
 			} else if profile == nil {
 				return fmt.Errorf("Time profile %v is not defined for %v", v, deviceID)
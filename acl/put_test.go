@@ -2,11 +2,14 @@ package acl
 
 import (
 	"fmt"
+	"net"
 	"reflect"
 	"testing"
 	"time"
 
 	"github.com/uhppoted/uhppote-core/types"
+	"github.com/uhppoted/uhppote-core/uhppote"
+	"github.com/uhppoted/uhppoted-api/simulator"
 )
 
 func TestPutACL(t *testing.T) {
@@ -995,3 +998,61 @@ func TestPutACLWithNoCurrentPermissions(t *testing.T) {
 		t.Errorf("Returned report does not match expected:\n    expected:%+v\n    got:     %+v", report, rpt)
 	}
 }
+
+func TestPutACLWithSimulator(t *testing.T) {
+	acl := ACL{
+		12345: map[uint32]types.Card{
+			65536: types.Card{CardNumber: 65536, From: date("2020-03-04"), To: date("2020-12-31"), Doors: map[uint8]int{1: 1, 2: 0, 3: 1, 4: 0}},
+			65537: types.Card{CardNumber: 65537, From: date("2020-01-02"), To: date("2020-10-31"), Doors: map[uint8]int{1: 1, 2: 0, 3: 0, 4: 0}},
+			65538: types.Card{CardNumber: 65538, From: date("2020-02-03"), To: date("2020-12-31"), Doors: map[uint8]int{1: 0, 2: 0, 3: 0, 4: 0}},
+		},
+	}
+
+	expected := ACL{
+		12345: map[uint32]types.Card{
+			65536: types.Card{CardNumber: 65536, From: date("2020-03-04"), To: date("2020-12-31"), Doors: map[uint8]int{1: 1, 2: 0, 3: 1, 4: 0}},
+			65537: types.Card{CardNumber: 65537, From: date("2020-01-02"), To: date("2020-10-31"), Doors: map[uint8]int{1: 1, 2: 0, 3: 0, 4: 0}},
+			65538: types.Card{CardNumber: 65538, From: date("2020-02-03"), To: date("2020-11-30"), Doors: map[uint8]int{1: 1, 2: 0, 3: 0, 4: 1}},
+		},
+	}
+
+	report := map[uint32]Report{
+		12345: Report{
+			Unchanged: []uint32{65537},
+			Updated:   []uint32{},
+			Added:     []uint32{65536},
+			Deleted:   []uint32{65539},
+			Failed:    []uint32{65538},
+			Errored:   []uint32{},
+			Errors:    []error{},
+		},
+	}
+
+	device := simulator.NewDevice(12345, net.IPv4(192, 168, 1, 100))
+	device.Cards = []types.Card{
+		types.Card{CardNumber: 65537, From: date("2020-01-02"), To: date("2020-10-31"), Doors: map[uint8]int{1: 1, 2: 0, 3: 0, 4: 0}},
+		types.Card{CardNumber: 65538, From: date("2020-02-03"), To: date("2020-11-30"), Doors: map[uint8]int{1: 1, 2: 0, 3: 0, 4: 1}},
+		types.Card{CardNumber: 65539, From: date("2020-03-04"), To: date("2020-12-31"), Doors: map[uint8]int{1: 0, 2: 0, 3: 0, 4: 0}},
+	}
+
+	s := simulator.NewSimulator(nil, device)
+	s.Inject(simulator.Fault{Type: simulator.Rejected, Operation: "put-card", Count: 1})
+
+	rpt, err := PutACL(s, acl, false)
+	if len(err) > 0 {
+		t.Fatalf("Unexpected error putting ACL: %v", err)
+	}
+
+	if !reflect.DeepEqual(rpt, report) {
+		t.Errorf("Returned report does not match expected:\n    expected:%+v\n    got:     %+v", report, rpt)
+	}
+
+	acl, err = GetACL(s, []uhppote.Device{deviceA})
+	if len(err) > 0 {
+		t.Fatalf("Unexpected error getting ACL: %v", err)
+	}
+
+	if !reflect.DeepEqual(acl, expected) {
+		t.Errorf("Device card list not updated correctly:\n    expected:%+v\n    got:     %+v", expected, acl)
+	}
+}
@@ -0,0 +1,252 @@
+package cache
+
+import (
+	"sync"
+	"time"
+
+	"github.com/uhppoted/uhppote-core/types"
+	"github.com/uhppoted/uhppote-core/uhppote"
+	"github.com/uhppoted/uhppoted-api/uhppoted"
+)
+
+// Cache wraps an IUHPPOTE with a per-device read-through cache for time profiles and card
+// records, e.g. to avoid re-fetching the same time profiles for every card in an ACL update.
+// Cached entries are invalidated by writes made through the Cache (put-card, delete-card,
+// delete-cards, set-time-profile and clear-time-profiles) and optionally expire after the TTL.
+// Changes made to a controller by anything else are not visible until the entries expire or
+// the cache is flushed.
+type Cache struct {
+	uhppote uhppote.IUHPPOTE
+	ttl     time.Duration
+	guard   *sync.Mutex
+	devices map[uint32]*device
+	stats   *Stats
+}
+
+type Stats struct {
+	Hits   uint64 `json:"hits"`
+	Misses uint64 `json:"misses"`
+}
+
+type device struct {
+	generation uint64
+	profiles   map[uint8]entry
+	cards      map[uint32]entry
+}
+
+type entry struct {
+	value   interface{}
+	expires time.Time
+}
+
+// Creates a Cache for the IUHPPOTE. A zero TTL caches entries until they are invalidated or
+// flushed.
+func NewCache(u uhppote.IUHPPOTE, ttl time.Duration) *Cache {
+	return &Cache{
+		uhppote: u,
+		ttl:     ttl,
+		guard:   &sync.Mutex{},
+		devices: map[uint32]*device{},
+		stats:   &Stats{},
+	}
+}
+
+// Passes retry tracking through to the wrapped IUHPPOTE (if it is a uhppoted.RetryTracker).
+// The tracked Cache shares the cached entries and statistics of this Cache.
+func (c *Cache) Track() uhppoted.TrackedUHPPOTE {
+	if t, ok := c.uhppote.(uhppoted.RetryTracker); ok {
+		tracked := t.Track()
+		v := *c
+		v.uhppote = tracked
+
+		return uhppoted.Tracked(&v, tracked.Retries)
+	}
+
+	return uhppoted.Tracked(c, uhppoted.Untracked)
+}
+
+// Discards all cached entries.
+func (c *Cache) Flush() {
+	c.guard.Lock()
+	defer c.guard.Unlock()
+
+	for _, d := range c.devices {
+		d.generation++
+		d.profiles = map[uint8]entry{}
+		d.cards = map[uint32]entry{}
+	}
+}
+
+// Discards the cached entries for a single device.
+func (c *Cache) FlushDevice(deviceID uint32) {
+	c.guard.Lock()
+	defer c.guard.Unlock()
+
+	d := c.device(deviceID)
+	d.generation++
+	d.profiles = map[uint8]entry{}
+	d.cards = map[uint32]entry{}
+}
+
+// Returns the cache hit and miss counts.
+func (c *Cache) Stats() Stats {
+	c.guard.Lock()
+	defer c.guard.Unlock()
+
+	return *c.stats
+}
+
+func (c *Cache) getTimeProfile(deviceID uint32, profileID uint8) (*types.TimeProfile, error) {
+	c.guard.Lock()
+	d := c.device(deviceID)
+	if e, ok := d.profiles[profileID]; ok && !c.expired(e) {
+		c.stats.Hits++
+		c.guard.Unlock()
+
+		return copyProfile(e.value.(*types.TimeProfile)), nil
+	}
+
+	c.stats.Misses++
+	generation := d.generation
+	c.guard.Unlock()
+
+	profile, err := c.uhppote.GetTimeProfile(deviceID, profileID)
+	if err != nil {
+		return nil, err
+	}
+
+	c.guard.Lock()
+	if d.generation == generation {
+		d.profiles[profileID] = c.entry(copyProfile(profile))
+	}
+	c.guard.Unlock()
+
+	return profile, nil
+}
+
+func (c *Cache) getCardByID(deviceID, cardNumber uint32) (*types.Card, error) {
+	c.guard.Lock()
+	d := c.device(deviceID)
+	if e, ok := d.cards[cardNumber]; ok && !c.expired(e) {
+		c.stats.Hits++
+		c.guard.Unlock()
+
+		return copyCard(e.value.(*types.Card)), nil
+	}
+
+	c.stats.Misses++
+	generation := d.generation
+	c.guard.Unlock()
+
+	card, err := c.uhppote.GetCardByID(deviceID, cardNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	c.guard.Lock()
+	if d.generation == generation {
+		d.cards[cardNumber] = c.entry(copyCard(card))
+	}
+	c.guard.Unlock()
+
+	return card, nil
+}
+
+// Invalidates the cached entries selected by 'f' for a device. The device generation is
+// incremented so that a read that was in flight when the entries were invalidated does
+// not cache a stale value.
+func (c *Cache) invalidate(deviceID uint32, f func(d *device)) {
+	c.guard.Lock()
+	defer c.guard.Unlock()
+
+	d := c.device(deviceID)
+	d.generation++
+
+	f(d)
+}
+
+// NOTE: expects the caller to hold the cache lock
+func (c *Cache) device(deviceID uint32) *device {
+	d, ok := c.devices[deviceID]
+	if !ok {
+		d = &device{
+			profiles: map[uint8]entry{},
+			cards:    map[uint32]entry{},
+		}
+
+		c.devices[deviceID] = d
+	}
+
+	return d
+}
+
+func (c *Cache) entry(v interface{}) entry {
+	e := entry{
+		value: v,
+	}
+
+	if c.ttl > 0 {
+		e.expires = time.Now().Add(c.ttl)
+	}
+
+	return e
+}
+
+func (c *Cache) expired(e entry) bool {
+	return !e.expires.IsZero() && time.Now().After(e.expires)
+}
+
+func copyProfile(profile *types.TimeProfile) *types.TimeProfile {
+	if profile == nil {
+		return nil
+	}
+
+	p := *profile
+	p.From = copyDate(profile.From)
+	p.To = copyDate(profile.To)
+
+	if profile.Weekdays != nil {
+		p.Weekdays = types.Weekdays{}
+		for k, v := range profile.Weekdays {
+			p.Weekdays[k] = v
+		}
+	}
+
+	if profile.Segments != nil {
+		p.Segments = types.Segments{}
+		for k, v := range profile.Segments {
+			p.Segments[k] = v
+		}
+	}
+
+	return &p
+}
+
+func copyCard(card *types.Card) *types.Card {
+	if card == nil {
+		return nil
+	}
+
+	c := *card
+	c.From = copyDate(card.From)
+	c.To = copyDate(card.To)
+
+	if card.Doors != nil {
+		c.Doors = map[uint8]int{}
+		for k, v := range card.Doors {
+			c.Doors[k] = v
+		}
+	}
+
+	return &c
+}
+
+func copyDate(date *types.Date) *types.Date {
+	if date == nil {
+		return nil
+	}
+
+	d := *date
+
+	return &d
+}
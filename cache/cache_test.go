@@ -0,0 +1,119 @@
+package cache
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/uhppoted/uhppote-core/types"
+	"github.com/uhppoted/uhppote-core/uhppote"
+	"github.com/uhppoted/uhppoted-api/simulator"
+)
+
+var _ uhppote.IUHPPOTE = &Cache{}
+
+func TestCachedTimeProfile(t *testing.T) {
+	s := simulator.NewSimulator(nil, simulator.NewDevice(405419896, net.IPv4(192, 168, 1, 125)))
+	s.SetTimeProfile(405419896, types.TimeProfile{ID: 29, LinkedProfileID: 3})
+
+	c := NewCache(s, 0)
+
+	for i := 0; i < 3; i++ {
+		if profile, err := c.GetTimeProfile(405419896, 29); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		} else if profile == nil || profile.LinkedProfileID != 3 {
+			t.Fatalf("Incorrect time profile - got:%v", profile)
+		}
+	}
+
+	if profile, _ := c.GetTimeProfile(405419896, 30); profile != nil {
+		t.Errorf("Expected undefined time profile, got:%v", profile)
+	}
+
+	c.GetTimeProfile(405419896, 30)
+
+	expected := Stats{Hits: 3, Misses: 2}
+	if stats := c.Stats(); stats != expected {
+		t.Errorf("Incorrect stats - expected:%+v, got:%+v", expected, stats)
+	}
+
+	// ... invalidated by set-time-profile
+	c.SetTimeProfile(405419896, types.TimeProfile{ID: 29, LinkedProfileID: 5})
+
+	if profile, _ := c.GetTimeProfile(405419896, 29); profile == nil || profile.LinkedProfileID != 5 {
+		t.Errorf("Time profile not invalidated - got:%v", profile)
+	}
+
+	// ... not invalidated by an update made elsewhere until flushed
+	s.SetTimeProfile(405419896, types.TimeProfile{ID: 29, LinkedProfileID: 7})
+
+	if profile, _ := c.GetTimeProfile(405419896, 29); profile == nil || profile.LinkedProfileID != 5 {
+		t.Errorf("Expected cached time profile - got:%v", profile)
+	}
+
+	c.FlushDevice(405419896)
+
+	if profile, _ := c.GetTimeProfile(405419896, 29); profile == nil || profile.LinkedProfileID != 7 {
+		t.Errorf("Time profile not flushed - got:%v", profile)
+	}
+}
+
+func TestCachedCard(t *testing.T) {
+	s := simulator.NewSimulator(nil, simulator.NewDevice(405419896, net.IPv4(192, 168, 1, 125)))
+	s.PutCard(405419896, types.Card{CardNumber: 8165538, Doors: map[uint8]int{1: 1, 2: 0, 3: 0, 4: 0}})
+
+	c := NewCache(s, 0)
+
+	card, err := c.GetCardByID(405419896, 8165538)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	} else if card == nil {
+		t.Fatalf("Expected card, got:%v", card)
+	}
+
+	// ... returned records are copies
+	card.Doors[1] = 0
+
+	if card, _ := c.GetCardByID(405419896, 8165538); card == nil || card.Doors[1] != 1 {
+		t.Errorf("Cached card modified by caller - got:%v", card)
+	}
+
+	c.DeleteCard(405419896, 8165538)
+
+	if card, _ := c.GetCardByID(405419896, 8165538); card != nil {
+		t.Errorf("Card not invalidated by delete-card - got:%v", card)
+	}
+
+	c.PutCard(405419896, types.Card{CardNumber: 8165538, Doors: map[uint8]int{1: 0, 2: 1, 3: 0, 4: 0}})
+
+	if card, _ := c.GetCardByID(405419896, 8165538); card == nil || card.Doors[2] != 1 {
+		t.Errorf("Card not invalidated by put-card - got:%v", card)
+	}
+
+	c.DeleteCards(405419896)
+
+	if card, _ := c.GetCardByID(405419896, 8165538); card != nil {
+		t.Errorf("Card not invalidated by delete-cards - got:%v", card)
+	}
+}
+
+func TestCacheTTL(t *testing.T) {
+	s := simulator.NewSimulator(nil, simulator.NewDevice(405419896, net.IPv4(192, 168, 1, 125)))
+	s.SetTimeProfile(405419896, types.TimeProfile{ID: 29, LinkedProfileID: 3})
+
+	c := NewCache(s, 10*time.Millisecond)
+
+	c.GetTimeProfile(405419896, 29)
+	s.SetTimeProfile(405419896, types.TimeProfile{ID: 29, LinkedProfileID: 5})
+
+	time.Sleep(20 * time.Millisecond)
+
+	if profile, _ := c.GetTimeProfile(405419896, 29); profile == nil || profile.LinkedProfileID != 5 {
+		t.Errorf("Time profile not expired - got:%v", profile)
+	}
+
+	expected := Stats{Hits: 0, Misses: 2}
+	if stats := c.Stats(); stats != expected {
+		t.Errorf("Incorrect stats - expected:%+v, got:%+v", expected, stats)
+	}
+}
@@ -0,0 +1,128 @@
+package cache
+
+import (
+	"net"
+	"os"
+	"time"
+
+	"github.com/uhppoted/uhppote-core/types"
+	"github.com/uhppoted/uhppote-core/uhppote"
+)
+
+func (c *Cache) DeviceList() map[uint32]uhppote.Device {
+	return c.uhppote.DeviceList()
+}
+
+func (c *Cache) ListenAddr() *net.UDPAddr {
+	return c.uhppote.ListenAddr()
+}
+
+func (c *Cache) GetDevices() ([]types.Device, error) {
+	return c.uhppote.GetDevices()
+}
+
+func (c *Cache) GetDevice(deviceID uint32) (*types.Device, error) {
+	return c.uhppote.GetDevice(deviceID)
+}
+
+func (c *Cache) SetAddress(deviceID uint32, address, mask, gateway net.IP) (*types.Result, error) {
+	return c.uhppote.SetAddress(deviceID, address, mask, gateway)
+}
+
+func (c *Cache) GetTime(deviceID uint32) (*types.Time, error) {
+	return c.uhppote.GetTime(deviceID)
+}
+
+func (c *Cache) SetTime(deviceID uint32, datetime time.Time) (*types.Time, error) {
+	return c.uhppote.SetTime(deviceID, datetime)
+}
+
+func (c *Cache) GetDoorControlState(deviceID uint32, door byte) (*types.DoorControlState, error) {
+	return c.uhppote.GetDoorControlState(deviceID, door)
+}
+
+func (c *Cache) SetDoorControlState(deviceID uint32, door uint8, state uint8, delay uint8) (*types.DoorControlState, error) {
+	return c.uhppote.SetDoorControlState(deviceID, door, state, delay)
+}
+
+func (c *Cache) GetListener(deviceID uint32) (*types.Listener, error) {
+	return c.uhppote.GetListener(deviceID)
+}
+
+func (c *Cache) SetListener(deviceID uint32, address net.UDPAddr) (*types.Result, error) {
+	return c.uhppote.SetListener(deviceID, address)
+}
+
+func (c *Cache) GetStatus(deviceID uint32) (*types.Status, error) {
+	return c.uhppote.GetStatus(deviceID)
+}
+
+func (c *Cache) GetCards(deviceID uint32) (uint32, error) {
+	return c.uhppote.GetCards(deviceID)
+}
+
+func (c *Cache) GetCardByIndex(deviceID, index uint32) (*types.Card, error) {
+	return c.uhppote.GetCardByIndex(deviceID, index)
+}
+
+func (c *Cache) GetCardByID(deviceID, cardNumber uint32) (*types.Card, error) {
+	return c.getCardByID(deviceID, cardNumber)
+}
+
+func (c *Cache) PutCard(deviceID uint32, card types.Card) (bool, error) {
+	defer c.invalidate(deviceID, func(d *device) { delete(d.cards, card.CardNumber) })
+
+	return c.uhppote.PutCard(deviceID, card)
+}
+
+func (c *Cache) DeleteCard(deviceID uint32, cardNumber uint32) (bool, error) {
+	defer c.invalidate(deviceID, func(d *device) { delete(d.cards, cardNumber) })
+
+	return c.uhppote.DeleteCard(deviceID, cardNumber)
+}
+
+func (c *Cache) DeleteCards(deviceID uint32) (bool, error) {
+	defer c.invalidate(deviceID, func(d *device) { d.cards = map[uint32]entry{} })
+
+	return c.uhppote.DeleteCards(deviceID)
+}
+
+func (c *Cache) GetTimeProfile(deviceID uint32, profileID uint8) (*types.TimeProfile, error) {
+	return c.getTimeProfile(deviceID, profileID)
+}
+
+func (c *Cache) SetTimeProfile(deviceID uint32, profile types.TimeProfile) (bool, error) {
+	defer c.invalidate(deviceID, func(d *device) { delete(d.profiles, profile.ID) })
+
+	return c.uhppote.SetTimeProfile(deviceID, profile)
+}
+
+func (c *Cache) ClearTimeProfiles(deviceID uint32) (bool, error) {
+	defer c.invalidate(deviceID, func(d *device) { d.profiles = map[uint8]entry{} })
+
+	return c.uhppote.ClearTimeProfiles(deviceID)
+}
+
+func (c *Cache) RecordSpecialEvents(deviceID uint32, enable bool) (bool, error) {
+	return c.uhppote.RecordSpecialEvents(deviceID, enable)
+}
+
+func (c *Cache) GetEvent(deviceID, index uint32) (*types.Event, error) {
+	return c.uhppote.GetEvent(deviceID, index)
+}
+
+func (c *Cache) GetEventIndex(deviceID uint32) (*types.EventIndex, error) {
+	return c.uhppote.GetEventIndex(deviceID)
+}
+
+func (c *Cache) SetEventIndex(deviceID, index uint32) (*types.EventIndexResult, error) {
+	return c.uhppote.SetEventIndex(deviceID, index)
+}
+
+func (c *Cache) Listen(listener uhppote.Listener, q chan os.Signal) error {
+	return c.uhppote.Listen(listener, q)
+}
+
+func (c *Cache) OpenDoor(deviceID uint32, door uint8) (*types.Result, error) {
+	return c.uhppote.OpenDoor(deviceID, door)
+}
@@ -25,6 +25,7 @@ type Device struct {
 	Rollover uint32
 	Doors    []string
 	TimeZone string
+	Retry    *Retry
 }
 
 type kv struct {
@@ -62,7 +63,10 @@ UT0311-L0x.{{$id}}.door.1 = {{index $device.Doors 0}}
 UT0311-L0x.{{$id}}.door.2 = {{index $device.Doors 1}}
 UT0311-L0x.{{$id}}.door.3 = {{index $device.Doors 2}}
 UT0311-L0x.{{$id}}.door.4 = {{index $device.Doors 3}}
-UT0311-L0x.{{$id}}.timezone = {{$device.TimeZone}}
+UT0311-L0x.{{$id}}.timezone = {{$device.TimeZone}}{{if $device.Retry}}
+UT0311-L0x.{{$id}}.retry.attempts = {{$device.Retry.Attempts}}
+UT0311-L0x.{{$id}}.retry.backoff = {{$device.Retry.Backoff}}
+UT0311-L0x.{{$id}}.retry.jitter = {{$device.Retry.Jitter}}{{end}}
 {{else}}
 # Example configuration for UTO311-L04 with serial number 405419896
 # UT0311-L0x.405419896.name = D405419896
@@ -135,6 +139,7 @@ type System struct {
 	HealthCheckIdle     time.Duration `conf:"monitoring.healthcheck.idle"`
 	HealthCheckIgnore   time.Duration `conf:"monitoring.healthcheck.ignore"`
 	WatchdogInterval    time.Duration `conf:"monitoring.watchdog.interval"`
+	Retry               Retry         `conf:"retry"`
 }
 
 const ROLLOVER = 100000
@@ -152,6 +157,7 @@ func NewConfig() *Config {
 			HealthCheckIdle:     monitoring.IDLE,
 			HealthCheckIgnore:   monitoring.IGNORE,
 			WatchdogInterval:    5 * time.Second,
+			Retry:               *NewRetry(),
 		},
 		REST:        *NewREST(),
 		MQTT:        *NewMQTT(),
@@ -366,6 +372,12 @@ func (f DeviceMap) MarshalConf(tag string) ([]byte, error) {
 			for d, door := range device.Doors {
 				fmt.Fprintf(&s, "UTO311-L0x.%d.door.%d = %s\n", id, d+1, door)
 			}
+
+			if device.Retry != nil {
+				fmt.Fprintf(&s, "UTO311-L0x.%d.retry.attempts = %d\n", id, device.Retry.Attempts)
+				fmt.Fprintf(&s, "UTO311-L0x.%d.retry.backoff = %v\n", id, device.Retry.Backoff)
+				fmt.Fprintf(&s, "UTO311-L0x.%d.retry.jitter = %v\n", id, device.Retry.Jitter)
+			}
 			fmt.Fprintf(&s, "\n")
 		}
 	}
@@ -385,6 +397,8 @@ func (f *DeviceMap) UnmarshalConf(tag string, values map[string]string) (interfa
 		return f, err
 	}
 
+	policy := systemRetry(values)
+
 	for key, value := range values {
 		match := re.FindStringSubmatch(key)
 		if len(match) > 1 {
@@ -443,6 +457,30 @@ func (f *DeviceMap) UnmarshalConf(tag string, values map[string]string) (interfa
 
 			case "timezone":
 				d.TimeZone = value
+
+			case "retry.attempts":
+				attempts, err := strconv.ParseUint(strings.TrimSpace(value), 10, 8)
+				if err != nil {
+					return f, fmt.Errorf("Device %v, invalid retry attempts '%s': %v", id, value, err)
+				} else {
+					d.retry(policy).Attempts = int(attempts)
+				}
+
+			case "retry.backoff":
+				backoff, err := time.ParseDuration(strings.TrimSpace(value))
+				if err != nil {
+					return f, fmt.Errorf("Device %v, invalid retry backoff '%s': %v", id, value, err)
+				} else {
+					d.retry(policy).Backoff = backoff
+				}
+
+			case "retry.jitter":
+				jitter, err := time.ParseDuration(strings.TrimSpace(value))
+				if err != nil {
+					return f, fmt.Errorf("Device %v, invalid retry jitter '%s': %v", id, value, err)
+				} else {
+					d.retry(policy).Jitter = jitter
+				}
 			}
 		}
 	}
@@ -450,6 +488,42 @@ func (f *DeviceMap) UnmarshalConf(tag string, values map[string]string) (interfa
 	return f, nil
 }
 
+// Returns the device retry policy, initialising it with the system policy if necessary so
+// that any 'retry.*' fields not set for the device are inherited from the system policy.
+func (d *Device) retry(policy Retry) *Retry {
+	if d.Retry == nil {
+		d.Retry = &policy
+	}
+
+	return d.Retry
+}
+
+// Returns the system 'retry.*' policy, with the default policy for any fields that are not
+// set (or are invalid, which is reported when the system policy is unmarshalled).
+func systemRetry(values map[string]string) Retry {
+	policy := *NewRetry()
+
+	if v, ok := values["retry.attempts"]; ok {
+		if attempts, err := strconv.ParseUint(strings.TrimSpace(v), 10, 8); err == nil {
+			policy.Attempts = int(attempts)
+		}
+	}
+
+	if v, ok := values["retry.backoff"]; ok {
+		if backoff, err := time.ParseDuration(strings.TrimSpace(v)); err == nil {
+			policy.Backoff = backoff
+		}
+	}
+
+	if v, ok := values["retry.jitter"]; ok {
+		if jitter, err := time.ParseDuration(strings.TrimSpace(v)); err == nil {
+			policy.Jitter = jitter
+		}
+	}
+
+	return policy
+}
+
 func resolve(v string) (*net.UDPAddr, error) {
 	address, err := net.ResolveUDPAddr("udp", v)
 	if err != nil {
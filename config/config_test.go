@@ -23,6 +23,10 @@ monitoring.healthcheck.idle = 67s
 monitoring.healthcheck.ignore = 97s
 monitoring.watchdog.interval = 23s
 
+retry.attempts = 5
+retry.backoff = 750ms
+retry.jitter = 50ms
+
 # MQTT
 mqtt.connection.broker = tls://127.0.0.63:8887
 mqtt.connection.client.ID = muppet
@@ -57,6 +61,8 @@ UT0311-L0x.405419896.door.2 = Side Door
 UT0311-L0x.405419896.door.3 = Garage
 UT0311-L0x.405419896.door.4 = Workshop
 UT0311-L0x.405419896.timezone = France/Paris
+UT0311-L0x.405419896.retry.attempts = 2
+UT0311-L0x.405419896.retry.backoff = 1.5s
 `)
 
 func TestDefaultConfig(t *testing.T) {
@@ -72,6 +78,11 @@ func TestDefaultConfig(t *testing.T) {
 			HealthCheckIdle:     60 * time.Second,
 			HealthCheckIgnore:   5 * time.Minute,
 			WatchdogInterval:    5 * time.Second,
+			Retry: Retry{
+				Attempts: 3,
+				Backoff:  250 * time.Millisecond,
+				Jitter:   100 * time.Millisecond,
+			},
 		},
 
 		MQTT: MQTT{
@@ -143,6 +154,11 @@ func TestConfigUnmarshal(t *testing.T) {
 			HealthCheckIdle:     67 * time.Second,
 			HealthCheckIgnore:   97 * time.Second,
 			WatchdogInterval:    23 * time.Second,
+			Retry: Retry{
+				Attempts: 5,
+				Backoff:  750 * time.Millisecond,
+				Jitter:   50 * time.Millisecond,
+			},
 		},
 
 		MQTT: MQTT{
@@ -283,6 +299,11 @@ func TestConfigUnmarshal(t *testing.T) {
 			t.Errorf("Expected 'device.timezone' %s for ID '%v', got:'%v'", "France/Paris", 405419896, d.TimeZone)
 		}
 
+		retry := Retry{Attempts: 2, Backoff: 1500 * time.Millisecond, Jitter: 50 * time.Millisecond}
+		if d.Retry == nil || *d.Retry != retry {
+			t.Errorf("Expected 'device.retry' %+v for ID '%v', got:'%+v'", retry, 405419896, d.Retry)
+		}
+
 	}
 }
 
@@ -298,6 +319,9 @@ func TestDefaultConfigWrite(t *testing.T) {
 ; monitoring.healthcheck.idle = 1m0s
 ; monitoring.healthcheck.ignore = 5m0s
 ; monitoring.watchdog.interval = 5s
+; retry.attempts = 3
+; retry.backoff = 250ms
+; retry.jitter = 100ms
 
 # REST
 ; rest.http.enabled = false
@@ -458,6 +482,9 @@ timeout = %[4]v
 ; monitoring.healthcheck.idle = 1m0s
 ; monitoring.healthcheck.ignore = 5m0s
 ; monitoring.watchdog.interval = 5s
+; retry.attempts = 3
+; retry.backoff = 250ms
+; retry.jitter = 100ms
 
 # REST
 ; rest.http.enabled = false
@@ -572,6 +599,9 @@ UT0311-L0x.405419896.door.2 = D2
 UT0311-L0x.405419896.door.3 = D3
 UT0311-L0x.405419896.door.4 = D4
 UT0311-L0x.405419896.timezone = France/Paris
+UT0311-L0x.405419896.retry.attempts = 2
+UT0311-L0x.405419896.retry.backoff = 1.5s
+UT0311-L0x.405419896.retry.jitter = 0s
 `, bind.String(), broadcast.String(), listen.String(), 4500*time.Millisecond,
 		restUsers, restGroups, restHOTP,
 		mqttBrokerCertificate, mqttClientCertificate, mqttClientKey, eventIDs, mqttUsers, mqttGroups, mqttCards, hotpSecrets, hotpCounters, rsaKeyDir,
@@ -593,6 +623,7 @@ UT0311-L0x.405419896.timezone = France/Paris
 			Rollover: 98765,
 			Doors:    []string{"D1", "D2", "D3", "D4"},
 			TimeZone: "France/Paris",
+			Retry:    &Retry{Attempts: 2, Backoff: 1500 * time.Millisecond},
 		},
 
 		303986753: &Device{
@@ -745,6 +776,8 @@ UT0311-L0x.405419896.door.2 = Side Door
 UT0311-L0x.405419896.door.3 = Garage
 UT0311-L0x.405419896.door.4 = Workshop
 UT0311-L0x.405419896.timezone = France/Paris
+UT0311-L0x.405419896.retry.attempts = 2
+UT0311-L0x.405419896.retry.backoff = 1.5s
 `)
 
 	config := NewConfig()
@@ -766,6 +799,8 @@ UT0311-L0x.405419896.door.2 = Side Door
 UT0311-L0x.405419896.door.3 = Garage
 UT0311-L0x.405419896.door.4 = Front Door
 UT0311-L0x.405419896.timezone = France/Paris
+UT0311-L0x.405419896.retry.attempts = 2
+UT0311-L0x.405419896.retry.backoff = 1.5s
 `)
 
 	config := NewConfig()
@@ -790,6 +825,8 @@ UT0311-L0x.405419896.door.2 =
 UT0311-L0x.405419896.door.3 = 
 UT0311-L0x.405419896.door.4 = 
 UT0311-L0x.405419896.timezone = France/Paris
+UT0311-L0x.405419896.retry.attempts = 2
+UT0311-L0x.405419896.retry.backoff = 1.5s
 `)
 
 	config := NewConfig()
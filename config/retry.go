@@ -0,0 +1,22 @@
+package config
+
+import (
+	"time"
+)
+
+// Retry policy for requests to a controller. Attempts is the maximum number of times a request
+// is sent, Backoff the delay before the first retry (doubled for each subsequent retry) and
+// Jitter the maximum random delay added to each backoff.
+type Retry struct {
+	Attempts int           `conf:"attempts"`
+	Backoff  time.Duration `conf:"backoff"`
+	Jitter   time.Duration `conf:"jitter"`
+}
+
+func NewRetry() *Retry {
+	return &Retry{
+		Attempts: 3,
+		Backoff:  250 * time.Millisecond,
+		Jitter:   100 * time.Millisecond,
+	}
+}
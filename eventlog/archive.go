@@ -0,0 +1,176 @@
+package eventlog
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/uhppoted/uhppoted-api/uhppoted"
+)
+
+// JSON Lines event archive. Events are appended to the archive file, which is rotated when
+// it exceeds MaxSize (megabytes) and/or on the wall-clock interval. Rotated segments are
+// gzipped and old segments are removed according to MaxBackups and MaxAge (days).
+type Archive struct {
+	writer *Writer
+}
+
+func NewArchive(filename string, maxSize int, interval time.Duration, maxBackups int, maxAge int) *Archive {
+	return &Archive{
+		writer: &Writer{
+			Filename:   filename,
+			MaxSize:    maxSize,
+			Interval:   interval,
+			Compress:   true,
+			MaxBackups: maxBackups,
+			MaxAge:     maxAge,
+		},
+	}
+}
+
+// Appends the event to the archive.
+func (a *Archive) Write(event uhppoted.ListenEvent) error {
+	bytes, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	_, err = a.writer.Write(append(bytes, '\n'))
+
+	return err
+}
+
+// Returns an EventHandler for Listen that archives each event. The handler returns false if
+// the event could not be written to the archive.
+func (a *Archive) Handler() uhppoted.EventHandler {
+	return func(message uhppoted.EventMessage) bool {
+		return a.Write(message.Event) == nil
+	}
+}
+
+func (a *Archive) Close() error {
+	return a.writer.Close()
+}
+
+// Streams the events from an archive, starting with the oldest (compressed) segment and
+// ending with the current segment. Reading stops early if f returns false.
+func ReadArchive(filename string, f func(uhppoted.ListenEvent) bool) error {
+	segments, err := segments(filename)
+	if err != nil {
+		return err
+	}
+
+	for _, segment := range segments {
+		if ok, err := readSegment(segment, f); err != nil {
+			return fmt.Errorf("%v: %w", segment, err)
+		} else if !ok {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// Returns the archive segment files in chronological order. A rotated segment that has been
+// compressed but not yet removed is skipped in favour of the compressed segment.
+func segments(filename string) ([]string, error) {
+	l := Writer{Filename: filename}
+	dir := l.dir()
+	prefix, ext := l.prefixAndExt()
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	type segment struct {
+		timestamp time.Time
+		file      string
+	}
+
+	compressed := map[string]bool{}
+	for _, f := range files {
+		if !f.IsDir() && strings.HasSuffix(f.Name(), ext+".gz") {
+			compressed[strings.TrimSuffix(f.Name(), ".gz")] = true
+		}
+	}
+
+	list := []segment{}
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ext) && !strings.HasSuffix(f.Name(), ext+".gz") {
+			continue
+		}
+
+		if compressed[f.Name()] {
+			continue
+		}
+
+		if name := l.timeFromName(f.Name(), prefix, ext); name != "" {
+			if t, err := l.parseTime(name); err == nil {
+				list = append(list, segment{t, filepath.Join(dir, f.Name())})
+			}
+		}
+	}
+
+	sort.SliceStable(list, func(i, j int) bool { return list[i].timestamp.Before(list[j].timestamp) })
+
+	segments := []string{}
+	for _, s := range list {
+		segments = append(segments, s.file)
+	}
+
+	if _, err := os.Stat(filename); err == nil {
+		segments = append(segments, filename)
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	return segments, nil
+}
+
+func readSegment(file string, f func(uhppoted.ListenEvent) bool) (bool, error) {
+	h, err := os.Open(file)
+	if err != nil {
+		return false, err
+	}
+
+	defer h.Close()
+
+	var r io.Reader = h
+	if strings.HasSuffix(file, ".gz") {
+		gz, err := gzip.NewReader(h)
+		if err != nil {
+			return false, err
+		}
+
+		defer gz.Close()
+		r = gz
+	}
+
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 0, 4096), 1024*1024)
+	for s.Scan() {
+		if len(strings.TrimSpace(s.Text())) == 0 {
+			continue
+		}
+
+		event := uhppoted.ListenEvent{}
+		if err := json.Unmarshal(s.Bytes(), &event); err != nil {
+			return false, err
+		}
+
+		if !f(event) {
+			return false, nil
+		}
+	}
+
+	return true, s.Err()
+}
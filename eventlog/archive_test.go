@@ -0,0 +1,110 @@
+package eventlog
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/uhppoted/uhppoted-api/uhppoted"
+)
+
+func TestArchive(t *testing.T) {
+	defer func(v int) { megabyte = v }(megabyte)
+	megabyte = 1024
+
+	file := filepath.Join(t.TempDir(), "events.log")
+	archive := NewArchive(file, 1, 0, 0, 0)
+	handler := archive.Handler()
+
+	expected := []uint32{}
+	for i := 1; i <= 25; i++ {
+		event := uhppoted.ListenEvent{DeviceID: 405419896, EventID: uint32(i), Type: 1, Granted: true, Door: 1, CardNumber: 8165538, Reason: 1}
+		if !handler(uhppoted.EventMessage{Event: event}) {
+			t.Fatalf("Error archiving event %v", i)
+		}
+
+		expected = append(expected, uint32(i))
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	if err := archive.Close(); err != nil {
+		t.Fatalf("Unexpected error closing archive: %v", err)
+	}
+
+	if gz, _ := filepath.Glob(filepath.Join(filepath.Dir(file), "events-*.log.gz")); len(gz) < 2 {
+		t.Errorf("Expected compressed archive segments, got %v", gz)
+	}
+
+	events := []uint32{}
+	err := ReadArchive(file, func(e uhppoted.ListenEvent) bool {
+		events = append(events, e.EventID)
+		return true
+	})
+
+	if err != nil {
+		t.Fatalf("Unexpected error reading archive: %v", err)
+	}
+
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("Incorrect archived events\n   expected:%v\n   got:     %v", expected, events)
+	}
+}
+
+func TestArchiveRotationInterval(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "events.log")
+	archive := NewArchive(file, 0, 20*time.Millisecond, 0, 0)
+
+	archive.Write(uhppoted.ListenEvent{DeviceID: 405419896, EventID: 1})
+	time.Sleep(50 * time.Millisecond)
+	archive.Write(uhppoted.ListenEvent{DeviceID: 405419896, EventID: 2})
+	archive.Close()
+
+	if gz, _ := filepath.Glob(filepath.Join(filepath.Dir(file), "events-*.log.gz")); len(gz) != 1 {
+		t.Errorf("Expected 1 compressed archive segment, got %v", gz)
+	}
+
+	count := 0
+	ReadArchive(file, func(e uhppoted.ListenEvent) bool {
+		count++
+		return true
+	})
+
+	if count != 2 {
+		t.Errorf("Incorrect number of archived events - expected:%v, got:%v", 2, count)
+	}
+}
+
+func TestReadArchiveWithUncompressedSegment(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "events.log")
+	segment := filepath.Join(dir, "events-2021-03-01T12-30-00.000.log")
+
+	if err := ioutil.WriteFile(segment, []byte(`{"device-id":405419896,"event-id":1}`+"\n"), 0644); err != nil {
+		t.Fatalf("Error creating archive segment (%v)", err)
+	}
+
+	if err := compress(segment); err != nil {
+		t.Fatalf("Error compressing archive segment (%v)", err)
+	}
+
+	// ... simulates a crash part way through a subsequent compression
+	if err := ioutil.WriteFile(segment+".gz.tmp", []byte{0x1f, 0x8b}, 0644); err != nil {
+		t.Fatalf("Error creating partial archive segment (%v)", err)
+	}
+
+	events := []uint32{}
+	err := ReadArchive(file, func(e uhppoted.ListenEvent) bool {
+		events = append(events, e.EventID)
+		return true
+	})
+
+	if err != nil {
+		t.Fatalf("Unexpected error reading archive: %v", err)
+	}
+
+	if expected := []uint32{1}; !reflect.DeepEqual(events, expected) {
+		t.Errorf("Incorrect archived events\n   expected:%v\n   got:     %v", expected, events)
+	}
+}
@@ -0,0 +1,429 @@
+package eventlog
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var _ io.WriteCloser = (*Writer)(nil)
+
+const (
+	Hourly = time.Hour
+	Daily  = 24 * time.Hour
+)
+
+// Rotating log file writer. The log file is rotated when a write would take it past MaxSize
+// megabytes and/or when the wall-clock Interval (e.g. Hourly, Daily) changes, in local time
+// if LocalTime is set and UTC otherwise. If the Interval is set and MaxSize is 0, the file is
+// only rotated on the interval - otherwise a MaxSize of 0 defaults to 100MB.
+//
+// Rotated files are renamed with a timestamp suffix and then (in the background, so that
+// writes are not blocked) gzipped if Compress is set and removed once there are more than
+// MaxBackups or they are older than MaxAge days. PostRotate (if not nil) is invoked from the
+// background goroutine with the name of the archived file once it has been compressed. Close
+// waits for any pending background work.
+type Writer struct {
+	Filename   string
+	MaxSize    int
+	Interval   time.Duration
+	LocalTime  bool
+	Compress   bool
+	MaxBackups int
+	MaxAge     int
+	PostRotate func(archived string)
+
+	size    int64
+	period  time.Time
+	file    *os.File
+	mu      sync.Mutex
+	pending sync.WaitGroup
+	post    sync.Mutex
+}
+
+func (w *Writer) Write(p []byte) (n int, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	writeLen := int64(len(p))
+	if max := w.max(); max > 0 && writeLen > max {
+		return 0, fmt.Errorf("write length %d exceeds maximum file size %d", writeLen, max)
+	}
+
+	if w.file == nil {
+		if err = w.openExistingOrNew(len(p)); err != nil {
+			return 0, err
+		}
+	} else if w.due(writeLen, currentTime()) {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err = w.file.Write(p)
+	w.size += int64(n)
+
+	return n, err
+}
+
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	err := w.close()
+	w.pending.Wait()
+
+	return err
+}
+
+func (w *Writer) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.rotate()
+}
+
+func (w *Writer) close() error {
+	if w.file == nil {
+		return nil
+	}
+
+	err := w.file.Close()
+	w.file = nil
+
+	return err
+}
+
+func (w *Writer) due(writeLen int64, now time.Time) bool {
+	if max := w.max(); max > 0 && w.size+writeLen > max {
+		return true
+	}
+
+	if w.Interval > 0 && !w.start(now).Equal(w.period) {
+		return true
+	}
+
+	return false
+}
+
+// Returns the start of the rotation interval containing t.
+func (w *Writer) start(t time.Time) time.Time {
+	if !w.LocalTime {
+		t = t.UTC()
+	}
+
+	_, offset := t.Zone()
+	zone := time.Duration(offset) * time.Second
+
+	return t.Add(zone).Truncate(w.Interval).Add(-zone)
+}
+
+func (w *Writer) rotate() error {
+	if err := w.close(); err != nil {
+		return err
+	}
+
+	archived, err := w.archive()
+	if err != nil {
+		return fmt.Errorf("Error archiving log file: %s", err)
+	}
+
+	if err := w.openNew(); err != nil {
+		return err
+	}
+
+	now := currentTime()
+
+	w.pending.Add(1)
+	go func() {
+		defer w.pending.Done()
+
+		w.post.Lock()
+		defer w.post.Unlock()
+
+		w.postRotate(archived, now)
+	}()
+
+	return nil
+}
+
+// Compresses the archived file (if enabled), removes expired backups and invokes PostRotate.
+// Runs in the background, serialized by the post mutex.
+func (w *Writer) postRotate(archived string, now time.Time) {
+	if archived != "" && w.Compress {
+		if err := compress(archived); err != nil {
+			if !os.IsNotExist(err) {
+				log.Printf("Error compressing archive file: %v\n", err)
+			}
+		} else if err := os.Remove(archived); err != nil {
+			log.Printf("Error deleting log file '%v'  %v\n", archived, err)
+		} else {
+			archived += ".gz"
+		}
+	}
+
+	if err := w.cleanup(now); err != nil {
+		log.Printf("Error removing expired log files: %v\n", err)
+	}
+
+	if archived != "" && w.PostRotate != nil {
+		w.PostRotate(archived)
+	}
+}
+
+func (w *Writer) openNew() error {
+	if err := os.MkdirAll(w.dir(), 0744); err != nil {
+		return fmt.Errorf("can't make directories for new logfile: %s", err)
+	}
+
+	f, err := os.OpenFile(w.filename(), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("can't open new logfile: %s", err)
+	}
+
+	w.file = f
+	w.size = 0
+	w.period = w.start(currentTime())
+
+	return nil
+}
+
+func (w *Writer) openExistingOrNew(writeLen int) error {
+	filename := w.filename()
+	info, err := os_Stat(filename)
+	if os.IsNotExist(err) {
+		return w.openNew()
+	} else if err != nil {
+		return fmt.Errorf("error getting log file info: %s", err)
+	}
+
+	now := currentTime()
+	if max := w.max(); max > 0 && info.Size()+int64(writeLen) > max {
+		return w.rotate()
+	}
+
+	if w.Interval > 0 && !w.start(info.ModTime()).Equal(w.start(now)) {
+		return w.rotate()
+	}
+
+	file, err := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return w.openNew()
+	}
+
+	w.file = file
+	w.size = info.Size()
+	w.period = w.start(now)
+
+	return nil
+}
+
+// Renames the current log file with a timestamp suffix. Returns the name of the archived file or "" if there was no log file to archive.
+func (w *Writer) archive() (string, error) {
+	name := w.filename()
+	info, err := os_Stat(name)
+	if os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+
+	archived := w.backupName()
+	if err := os.Rename(name, archived); err != nil {
+		return "", err
+	}
+
+	if err := chown(name, info); err != nil {
+		return "", err
+	}
+
+	return archived, nil
+}
+
+func (w *Writer) backupName() string {
+	dir := filepath.Dir(w.filename())
+	prefix, ext := w.prefixAndExt()
+	t := currentTime()
+	if !w.LocalTime {
+		t = t.UTC()
+	}
+
+	// ... avoid overwriting an existing backup if rotated more than once per millisecond
+	for {
+		name := filepath.Join(dir, fmt.Sprintf("%s%s%s", prefix, t.Format(backupTimeFormat), ext))
+		_, err := os_Stat(name)
+		_, errgz := os_Stat(name + ".gz")
+		if os.IsNotExist(err) && os.IsNotExist(errgz) {
+			return name
+		}
+
+		t = t.Add(time.Millisecond)
+	}
+}
+
+// Compresses the file to a temporary file which is renamed to <file>.gz once complete, so
+// that a <file>.gz is never a partial archive (e.g. after a crash).
+func compress(file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	tmp := file + ".gz.tmp"
+	gz, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(tmp)
+
+	w, err := gzip.NewWriterLevel(gz, 9)
+	if err != nil {
+		gz.Close()
+		return err
+	}
+
+	if _, err := io.Copy(w, f); err != nil {
+		gz.Close()
+		return err
+	}
+
+	if err := w.Close(); err != nil {
+		gz.Close()
+		return err
+	}
+
+	if err := gz.Sync(); err != nil {
+		gz.Close()
+		return err
+	}
+
+	if err := gz.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, file+".gz")
+}
+
+func (w *Writer) cleanup(now time.Time) error {
+	if w.MaxBackups == 0 && w.MaxAge == 0 {
+		return nil
+	}
+
+	files, err := w.oldLogFiles()
+	if err != nil {
+		return err
+	}
+
+	var deletes []logInfo
+
+	if w.MaxBackups > 0 && w.MaxBackups < len(files) {
+		deletes = files[w.MaxBackups:]
+		files = files[:w.MaxBackups]
+	}
+
+	if w.MaxAge > 0 {
+		cutoff := now.Add(-time.Duration(w.MaxAge) * 24 * time.Hour)
+		for _, f := range files {
+			if f.timestamp.Before(cutoff) {
+				deletes = append(deletes, f)
+			}
+		}
+	}
+
+	deleteAll(w.dir(), deletes)
+
+	return nil
+}
+
+// Returns the rotated log files, newest first.
+func (w *Writer) oldLogFiles() ([]logInfo, error) {
+	files, err := ioutil.ReadDir(w.dir())
+	if err != nil {
+		return nil, fmt.Errorf("can't read log file directory: %s", err)
+	}
+
+	logFiles := []logInfo{}
+	prefix, ext := w.prefixAndExt()
+
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+
+		if name := w.timeFromName(f.Name(), prefix, ext); name != "" {
+			if t, err := w.parseTime(name); err == nil {
+				logFiles = append(logFiles, logInfo{t, f})
+			}
+		}
+	}
+
+	sort.Sort(byFormatTime(logFiles))
+
+	return logFiles, nil
+}
+
+func (w *Writer) parseTime(s string) (time.Time, error) {
+	if w.LocalTime {
+		return time.ParseInLocation(backupTimeFormat, s, time.Local)
+	}
+
+	return time.Parse(backupTimeFormat, s)
+}
+
+func (w *Writer) timeFromName(filename, prefix, ext string) string {
+	if !strings.HasPrefix(filename, prefix) {
+		return ""
+	}
+
+	filename = strings.TrimSuffix(filename[len(prefix):], ".gz")
+	if !strings.HasSuffix(filename, ext) {
+		return ""
+	}
+
+	return filename[:len(filename)-len(ext)]
+}
+
+func (w *Writer) filename() string {
+	if w.Filename != "" {
+		return w.Filename
+	}
+
+	name := filepath.Base(os.Args[0]) + "-lumberjack.log"
+
+	return filepath.Join(os.TempDir(), name)
+}
+
+func (w *Writer) max() int64 {
+	if w.MaxSize > 0 {
+		return int64(w.MaxSize) * int64(megabyte)
+	}
+
+	if w.Interval > 0 {
+		return 0
+	}
+
+	return int64(defaultMaxSize * megabyte)
+}
+
+func (w *Writer) dir() string {
+	return filepath.Dir(w.filename())
+}
+
+func (w *Writer) prefixAndExt() (prefix, ext string) {
+	filename := filepath.Base(w.filename())
+	ext = filepath.Ext(filename)
+	prefix = filename[:len(filename)-len(ext)] + "-"
+
+	return prefix, ext
+}
@@ -0,0 +1,106 @@
+package eventlog
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestWriterIntervalRotation(t *testing.T) {
+	defer func(f func() time.Time) { currentTime = f }(currentTime)
+
+	now := time.Date(2021, time.March, 1, 23, 59, 30, 0, time.UTC)
+	currentTime = func() time.Time { return now }
+
+	dir := t.TempDir()
+	rotated := []string{}
+	w := Writer{
+		Filename:   filepath.Join(dir, "events.log"),
+		Interval:   Daily,
+		Compress:   true,
+		PostRotate: func(archived string) { rotated = append(rotated, filepath.Base(archived)) },
+	}
+
+	w.Write([]byte("day 1\n"))
+	now = now.Add(15 * time.Second)
+	w.Write([]byte("day 1 again\n"))
+	now = now.Add(30 * time.Second)
+	w.Write([]byte("day 2\n"))
+	w.Close()
+
+	if len(rotated) != 1 || rotated[0] != "events-2021-03-02T00-00-15.000.log.gz" {
+		t.Errorf("Incorrect rotated files - expected:%v, got:%v", []string{"events-2021-03-02T00-00-15.000.log.gz"}, rotated)
+	}
+
+	if bytes, _ := ioutil.ReadFile(w.Filename); string(bytes) != "day 2\n" {
+		t.Errorf("Incorrect current log file contents - expected:%q, got:%q", "day 2\n", string(bytes))
+	}
+}
+
+func TestWriterMaxBackups(t *testing.T) {
+	defer func(f func() time.Time) { currentTime = f }(currentTime)
+	defer func(v int) { megabyte = v }(megabyte)
+
+	now := time.Date(2021, time.March, 1, 12, 0, 0, 0, time.UTC)
+	currentTime = func() time.Time { return now }
+	megabyte = 16
+
+	dir := t.TempDir()
+	w := Writer{
+		Filename:   filepath.Join(dir, "events.log"),
+		MaxSize:    1,
+		Interval:   Hourly,
+		Compress:   true,
+		MaxBackups: 2,
+	}
+
+	for i := 0; i < 10; i++ {
+		if _, err := w.Write([]byte("0123456789\n")); err != nil {
+			t.Fatalf("Unexpected error writing to log file: %v", err)
+		}
+
+		now = now.Add(7 * time.Minute)
+	}
+
+	w.Close()
+
+	files, _ := filepath.Glob(filepath.Join(dir, "events-*"))
+	sort.Strings(files)
+
+	if len(files) != 2 {
+		t.Fatalf("Incorrect number of backups - expected:%v, got:%v", 2, files)
+	}
+
+	for i, f := range []string{"events-2021-03-01T12-56-00.000.log.gz", "events-2021-03-01T13-03-00.000.log.gz"} {
+		if filepath.Base(files[i]) != f {
+			t.Errorf("Incorrect backup %v - expected:%v, got:%v", i+1, f, filepath.Base(files[i]))
+		}
+	}
+}
+
+func TestTickerIntervalRotation(t *testing.T) {
+	defer func(f func() time.Time) { currentTime = f }(currentTime)
+
+	now := time.Date(2021, time.March, 1, 12, 59, 30, 0, time.UTC)
+	currentTime = func() time.Time { return now }
+
+	rotated := []string{}
+	l := Ticker{
+		Options: Options{
+			Filename:   filepath.Join(t.TempDir(), "events.log"),
+			Interval:   Hourly,
+			PostRotate: func(archived string) { rotated = append(rotated, filepath.Base(archived)) },
+		},
+	}
+
+	l.Write([]byte("hour 1\n"))
+	now = now.Add(time.Minute)
+	l.Write([]byte("hour 2\n"))
+	l.Close()
+
+	if len(rotated) != 1 || rotated[0] != "events-2021-03-01T13-00-30.000.log.gz" {
+		t.Errorf("Incorrect rotated files - expected:%v, got:%v", []string{"events-2021-03-01T13-00-30.000.log.gz"}, rotated)
+	}
+}
@@ -0,0 +1,444 @@
+package eventstore
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/uhppoted/uhppote-core/types"
+)
+
+type Event struct {
+	DeviceID   uint32         `json:"device-id"`
+	Index      uint32         `json:"event-id"`
+	Type       uint8          `json:"event-type"`
+	Granted    bool           `json:"access-granted"`
+	Door       uint8          `json:"door-id"`
+	Direction  uint8          `json:"direction"`
+	CardNumber uint32         `json:"card-number"`
+	Timestamp  types.DateTime `json:"timestamp"`
+	Reason     uint8          `json:"event-reason"`
+}
+
+// Query filter for the event store. Nil fields match all events and Limit 0 returns all
+// matching events.
+type Query struct {
+	DeviceID   *uint32
+	Start      *types.DateTime
+	End        *types.DateTime
+	CardNumber *uint32
+	Door       *uint8
+	Granted    *bool
+	Type       *uint8
+	Limit      int
+}
+
+// Retention limits the events kept by the event store. MaxEvents is the maximum number of events
+// and MaxAge the maximum age (by event timestamp) of the events. The oldest events are discarded
+// first and zero values are unlimited. Discarded events are removed from the file when it is
+// compacted, which Put does automatically once the discarded events outnumber the retained
+// events (and COMPACT_THRESHOLD).
+type Retention struct {
+	MaxEvents int
+	MaxAge    time.Duration
+}
+
+// Append-only event store, persisted as a JSON Lines file. Events are keyed by device ID and
+// event index - an event with the same key and timestamp as a stored event is a duplicate
+// and is discarded, whereas an event with the same key and a different timestamp is a new
+// event recorded after the controller event buffer rolled over.
+//
+// Events discarded by the retention policy are removed from memory immediately and from the
+// file when it is compacted (on Open, by Compact and by Put once enough events have been
+// discarded).
+type EventStore struct {
+	file      string
+	f         *os.File
+	offset    int64
+	closed    bool
+	retention Retention
+	events    []Event
+	base      int64
+	pruned    int
+	index     map[key]int64
+	guard     sync.RWMutex
+}
+
+// Minimum number of discarded events before Put compacts the event store file.
+const COMPACT_THRESHOLD = 1024
+
+var ErrClosed = errors.New("event store closed")
+
+type key struct {
+	deviceID uint32
+	index    uint32
+}
+
+// Opens (or creates) the event store file and loads the stored events. An empty file name
+// creates a memory only event store. The store keeps all events - use OpenWithRetention to
+// limit the number or age of the stored events.
+func Open(file string) (*EventStore, error) {
+	return OpenWithRetention(file, Retention{})
+}
+
+// Opens (or creates) the event store file, loads the stored events and discards any events
+// outside the retention limits.
+func OpenWithRetention(file string, retention Retention) (*EventStore, error) {
+	s := EventStore{
+		file:      file,
+		retention: retention,
+		events:    []Event{},
+		index:     map[key]int64{},
+	}
+
+	if file == "" {
+		return &s, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(file), 0770); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(file, os.O_RDWR|os.O_CREATE, 0660)
+	if err != nil {
+		return nil, err
+	}
+
+	offset, err := s.load(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%v: %w", file, err)
+	}
+
+	// ... discard any partially written last record
+	if err := f.Truncate(offset); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	s.f = f
+	s.offset = offset
+
+	if s.prune() > 0 {
+		if err := s.compact(); err != nil {
+			s.f.Close()
+			return nil, fmt.Errorf("%v: %w", file, err)
+		}
+	}
+
+	return &s, nil
+}
+
+func (s *EventStore) Close() error {
+	s.guard.Lock()
+	defer s.guard.Unlock()
+
+	s.closed = true
+
+	if s.f != nil {
+		err := s.f.Close()
+		s.f = nil
+		return err
+	}
+
+	return nil
+}
+
+// Rewrites the event store file to hold only the events within the retention limits.
+func (s *EventStore) Compact() error {
+	s.guard.Lock()
+	defer s.guard.Unlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+
+	s.prune()
+
+	if err := s.compact(); err != nil {
+		return err
+	}
+
+	s.pruned = 0
+
+	return nil
+}
+
+// Appends the event to the store, returning false if the event is a duplicate of a stored
+// event. The event is written and synced to disk before Put returns. A failed write is truncated
+// so that the file does not hold a partial record.
+func (s *EventStore) Put(event Event) (bool, error) {
+	s.guard.Lock()
+	defer s.guard.Unlock()
+
+	if s.closed {
+		return false, ErrClosed
+	}
+
+	k := key{event.DeviceID, event.Index}
+	if seq, ok := s.index[k]; ok {
+		if time.Time(s.events[seq-s.base].Timestamp).Equal(time.Time(event.Timestamp)) {
+			return false, nil
+		}
+	}
+
+	if s.f != nil {
+		bytes, err := json.Marshal(event)
+		if err != nil {
+			return false, err
+		}
+
+		record := append(bytes, '\n')
+		if _, err := s.f.Write(record); err != nil {
+			return false, s.rollback(err)
+		}
+
+		if err := s.f.Sync(); err != nil {
+			return false, s.rollback(err)
+		}
+
+		s.offset += int64(len(record))
+	}
+
+	s.append(event)
+
+	// ... compaction is amortized over the discarded events and a failed compaction is retried
+	//     on a subsequent Put (the file is replaced atomically so it is left intact)
+	s.pruned += s.prune()
+	if s.pruned >= COMPACT_THRESHOLD && s.pruned >= len(s.events) {
+		if err := s.compact(); err == nil {
+			s.pruned = 0
+		}
+	}
+
+	return true, nil
+}
+
+// Returns the most recent event stored for the device ID and event index.
+func (s *EventStore) Get(deviceID, index uint32) (*Event, bool) {
+	s.guard.RLock()
+	defer s.guard.RUnlock()
+
+	if seq, ok := s.index[key{deviceID, index}]; ok {
+		event := s.events[seq-s.base]
+		return &event, true
+	}
+
+	return nil, false
+}
+
+// Returns the stored events that match the query, in the order in which they were stored.
+func (s *EventStore) Query(q Query) []Event {
+	s.guard.RLock()
+	defer s.guard.RUnlock()
+
+	events := []Event{}
+	for _, e := range s.events {
+		if q.Limit > 0 && len(events) >= q.Limit {
+			break
+		}
+
+		if q.match(e) {
+			events = append(events, e)
+		}
+	}
+
+	return events
+}
+
+func (q Query) match(e Event) bool {
+	if q.DeviceID != nil && e.DeviceID != *q.DeviceID {
+		return false
+	}
+
+	if q.Start != nil && time.Time(e.Timestamp).Before(time.Time(*q.Start)) {
+		return false
+	}
+
+	if q.End != nil && time.Time(e.Timestamp).After(time.Time(*q.End)) {
+		return false
+	}
+
+	if q.CardNumber != nil && e.CardNumber != *q.CardNumber {
+		return false
+	}
+
+	if q.Door != nil && e.Door != *q.Door {
+		return false
+	}
+
+	if q.Granted != nil && e.Granted != *q.Granted {
+		return false
+	}
+
+	if q.Type != nil && e.Type != *q.Type {
+		return false
+	}
+
+	return true
+}
+
+// Loads the stored events and returns the offset of the end of the last complete record. A
+// malformed record is an error unless it is the (incomplete) last line in the file.
+func (s *EventStore) load(r io.Reader) (int64, error) {
+	offset := int64(0)
+	line := 0
+	reader := bufio.NewReader(r)
+
+	for {
+		record, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			return offset, nil
+		} else if err != nil {
+			return 0, err
+		}
+
+		line++
+		if text := bytes.TrimSpace(record); len(text) > 0 {
+			event := Event{}
+			if err := json.Unmarshal(text, &event); err != nil {
+				return 0, fmt.Errorf("invalid event record at line %v (%v)", line, err)
+			}
+
+			s.append(event)
+		}
+
+		offset += int64(len(record))
+	}
+}
+
+// NOTE: expects the caller to hold the write lock
+func (s *EventStore) append(event Event) {
+	s.events = append(s.events, event)
+	s.index[key{event.DeviceID, event.Index}] = s.base + int64(len(s.events)) - 1
+}
+
+// Discards the oldest events that are outside the retention limits from memory and returns the
+// number of discarded events.
+//
+// NOTE: expects the caller to hold the write lock
+func (s *EventStore) prune() int {
+	n := 0
+	if s.retention.MaxEvents > 0 && len(s.events) > s.retention.MaxEvents {
+		n = len(s.events) - s.retention.MaxEvents
+	}
+
+	if s.retention.MaxAge > 0 {
+		cutoff := time.Now().Add(-s.retention.MaxAge)
+		for n < len(s.events) && time.Time(s.events[n].Timestamp).Before(cutoff) {
+			n++
+		}
+	}
+
+	if n == 0 {
+		return 0
+	}
+
+	for i := 0; i < n; i++ {
+		e := s.events[i]
+		k := key{e.DeviceID, e.Index}
+		if s.index[k] == s.base+int64(i) {
+			delete(s.index, k)
+		}
+	}
+
+	// ... reslicing rather than copying keeps Put O(1) (amortized) - append reallocates the
+	//     backing array (and releases the discarded events) once the capacity is exhausted
+	s.events = s.events[n:]
+	s.base += int64(n)
+
+	return n
+}
+
+// Rewrites the event store file with the events currently held in memory, replacing the file
+// atomically (write to temporary file + rename).
+//
+// NOTE: expects the caller to hold the write lock
+func (s *EventStore) compact() error {
+	if s.f == nil {
+		return nil
+	}
+
+	dir := filepath.Dir(s.file)
+	tmp, err := os.CreateTemp(dir, filepath.Base(s.file)+".*")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	for _, e := range s.events {
+		bytes, err := json.Marshal(e)
+		if err != nil {
+			tmp.Close()
+			return err
+		}
+
+		w.Write(append(bytes, '\n'))
+	}
+
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	offset, err := tmp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Chmod(0660); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), s.file); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+
+	s.f.Close()
+	s.f = tmp
+	s.offset = offset
+
+	return nil
+}
+
+// Truncates the file back to the end of the last complete record after a failed write.
+//
+// NOTE: expects the caller to hold the write lock
+func (s *EventStore) rollback(err error) error {
+	if e := s.f.Truncate(s.offset); e != nil {
+		return fmt.Errorf("%v (error discarding partial record: %v)", err, e)
+	}
+
+	if _, e := s.f.Seek(s.offset, io.SeekStart); e != nil {
+		return fmt.Errorf("%v (error discarding partial record: %v)", err, e)
+	}
+
+	return err
+}
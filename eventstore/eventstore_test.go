@@ -0,0 +1,243 @@
+package eventstore
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/uhppoted/uhppote-core/types"
+	"github.com/uhppoted/uhppoted-api/uhppoted"
+)
+
+func datetime(s string) *types.DateTime {
+	d, _ := types.DateTimeFromString(s)
+
+	return d
+}
+
+var events = []Event{
+	Event{DeviceID: 405419896, Index: 1, Type: 1, Granted: true, Door: 1, CardNumber: 65537, Timestamp: *datetime("2021-03-01 08:15:00"), Reason: 1},
+	Event{DeviceID: 405419896, Index: 2, Type: 1, Granted: false, Door: 2, CardNumber: 65538, Timestamp: *datetime("2021-03-01 09:30:00"), Reason: 6},
+	Event{DeviceID: 303986753, Index: 1, Type: 2, Granted: true, Door: 3, CardNumber: 0, Timestamp: *datetime("2021-03-02 10:45:00"), Reason: 23},
+	Event{DeviceID: 303986753, Index: 2, Type: 1, Granted: true, Door: 3, CardNumber: 65537, Timestamp: *datetime("2021-03-03 11:00:00"), Reason: 1},
+}
+
+func TestQuery(t *testing.T) {
+	s, _ := Open("")
+	for _, e := range events {
+		s.Put(e)
+	}
+
+	device := uint32(303986753)
+	card := uint32(65537)
+	door := uint8(3)
+	denied := false
+	door2 := uint8(2)
+
+	vector := []struct {
+		query    Query
+		expected []Event
+	}{
+		{Query{}, events},
+		{Query{DeviceID: &device}, events[2:]},
+		{Query{CardNumber: &card}, []Event{events[0], events[3]}},
+		{Query{Door: &door}, events[2:]},
+		{Query{Granted: &denied}, events[1:2]},
+		{Query{Type: &door2}, events[2:3]},
+		{Query{Start: datetime("2021-03-01 09:00:00"), End: datetime("2021-03-02 12:00:00")}, events[1:3]},
+		{Query{CardNumber: &card, DeviceID: &device}, events[3:]},
+		{Query{Limit: 2}, events[0:2]},
+	}
+
+	for i, v := range vector {
+		if result := s.Query(v.query); !reflect.DeepEqual(result, v.expected) {
+			t.Errorf("Incorrect query result %v\n   expected:%v\n   got:     %v", i+1, v.expected, result)
+		}
+	}
+}
+
+func TestPersistence(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "events.json")
+
+	s, err := Open(file)
+	if err != nil {
+		t.Fatalf("Unexpected error opening event store: %v", err)
+	}
+
+	for _, e := range events {
+		if ok, err := s.Put(e); err != nil || !ok {
+			t.Fatalf("Error storing event (%v,%v)", ok, err)
+		}
+	}
+
+	if ok, err := s.Put(events[1]); err != nil || ok {
+		t.Errorf("Expected duplicate event to be discarded, got (%v,%v)", ok, err)
+	}
+
+	rollover := events[1]
+	rollover.Timestamp = types.DateTime(time.Time(rollover.Timestamp).Add(48 * time.Hour))
+	if ok, err := s.Put(rollover); err != nil || !ok {
+		t.Errorf("Expected rolled over event to be stored, got (%v,%v)", ok, err)
+	}
+
+	s.Close()
+
+	// ... simulate partially written record
+	f, _ := os.OpenFile(file, os.O_WRONLY|os.O_APPEND, 0660)
+	f.Write([]byte(`{"device-id":405419896,"event-id":`))
+	f.Close()
+
+	s, err = Open(file)
+	if err != nil {
+		t.Fatalf("Unexpected error reopening event store: %v", err)
+	}
+
+	defer s.Close()
+
+	expected := append(append([]Event{}, events...), rollover)
+	if result := s.Query(Query{}); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Incorrect events after reopening\n   expected:%v\n   got:     %v", expected, result)
+	}
+
+	if e, ok := s.Get(405419896, 2); !ok || !reflect.DeepEqual(*e, rollover) {
+		t.Errorf("Incorrect event for key - expected:%v, got:%v", rollover, e)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	s, _ := Open("")
+	forwarded := 0
+
+	h := Handler(s, func(m uhppoted.EventMessage) bool {
+		forwarded++
+		return true
+	})
+
+	message := uhppoted.EventMessage{
+		Event: uhppoted.ListenEvent{DeviceID: 405419896, EventID: 17, Type: 1, Granted: true, Door: 1, CardNumber: 65537, Timestamp: *datetime("2021-03-01 08:15:00"), Reason: 1},
+	}
+
+	if !h(message) {
+		t.Errorf("Expected event handler to return true")
+	}
+
+	if e, ok := s.Get(405419896, 17); !ok || e.CardNumber != 65537 {
+		t.Errorf("Event not stored - got %v", e)
+	}
+
+	if forwarded != 1 {
+		t.Errorf("Expected event to be forwarded to next handler")
+	}
+}
+
+func TestPutAfterClose(t *testing.T) {
+	s, _ := Open(filepath.Join(t.TempDir(), "events.json"))
+	s.Close()
+
+	if ok, err := s.Put(events[0]); ok || !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed, got (%v,%v)", ok, err)
+	}
+
+	if result := s.Query(Query{}); len(result) != 0 {
+		t.Errorf("Event stored after Close - got:%v", result)
+	}
+}
+
+func TestRetention(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "events.json")
+
+	s, err := OpenWithRetention(file, Retention{MaxEvents: 2})
+	if err != nil {
+		t.Fatalf("Unexpected error opening event store: %v", err)
+	}
+
+	for _, e := range events {
+		s.Put(e)
+	}
+
+	if result := s.Query(Query{}); !reflect.DeepEqual(result, events[2:]) {
+		t.Errorf("Incorrect events\n   expected:%v\n   got:     %v", events[2:], result)
+	}
+
+	if _, ok := s.Get(405419896, 1); ok {
+		t.Errorf("Expected event to have been discarded")
+	}
+
+	if e, ok := s.Get(303986753, 2); !ok || !reflect.DeepEqual(*e, events[3]) {
+		t.Errorf("Incorrect event for key - expected:%v, got:%v", events[3], e)
+	}
+
+	s.Close()
+
+	// ... file is compacted on reopen and by Compact
+	s, err = OpenWithRetention(file, Retention{MaxEvents: 1})
+	if err != nil {
+		t.Fatalf("Unexpected error reopening event store: %v", err)
+	}
+
+	s.Put(events[0])
+
+	if err := s.Compact(); err != nil {
+		t.Fatalf("Unexpected error compacting event store: %v", err)
+	}
+
+	s.Close()
+
+	s, _ = Open(file)
+	defer s.Close()
+
+	expected := []Event{events[0]}
+	if result := s.Query(Query{}); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Incorrect events after compaction\n   expected:%v\n   got:     %v", expected, result)
+	}
+}
+
+func TestRetentionCompactsFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "events.json")
+
+	s, err := OpenWithRetention(file, Retention{MaxEvents: 10})
+	if err != nil {
+		t.Fatalf("Unexpected error opening event store: %v", err)
+	}
+
+	defer s.Close()
+
+	for i := 1; i <= 2*COMPACT_THRESHOLD+10; i++ {
+		event := events[0]
+		event.Index = uint32(i)
+		if _, err := s.Put(event); err != nil {
+			t.Fatalf("Unexpected error storing event %v: %v", i, err)
+		}
+	}
+
+	bytes, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("Unexpected error reading event store file: %v", err)
+	}
+
+	if lines := strings.Count(string(bytes), "\n"); lines > COMPACT_THRESHOLD+10 {
+		t.Errorf("Event store file not compacted - expected at most %v events, got %v", COMPACT_THRESHOLD+10, lines)
+	}
+
+	if result := s.Query(Query{}); len(result) != 10 || result[0].Index != 2*COMPACT_THRESHOLD+1 {
+		t.Errorf("Incorrect retained events - got:%v", result)
+	}
+}
+
+func TestRetentionByAge(t *testing.T) {
+	s, _ := OpenWithRetention("", Retention{MaxAge: 24 * time.Hour})
+
+	recent := events[3]
+	recent.Timestamp = types.DateTime(time.Now().Add(-time.Hour))
+
+	s.Put(events[0])
+	s.Put(recent)
+
+	if result := s.Query(Query{}); !reflect.DeepEqual(result, []Event{recent}) {
+		t.Errorf("Incorrect events\n   expected:%v\n   got:     %v", []Event{recent}, result)
+	}
+}
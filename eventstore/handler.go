@@ -0,0 +1,35 @@
+package eventstore
+
+import (
+	"github.com/uhppoted/uhppoted-api/uhppoted"
+)
+
+// Returns an event handler for UHPPOTED.Listen that writes each event to the event store
+// before passing it on to the next handler (if any). An event that cannot be stored is not
+// passed on and returns false, so that Listen does not mark it as retrieved.
+func Handler(store *EventStore, next uhppoted.EventHandler) uhppoted.EventHandler {
+	return func(message uhppoted.EventMessage) bool {
+		e := message.Event
+		event := Event{
+			DeviceID:   uint32(e.DeviceID),
+			Index:      e.EventID,
+			Type:       e.Type,
+			Granted:    e.Granted,
+			Door:       e.Door,
+			Direction:  e.Direction,
+			CardNumber: e.CardNumber,
+			Timestamp:  e.Timestamp,
+			Reason:     e.Reason,
+		}
+
+		if _, err := store.Put(event); err != nil {
+			return false
+		}
+
+		if next != nil {
+			return next(message)
+		}
+
+		return true
+	}
+}
@@ -0,0 +1,12 @@
+package wallclock
+
+import (
+	"time"
+)
+
+// The controller clock has no time zone and uhppote-core decodes the controller date/time as
+// local time. In reinterprets the date and time as the 'wall clock' time in the controller
+// time zone.
+func In(t time.Time, tz *time.Location) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), tz)
+}
@@ -12,6 +12,8 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/uhppoted/uhppoted-api/logging"
 )
 
 type KeyValueStore struct {
@@ -60,6 +62,11 @@ func (kv *KeyValueStore) Put(key string, value interface{}) {
 }
 
 func (kv *KeyValueStore) Store(key string, value interface{}, filepath string, log *log.Logger) {
+	kv.StoreWithLogger(key, value, filepath, logging.NewLogAdapter(log, logging.DEBUG))
+}
+
+// Store with a structured logger for errors writing the file.
+func (kv *KeyValueStore) StoreWithLogger(key string, value interface{}, filepath string, log logging.Logger) {
 	kv.guard.Lock()
 	defer kv.guard.Unlock()
 
@@ -85,7 +92,7 @@ func (kv *KeyValueStore) LoadFromFile(filepath string) error {
 }
 
 // Ref. https://www.joeshaw.org/dont-defer-close-on-writable-files/
-func (kv *KeyValueStore) save(file string, log *log.Logger) {
+func (kv *KeyValueStore) save(file string, log logging.Logger) {
 	// ... copy current store
 
 	kv.guard.Lock()
@@ -107,6 +114,10 @@ func (kv *KeyValueStore) save(file string, log *log.Logger) {
 		return
 	}
 
+	if log == nil {
+		log = logging.NewNopLogger()
+	}
+
 	dir := filepath.Dir(file)
 	filename := fmt.Sprintf("%s.%d", filepath.Base(file), kv.version)
 	tmpfile := filepath.Join(dir, filename)
@@ -115,39 +126,39 @@ func (kv *KeyValueStore) save(file string, log *log.Logger) {
 
 	f, err := os.Create(tmpfile)
 	if err != nil {
-		log.Printf("ERROR: %s - %v", kv.name, err)
+		log.Error(fmt.Sprintf("%s - %v", kv.name, err), logging.Operation("kvs"))
 		return
 	}
 
 	for key, value := range store {
 		if _, err := fmt.Fprintf(f, "%-20s  %v\n", key, value); err != nil {
-			log.Printf("ERROR: %s - %v", kv.name, err)
+			log.Error(fmt.Sprintf("%s - %v", kv.name, err), logging.Operation("kvs"))
 			f.Close()
 			return
 		}
 	}
 
 	if err := f.Sync(); err != nil {
-		log.Printf("ERROR: %s - %v", kv.name, err)
+		log.Error(fmt.Sprintf("%s - %v", kv.name, err), logging.Operation("kvs"))
 		f.Close()
 		return
 	}
 
 	if err := f.Close(); err != nil {
-		log.Printf("ERROR: %s - %v", kv.name, err)
+		log.Error(fmt.Sprintf("%s - %v", kv.name, err), logging.Operation("kvs"))
 		return
 	}
 
 	if version > kv.stored {
 		if err := os.Rename(tmpfile, file); err != nil {
-			log.Printf("ERROR: %s - %v", kv.name, err)
+			log.Error(fmt.Sprintf("%s - %v", kv.name, err), logging.Operation("kvs"))
 		} else {
 			kv.stored = version
 		}
 	} else {
-		log.Printf("WARN: %s - out of date version discarded", kv.name)
+		log.Warn(fmt.Sprintf("%s - out of date version discarded", kv.name), logging.Operation("kvs"))
 		if err := os.Remove(tmpfile); err != nil {
-			log.Printf("ERROR: %s - %v", kv.name, err)
+			log.Error(fmt.Sprintf("%s - %v", kv.name, err), logging.Operation("kvs"))
 		}
 	}
 }
@@ -165,11 +176,20 @@ func (kv *KeyValueStore) Save(w io.Writer) error {
 // NOTE: interim file watcher implementation pending fsnotify in Go 1.4
 //       (https://github.com/fsnotify/fsnotify requires workarounds for
 //        files updated atomically by renaming)
-func (kv *KeyValueStore) Watch(filepath string, logger *log.Logger) {
+func (kv *KeyValueStore) Watch(filepath string, log *log.Logger) {
+	kv.WatchWithLogger(filepath, logging.NewLogAdapter(log, logging.DEBUG))
+}
+
+// Watch with a structured logger for reload errors and updates.
+func (kv *KeyValueStore) WatchWithLogger(filepath string, log logging.Logger) {
+	if log == nil {
+		log = logging.NewNopLogger()
+	}
+
 	go func() {
 		finfo, err := os.Stat(filepath)
 		if err != nil {
-			logger.Printf("ERROR Failed to get file information for '%s': %v", filepath, err)
+			log.Error(fmt.Sprintf("Failed to get file information for '%s': %v", filepath, err), logging.Operation("kvs"))
 			return
 		}
 
@@ -180,7 +200,7 @@ func (kv *KeyValueStore) Watch(filepath string, logger *log.Logger) {
 			finfo, err := os.Stat(filepath)
 			if err != nil {
 				if !logged {
-					logger.Printf("ERROR Failed to get file information for '%s': %v", filepath, err)
+					log.Error(fmt.Sprintf("Failed to get file information for '%s': %v", filepath, err), logging.Operation("kvs"))
 					logged = true
 				}
 
@@ -189,15 +209,15 @@ func (kv *KeyValueStore) Watch(filepath string, logger *log.Logger) {
 
 			logged = false
 			if finfo.ModTime() != lastModified {
-				log.Printf("INFO  Reloading information from %s\n", filepath)
+				log.Info(fmt.Sprintf("Reloading information from %s", filepath), logging.Operation("kvs"))
 
 				err := kv.LoadFromFile(filepath)
 				if err != nil {
-					log.Printf("ERROR Failed to reload information from %s: %v", filepath, err)
+					log.Error(fmt.Sprintf("Failed to reload information from %s: %v", filepath, err), logging.Operation("kvs"))
 					continue
 				}
 
-				log.Printf("WARN  Updated %s from %s", kv.name, filepath)
+				log.Warn(fmt.Sprintf("Updated %s from %s", kv.name, filepath), logging.Operation("kvs"))
 				lastModified = finfo.ModTime()
 			}
 		}
@@ -0,0 +1,146 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+type stdlog struct {
+	base
+	log *log.Logger
+}
+
+type jsonl struct {
+	base
+	w     io.Writer
+	guard *sync.Mutex
+}
+
+type nop struct {
+	base
+}
+
+// Returns a Logger that writes to a standard library *log.Logger as 'LEVEL message key=value ...'
+// lines. A nil *log.Logger returns a no-op logger.
+func NewLogAdapter(l *log.Logger, level Level) Logger {
+	if l == nil {
+		return NewNopLogger()
+	}
+
+	return &stdlog{
+		base: newBase(level),
+		log:  l,
+	}
+}
+
+// Returns a Logger that writes each log entry as a single line JSON object with 'time', 'level'
+// and 'message' fields along with any key/value fields.
+func NewJSONLogger(w io.Writer, level Level) Logger {
+	return &jsonl{
+		base:  newBase(level),
+		w:     w,
+		guard: &sync.Mutex{},
+	}
+}
+
+func NewNopLogger() Logger {
+	return &nop{
+		base: newBase(ERROR),
+	}
+}
+
+func (l *stdlog) Debug(msg string, fields ...Field) { l.write(DEBUG, msg, fields) }
+func (l *stdlog) Info(msg string, fields ...Field)  { l.write(INFO, msg, fields) }
+func (l *stdlog) Warn(msg string, fields ...Field)  { l.write(WARN, msg, fields) }
+func (l *stdlog) Error(msg string, fields ...Field) { l.write(ERROR, msg, fields) }
+
+func (l *stdlog) With(fields ...Field) Logger {
+	return &stdlog{
+		base: l.with(fields),
+		log:  l.log,
+	}
+}
+
+func (l *stdlog) write(level Level, msg string, fields []Field) {
+	if !l.enabled(level) {
+		return
+	}
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%-5v %v", level, msg)
+	for _, f := range append(l.fields, fields...) {
+		v := fmt.Sprintf("%v", f.Value)
+		if v == "" || strings.ContainsAny(v, " \t\n\"=") {
+			v = fmt.Sprintf("%q", v)
+		}
+
+		fmt.Fprintf(&b, "  %v=%v", f.Key, v)
+	}
+
+	l.log.Print(b.String())
+}
+
+func (l *jsonl) Debug(msg string, fields ...Field) { l.write(DEBUG, msg, fields) }
+func (l *jsonl) Info(msg string, fields ...Field)  { l.write(INFO, msg, fields) }
+func (l *jsonl) Warn(msg string, fields ...Field)  { l.write(WARN, msg, fields) }
+func (l *jsonl) Error(msg string, fields ...Field) { l.write(ERROR, msg, fields) }
+
+func (l *jsonl) With(fields ...Field) Logger {
+	return &jsonl{
+		base:  l.with(fields),
+		w:     l.w,
+		guard: l.guard,
+	}
+}
+
+func (l *jsonl) write(level Level, msg string, fields []Field) {
+	if !l.enabled(level) {
+		return
+	}
+
+	entry := map[string]interface{}{}
+	for _, f := range append(l.fields, fields...) {
+		switch v := f.Value.(type) {
+		case error:
+			entry[f.Key] = v.Error()
+		case fmt.Stringer:
+			entry[f.Key] = v.String()
+		default:
+			entry[f.Key] = v
+		}
+	}
+
+	entry["time"] = time.Now().Format(time.RFC3339Nano)
+	entry["level"] = level.String()
+	entry["message"] = msg
+
+	bytes, err := json.Marshal(entry)
+	if err != nil {
+		bytes, _ = json.Marshal(map[string]interface{}{
+			"time":    entry["time"],
+			"level":   entry["level"],
+			"message": msg,
+			"error":   err.Error(),
+		})
+	}
+
+	l.guard.Lock()
+	defer l.guard.Unlock()
+
+	l.w.Write(append(bytes, '\n'))
+}
+
+func (l *nop) Debug(msg string, fields ...Field) {}
+func (l *nop) Info(msg string, fields ...Field)  {}
+func (l *nop) Warn(msg string, fields ...Field)  {}
+func (l *nop) Error(msg string, fields ...Field) {}
+
+func (l *nop) With(fields ...Field) Logger {
+	return l
+}
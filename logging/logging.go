@@ -0,0 +1,122 @@
+package logging
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+type Level int32
+
+const (
+	DEBUG Level = iota
+	INFO
+	WARN
+	ERROR
+)
+
+func (l Level) String() string {
+	switch l {
+	case DEBUG:
+		return "DEBUG"
+	case INFO:
+		return "INFO"
+	case WARN:
+		return "WARN"
+	case ERROR:
+		return "ERROR"
+	}
+
+	return fmt.Sprintf("LEVEL(%d)", int32(l))
+}
+
+// Parses a case-insensitive level name ("debug", "info", "warn" or "error").
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return DEBUG, nil
+	case "info":
+		return INFO, nil
+	case "warn", "warning":
+		return WARN, nil
+	case "error":
+		return ERROR, nil
+	}
+
+	return INFO, fmt.Errorf("Invalid log level '%v'", s)
+}
+
+// Logger is the levelled, structured logging interface used throughout the library. Loggers
+// derived with With share the level of the parent logger so that SetLevel on any of them
+// changes the level for all of them.
+type Logger interface {
+	Debug(msg string, fields ...Field)
+	Info(msg string, fields ...Field)
+	Warn(msg string, fields ...Field)
+	Error(msg string, fields ...Field)
+	With(fields ...Field) Logger
+	SetLevel(level Level)
+	Level() Level
+}
+
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+func KV(key string, value interface{}) Field {
+	return Field{Key: key, Value: value}
+}
+
+func DeviceID(deviceID uint32) Field {
+	return Field{Key: "device-id", Value: deviceID}
+}
+
+func Operation(op string) Field {
+	return Field{Key: "operation", Value: op}
+}
+
+func Door(door uint8) Field {
+	return Field{Key: "door", Value: door}
+}
+
+func Card(card uint32) Field {
+	return Field{Key: "card", Value: card}
+}
+
+func Err(err error) Field {
+	return Field{Key: "error", Value: err}
+}
+
+// Common level and field handling for the Logger implementations.
+type base struct {
+	level  *int32
+	fields []Field
+}
+
+func newBase(level Level) base {
+	v := int32(level)
+
+	return base{
+		level: &v,
+	}
+}
+
+func (b base) with(fields []Field) base {
+	return base{
+		level:  b.level,
+		fields: append(append([]Field{}, b.fields...), fields...),
+	}
+}
+
+func (b base) enabled(level Level) bool {
+	return level >= Level(atomic.LoadInt32(b.level))
+}
+
+func (b base) SetLevel(level Level) {
+	atomic.StoreInt32(b.level, int32(level))
+}
+
+func (b base) Level() Level {
+	return Level(atomic.LoadInt32(b.level))
+}
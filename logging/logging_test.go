@@ -0,0 +1,98 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestLogAdapter(t *testing.T) {
+	var b bytes.Buffer
+
+	l := NewLogAdapter(log.New(&b, "", 0), INFO)
+
+	l.Debug("not logged")
+	l.Info("get-status", DeviceID(405419896), Operation("get-status"), Door(3), Card(8165538))
+	l.With(DeviceID(303986753)).Warn("timeout", Err(errors.New("no reply")))
+
+	expected := "INFO  get-status  device-id=405419896  operation=get-status  door=3  card=8165538\n" +
+		"WARN  timeout  device-id=303986753  error=\"no reply\"\n"
+
+	if b.String() != expected {
+		t.Errorf("Incorrect log output\n   expected:%q\n   got:     %q", expected, b.String())
+	}
+}
+
+func TestSetLevel(t *testing.T) {
+	var b bytes.Buffer
+
+	l := NewLogAdapter(log.New(&b, "", 0), WARN)
+	child := l.With(Operation("listen"))
+
+	child.Info("not logged")
+	if b.Len() != 0 {
+		t.Fatalf("Unexpected log output: %q", b.String())
+	}
+
+	l.SetLevel(DEBUG)
+	child.Debug("logged")
+
+	if b.String() != "DEBUG logged  operation=listen\n" {
+		t.Errorf("Incorrect log output after SetLevel: %q", b.String())
+	}
+
+	if child.Level() != DEBUG {
+		t.Errorf("Incorrect derived logger level - expected:%v, got:%v", DEBUG, child.Level())
+	}
+}
+
+func TestJSONLogger(t *testing.T) {
+	var b bytes.Buffer
+
+	l := NewJSONLogger(&b, DEBUG).With(DeviceID(405419896))
+	l.Error("device not found", Operation("health-check"), Err(errors.New("timeout")))
+
+	entry := map[string]interface{}{}
+	if err := json.Unmarshal(b.Bytes(), &entry); err != nil {
+		t.Fatalf("Invalid JSON log entry %q (%v)", b.String(), err)
+	}
+
+	if !strings.HasSuffix(b.String(), "}\n") {
+		t.Errorf("JSON log entry not newline terminated: %q", b.String())
+	}
+
+	expected := map[string]interface{}{
+		"level":     "ERROR",
+		"message":   "device not found",
+		"device-id": float64(405419896),
+		"operation": "health-check",
+		"error":     "timeout",
+	}
+
+	for k, v := range expected {
+		if entry[k] != v {
+			t.Errorf("Incorrect '%v' - expected:%v, got:%v", k, v, entry[k])
+		}
+	}
+
+	if _, ok := entry["time"]; !ok {
+		t.Errorf("Missing 'time' field")
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := map[string]Level{"debug": DEBUG, "INFO": INFO, " warn ": WARN, "warning": WARN, "Error": ERROR}
+
+	for s, expected := range tests {
+		if level, err := ParseLevel(s); err != nil || level != expected {
+			t.Errorf("Incorrect level for '%v' - expected:%v, got:%v (%v)", s, expected, level, err)
+		}
+	}
+
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Errorf("Expected error parsing invalid level")
+	}
+}
@@ -0,0 +1,165 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/uhppoted/uhppote-core/uhppote"
+	"github.com/uhppoted/uhppoted-api/monitoring"
+	"github.com/uhppoted/uhppoted-api/retry"
+	"github.com/uhppoted/uhppoted-api/uhppoted"
+)
+
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+var LatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+var LagBuckets = []float64{0.1, 0.5, 1, 5, 10, 30, 60, 300, 900, 3600}
+
+// Metrics collects controller request, event and health-check metrics and serves them in
+// the Prometheus text exposition format. Requests are instrumented by wrapping the IUHPPOTE
+// used by UHPPOTED and the acl functions with Wrap, events by wrapping the Listen event
+// handler with EventHandler, health-check counts by registering the HealthCheck and retry
+// counts by registering the Retry.
+type Metrics struct {
+	requests     *family
+	errors       *family
+	latency      *family
+	events       *family
+	lag          *family
+	healthchecks []*monitoring.HealthCheck
+	retries      []*retry.Retry
+	guard        sync.Mutex
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{
+		requests: newFamily("uhppoted_requests_total", "Number of requests sent to a controller.", counter, nil, "device", "operation"),
+		errors:   newFamily("uhppoted_request_errors_total", "Number of failed controller requests by error type.", counter, nil, "device", "operation", "type"),
+		latency:  newFamily("uhppoted_request_duration_seconds", "Controller request latency.", histogram, LatencyBuckets, "device", "operation"),
+		events:   newFamily("uhppoted_events_total", "Number of events received from a controller.", counter, nil, "device"),
+		lag:      newFamily("uhppoted_event_lag_seconds", "Delay between the event timestamp and the event being received.", histogram, LagBuckets, "device"),
+	}
+}
+
+// Returns an IUHPPOTE that records the count, latency and errors for each request.
+func (m *Metrics) Wrap(u uhppote.IUHPPOTE) uhppote.IUHPPOTE {
+	return &instrumented{
+		uhppote: u,
+		metrics: m,
+	}
+}
+
+// Returns an event handler that counts the received events and the event lag before
+// invoking the next handler.
+func (m *Metrics) EventHandler(next uhppoted.EventHandler) uhppoted.EventHandler {
+	return func(message uhppoted.EventMessage) bool {
+		device := fmt.Sprintf("%v", message.Event.DeviceID)
+		lag := time.Since(time.Time(message.Event.Timestamp)).Seconds()
+		if lag < 0 {
+			lag = 0
+		}
+
+		m.events.add(1, device)
+		m.lag.observe(lag, device)
+
+		return next(message)
+	}
+}
+
+// Adds the per-device warning and error counts of the health-check to the metrics.
+func (m *Metrics) HealthCheck(h *monitoring.HealthCheck) {
+	m.guard.Lock()
+	defer m.guard.Unlock()
+
+	m.healthchecks = append(m.healthchecks, h)
+}
+
+// Adds the per-device and per-operation retry counts of the Retry to the metrics.
+func (m *Metrics) Retry(r *retry.Retry) {
+	m.guard.Lock()
+	defer m.guard.Unlock()
+
+	m.retries = append(m.retries, r)
+}
+
+func (m *Metrics) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var b bytes.Buffer
+
+		if err := m.Write(&b); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", ContentType)
+		w.Write(b.Bytes())
+	})
+}
+
+// Writes all metrics in the Prometheus text exposition format.
+func (m *Metrics) Write(w io.Writer) error {
+	m.guard.Lock()
+	healthchecks := append([]*monitoring.HealthCheck{}, m.healthchecks...)
+	retries := append([]*retry.Retry{}, m.retries...)
+	m.guard.Unlock()
+
+	// ... gauges and retry counts are built as a local snapshot for each Write so that concurrent
+	//     Writes cannot interleave
+	warnings := newFamily("uhppoted_healthcheck_warnings", "Number of warnings for a controller from the most recent health-check.", gauge, nil, "device")
+	alerts := newFamily("uhppoted_healthcheck_errors", "Number of errors for a controller from the most recent health-check.", gauge, nil, "device")
+	for _, h := range healthchecks {
+		for id, counts := range h.Counts() {
+			warnings.set(float64(counts.Warnings), fmt.Sprintf("%v", id))
+			alerts.set(float64(counts.Errors), fmt.Sprintf("%v", id))
+		}
+	}
+
+	retried := newFamily("uhppoted_request_retries_total", "Number of controller requests resent after a failure.", counter, nil, "device", "operation")
+	for _, r := range retries {
+		for id, ops := range r.Stats() {
+			for op, stats := range ops {
+				retried.add(float64(stats.Retries), fmt.Sprintf("%v", id), op)
+			}
+		}
+	}
+
+	for _, f := range []*family{m.requests, m.errors, m.latency, retried, m.events, m.lag, warnings, alerts} {
+		if err := f.write(w); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *Metrics) record(deviceID uint32, op string, start time.Time, err error, rejected bool) {
+	device := fmt.Sprintf("%v", deviceID)
+
+	m.requests.add(1, device, op)
+	m.latency.observe(time.Since(start).Seconds(), device, op)
+
+	if err != nil {
+		m.errors.add(1, device, op, errorType(err))
+	} else if rejected {
+		m.errors.add(1, device, op, "rejected")
+	}
+}
+
+// Classifies request errors as 'timeout' (uhppote-core returns an unwrapped 'Timeout waiting for
+// reply' error) or 'error'.
+func errorType(err error) string {
+	if t, ok := err.(interface{ Timeout() bool }); ok && t.Timeout() {
+		return "timeout"
+	}
+
+	if strings.Contains(strings.ToLower(err.Error()), "timeout") {
+		return "timeout"
+	}
+
+	return "error"
+}
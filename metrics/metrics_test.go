@@ -0,0 +1,177 @@
+package metrics
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/uhppoted/uhppote-core/types"
+	"github.com/uhppoted/uhppoted-api/cache"
+	"github.com/uhppoted/uhppoted-api/config"
+	"github.com/uhppoted/uhppoted-api/monitoring"
+	"github.com/uhppoted/uhppoted-api/retry"
+	"github.com/uhppoted/uhppoted-api/scheduler"
+	"github.com/uhppoted/uhppoted-api/simulator"
+	"github.com/uhppoted/uhppoted-api/uhppoted"
+)
+
+type handler struct{}
+
+func (h *handler) Alive(m monitoring.Monitor, msg string) error {
+	return nil
+}
+
+func (h *handler) Alert(m monitoring.Monitor, msg string) error {
+	return nil
+}
+
+func TestMetricsHandler(t *testing.T) {
+	listen := net.UDPAddr{IP: net.IPv4(192, 168, 1, 100), Port: 60001}
+	device := simulator.NewDevice(405419896, net.IPv4(192, 168, 1, 125))
+	device.Listener = net.UDPAddr{IP: net.IPv4(192, 168, 1, 100), Port: 60002}
+
+	s := simulator.NewSimulator(&listen, device)
+	s.Inject(simulator.Fault{Type: simulator.Timeout, Operation: "get-time", Count: 1})
+	s.Inject(simulator.Fault{Type: simulator.Rejected, Operation: "put-card", Count: 1})
+
+	m := NewMetrics()
+	u := uhppoted.UHPPOTED{
+		UHPPOTE: m.Wrap(s),
+	}
+
+	u.GetTime(uhppoted.GetTimeRequest{DeviceID: 405419896})
+	u.GetTime(uhppoted.GetTimeRequest{DeviceID: 405419896})
+	m.Wrap(s).PutCard(405419896, types.Card{CardNumber: 8165538})
+
+	events := 0
+	f := m.EventHandler(func(e uhppoted.EventMessage) bool {
+		events++
+		return true
+	})
+
+	f(uhppoted.EventMessage{Event: uhppoted.ListenEvent{DeviceID: 405419896, Timestamp: types.DateTime(time.Now().Add(-2 * time.Second))}})
+
+	healthcheck := monitoring.NewHealthCheck(s, monitoring.IDLE, monitoring.IGNORE, nil)
+	healthcheck.Exec(&handler{})
+	m.HealthCheck(&healthcheck)
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+	if ct := w.Header().Get("Content-Type"); ct != ContentType {
+		t.Errorf("Incorrect content type - expected:%v, got:%v", ContentType, ct)
+	}
+
+	body, _ := ioutil.ReadAll(w.Body)
+	expected := []string{
+		`# TYPE uhppoted_requests_total counter`,
+		`uhppoted_requests_total{device="405419896",operation="get-time"} 2`,
+		`uhppoted_request_errors_total{device="405419896",operation="get-time",type="timeout"} 1`,
+		`uhppoted_request_errors_total{device="405419896",operation="put-card",type="rejected"} 1`,
+		`# TYPE uhppoted_request_duration_seconds histogram`,
+		`uhppoted_request_duration_seconds_bucket{device="405419896",operation="get-time",le="+Inf"} 2`,
+		`uhppoted_request_duration_seconds_count{device="405419896",operation="get-time"} 2`,
+		`uhppoted_events_total{device="405419896"} 1`,
+		`uhppoted_event_lag_seconds_bucket{device="405419896",le="1"} 0`,
+		`uhppoted_event_lag_seconds_bucket{device="405419896",le="5"} 1`,
+		`uhppoted_healthcheck_errors{device="405419896"} 1`,
+		`uhppoted_healthcheck_warnings{device="405419896"} 0`,
+	}
+
+	for _, line := range expected {
+		if !strings.Contains(string(body), line+"\n") {
+			t.Errorf("Missing metric '%v'\n%s", line, body)
+		}
+	}
+
+	if events != 1 {
+		t.Errorf("Event not passed to next handler")
+	}
+}
+
+func TestMetricsRetries(t *testing.T) {
+	s := simulator.NewSimulator(nil, simulator.NewDevice(405419896, net.IPv4(192, 168, 1, 125)))
+	s.Inject(simulator.Fault{Type: simulator.DroppedReply, Operation: "get-cards", Count: 2})
+
+	r := retry.NewRetry(s, config.Retry{Attempts: 3, Backoff: time.Millisecond}, nil)
+	m := NewMetrics()
+	m.Retry(r)
+
+	u := uhppoted.UHPPOTED{
+		UHPPOTE: r,
+	}
+
+	if _, err := u.GetCardRecords(uhppoted.GetCardRecordsRequest{DeviceID: 405419896}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var b strings.Builder
+	if err := m.Write(&b); err != nil {
+		t.Fatalf("Unexpected error writing metrics: %v", err)
+	}
+
+	if line := `uhppoted_request_retries_total{device="405419896",operation="get-cards"} 2`; !strings.Contains(b.String(), line+"\n") {
+		t.Errorf("Missing metric '%v'\n%s", line, b.String())
+	}
+}
+
+func TestRetryCountThroughWrappers(t *testing.T) {
+	s := simulator.NewSimulator(nil, simulator.NewDevice(405419896, net.IPv4(192, 168, 1, 125)))
+	s.Inject(simulator.Fault{Type: simulator.DroppedReply, Operation: "get-time", Count: 2})
+
+	r := retry.NewRetry(s, config.Retry{Attempts: 3, Backoff: time.Millisecond}, nil)
+	m := NewMetrics()
+
+	u := uhppoted.UHPPOTED{
+		UHPPOTE: m.Wrap(cache.NewCache(scheduler.NewScheduler(r, scheduler.Config{}), 0)),
+	}
+
+	response, err := u.GetTime(uhppoted.GetTimeRequest{DeviceID: 405419896})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if response.Retries != 2 {
+		t.Errorf("Incorrect retry count in response - expected:%v, got:%v", 2, response.Retries)
+	}
+
+	if r.Retries() != 2 {
+		t.Errorf("Incorrect total retry count - expected:%v, got:%v", 2, r.Retries())
+	}
+}
+
+func TestMetricsConcurrentWrite(t *testing.T) {
+	listen := net.UDPAddr{IP: net.IPv4(192, 168, 1, 100), Port: 60001}
+	s := simulator.NewSimulator(&listen, simulator.NewDevice(405419896, net.IPv4(192, 168, 1, 125)))
+
+	healthcheck := monitoring.NewHealthCheck(s, monitoring.IDLE, monitoring.IGNORE, nil)
+	healthcheck.Exec(&handler{})
+
+	m := NewMetrics()
+	m.HealthCheck(&healthcheck)
+
+	var wg sync.WaitGroup
+	bodies := make([]string, 8)
+	for i := range bodies {
+		wg.Add(1)
+		go func(ix int) {
+			defer wg.Done()
+
+			var b strings.Builder
+			m.Write(&b)
+			bodies[ix] = b.String()
+		}(i)
+	}
+
+	wg.Wait()
+
+	for _, body := range bodies {
+		if line := `uhppoted_healthcheck_errors{device="405419896"} 1`; !strings.Contains(body, line+"\n") {
+			t.Errorf("Missing metric '%v'\n%s", line, body)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type kind string
+
+const (
+	counter   kind = "counter"
+	gauge     kind = "gauge"
+	histogram kind = "histogram"
+)
+
+// A metric family with a fixed set of label names. Series are keyed by their label values.
+type family struct {
+	name    string
+	help    string
+	kind    kind
+	labels  []string
+	buckets []float64
+	guard   sync.Mutex
+	series  map[string]*series
+}
+
+type series struct {
+	labels  []string
+	value   float64
+	buckets []uint64
+	sum     float64
+	count   uint64
+}
+
+func newFamily(name, help string, k kind, buckets []float64, labels ...string) *family {
+	return &family{
+		name:    name,
+		help:    help,
+		kind:    k,
+		labels:  labels,
+		buckets: buckets,
+		series:  map[string]*series{},
+	}
+}
+
+// NOTE: expects the caller to hold the family lock
+func (f *family) get(values []string) *series {
+	key := strings.Join(values, "\xff")
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{
+			labels:  append([]string{}, values...),
+			buckets: make([]uint64, len(f.buckets)),
+		}
+
+		f.series[key] = s
+	}
+
+	return s
+}
+
+func (f *family) add(delta float64, values ...string) {
+	f.guard.Lock()
+	defer f.guard.Unlock()
+
+	f.get(values).value += delta
+}
+
+func (f *family) set(v float64, values ...string) {
+	f.guard.Lock()
+	defer f.guard.Unlock()
+
+	f.get(values).value = v
+}
+
+func (f *family) observe(v float64, values ...string) {
+	f.guard.Lock()
+	defer f.guard.Unlock()
+
+	s := f.get(values)
+	for i, le := range f.buckets {
+		if v <= le {
+			s.buckets[i]++
+		}
+	}
+
+	s.sum += v
+	s.count++
+}
+
+// Writes the metric family in the Prometheus text exposition format (version 0.0.4).
+func (f *family) write(w io.Writer) error {
+	f.guard.Lock()
+	defer f.guard.Unlock()
+
+	keys := []string{}
+	for k := range f.series {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	fmt.Fprintf(w, "# HELP %s %s\n", f.name, f.help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", f.name, f.kind)
+
+	for _, k := range keys {
+		s := f.series[k]
+		labels := f.format(s.labels, "", "")
+
+		switch f.kind {
+		case histogram:
+			for i, le := range f.buckets {
+				fmt.Fprintf(w, "%s_bucket%s %d\n", f.name, f.format(s.labels, "le", number(le)), s.buckets[i])
+			}
+
+			fmt.Fprintf(w, "%s_bucket%s %d\n", f.name, f.format(s.labels, "le", "+Inf"), s.count)
+			fmt.Fprintf(w, "%s_sum%s %s\n", f.name, labels, number(s.sum))
+			if _, err := fmt.Fprintf(w, "%s_count%s %d\n", f.name, labels, s.count); err != nil {
+				return err
+			}
+
+		default:
+			if _, err := fmt.Fprintf(w, "%s%s %s\n", f.name, labels, number(s.value)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (f *family) format(values []string, extra, value string) string {
+	pairs := []string{}
+	for i, l := range f.labels {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, l, escape(values[i])))
+	}
+
+	if extra != "" {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, extra, value))
+	}
+
+	if len(pairs) == 0 {
+		return ""
+	}
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func escape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+func number(v float64) string {
+	switch {
+	case math.IsInf(v, +1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
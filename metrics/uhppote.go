@@ -0,0 +1,231 @@
+package metrics
+
+import (
+	"net"
+	"os"
+	"time"
+
+	"github.com/uhppoted/uhppote-core/types"
+	"github.com/uhppoted/uhppote-core/uhppote"
+	"github.com/uhppoted/uhppoted-api/uhppoted"
+)
+
+type instrumented struct {
+	uhppote uhppote.IUHPPOTE
+	metrics *Metrics
+}
+
+// Passes retry tracking through to the wrapped IUHPPOTE (if it is a uhppoted.RetryTracker).
+func (u *instrumented) Track() uhppoted.TrackedUHPPOTE {
+	if t, ok := u.uhppote.(uhppoted.RetryTracker); ok {
+		tracked := t.Track()
+
+		return uhppoted.Tracked(&instrumented{uhppote: tracked, metrics: u.metrics}, tracked.Retries)
+	}
+
+	return uhppoted.Tracked(u, uhppoted.Untracked)
+}
+
+func (u *instrumented) DeviceList() map[uint32]uhppote.Device {
+	return u.uhppote.DeviceList()
+}
+
+func (u *instrumented) ListenAddr() *net.UDPAddr {
+	return u.uhppote.ListenAddr()
+}
+
+func (u *instrumented) Listen(listener uhppote.Listener, q chan os.Signal) error {
+	return u.uhppote.Listen(listener, q)
+}
+
+func (u *instrumented) GetDevices() ([]types.Device, error) {
+	start := time.Now()
+	response, err := u.uhppote.GetDevices()
+	u.metrics.record(0, "get-devices", start, err, false)
+
+	return response, err
+}
+
+func (u *instrumented) GetDevice(deviceID uint32) (*types.Device, error) {
+	start := time.Now()
+	response, err := u.uhppote.GetDevice(deviceID)
+	u.metrics.record(deviceID, "get-device", start, err, false)
+
+	return response, err
+}
+
+func (u *instrumented) SetAddress(deviceID uint32, address, mask, gateway net.IP) (*types.Result, error) {
+	start := time.Now()
+	response, err := u.uhppote.SetAddress(deviceID, address, mask, gateway)
+	u.metrics.record(deviceID, "set-address", start, err, response != nil && !response.Succeeded)
+
+	return response, err
+}
+
+func (u *instrumented) GetTime(deviceID uint32) (*types.Time, error) {
+	start := time.Now()
+	response, err := u.uhppote.GetTime(deviceID)
+	u.metrics.record(deviceID, "get-time", start, err, false)
+
+	return response, err
+}
+
+func (u *instrumented) SetTime(deviceID uint32, datetime time.Time) (*types.Time, error) {
+	start := time.Now()
+	response, err := u.uhppote.SetTime(deviceID, datetime)
+	u.metrics.record(deviceID, "set-time", start, err, false)
+
+	return response, err
+}
+
+func (u *instrumented) GetDoorControlState(deviceID uint32, door byte) (*types.DoorControlState, error) {
+	start := time.Now()
+	response, err := u.uhppote.GetDoorControlState(deviceID, door)
+	u.metrics.record(deviceID, "get-door-control", start, err, false)
+
+	return response, err
+}
+
+func (u *instrumented) SetDoorControlState(deviceID uint32, door uint8, state uint8, delay uint8) (*types.DoorControlState, error) {
+	start := time.Now()
+	response, err := u.uhppote.SetDoorControlState(deviceID, door, state, delay)
+	u.metrics.record(deviceID, "set-door-control", start, err, false)
+
+	return response, err
+}
+
+func (u *instrumented) GetListener(deviceID uint32) (*types.Listener, error) {
+	start := time.Now()
+	response, err := u.uhppote.GetListener(deviceID)
+	u.metrics.record(deviceID, "get-listener", start, err, false)
+
+	return response, err
+}
+
+func (u *instrumented) SetListener(deviceID uint32, address net.UDPAddr) (*types.Result, error) {
+	start := time.Now()
+	response, err := u.uhppote.SetListener(deviceID, address)
+	u.metrics.record(deviceID, "set-listener", start, err, response != nil && !response.Succeeded)
+
+	return response, err
+}
+
+func (u *instrumented) GetStatus(deviceID uint32) (*types.Status, error) {
+	start := time.Now()
+	response, err := u.uhppote.GetStatus(deviceID)
+	u.metrics.record(deviceID, "get-status", start, err, false)
+
+	return response, err
+}
+
+func (u *instrumented) GetCards(deviceID uint32) (uint32, error) {
+	start := time.Now()
+	response, err := u.uhppote.GetCards(deviceID)
+	u.metrics.record(deviceID, "get-cards", start, err, false)
+
+	return response, err
+}
+
+func (u *instrumented) GetCardByIndex(deviceID, index uint32) (*types.Card, error) {
+	start := time.Now()
+	response, err := u.uhppote.GetCardByIndex(deviceID, index)
+	u.metrics.record(deviceID, "get-card-by-index", start, err, false)
+
+	return response, err
+}
+
+func (u *instrumented) GetCardByID(deviceID, cardNumber uint32) (*types.Card, error) {
+	start := time.Now()
+	response, err := u.uhppote.GetCardByID(deviceID, cardNumber)
+	u.metrics.record(deviceID, "get-card-by-id", start, err, false)
+
+	return response, err
+}
+
+func (u *instrumented) PutCard(deviceID uint32, card types.Card) (bool, error) {
+	start := time.Now()
+	response, err := u.uhppote.PutCard(deviceID, card)
+	u.metrics.record(deviceID, "put-card", start, err, !response)
+
+	return response, err
+}
+
+func (u *instrumented) DeleteCard(deviceID uint32, cardNumber uint32) (bool, error) {
+	start := time.Now()
+	response, err := u.uhppote.DeleteCard(deviceID, cardNumber)
+	u.metrics.record(deviceID, "delete-card", start, err, !response)
+
+	return response, err
+}
+
+func (u *instrumented) DeleteCards(deviceID uint32) (bool, error) {
+	start := time.Now()
+	response, err := u.uhppote.DeleteCards(deviceID)
+	u.metrics.record(deviceID, "delete-cards", start, err, !response)
+
+	return response, err
+}
+
+func (u *instrumented) GetTimeProfile(deviceID uint32, profileID uint8) (*types.TimeProfile, error) {
+	start := time.Now()
+	response, err := u.uhppote.GetTimeProfile(deviceID, profileID)
+	u.metrics.record(deviceID, "get-time-profile", start, err, false)
+
+	return response, err
+}
+
+func (u *instrumented) SetTimeProfile(deviceID uint32, profile types.TimeProfile) (bool, error) {
+	start := time.Now()
+	response, err := u.uhppote.SetTimeProfile(deviceID, profile)
+	u.metrics.record(deviceID, "set-time-profile", start, err, !response)
+
+	return response, err
+}
+
+func (u *instrumented) ClearTimeProfiles(deviceID uint32) (bool, error) {
+	start := time.Now()
+	response, err := u.uhppote.ClearTimeProfiles(deviceID)
+	u.metrics.record(deviceID, "clear-time-profiles", start, err, !response)
+
+	return response, err
+}
+
+func (u *instrumented) RecordSpecialEvents(deviceID uint32, enable bool) (bool, error) {
+	start := time.Now()
+	response, err := u.uhppote.RecordSpecialEvents(deviceID, enable)
+	u.metrics.record(deviceID, "record-special-events", start, err, !response)
+
+	return response, err
+}
+
+func (u *instrumented) GetEvent(deviceID, index uint32) (*types.Event, error) {
+	start := time.Now()
+	response, err := u.uhppote.GetEvent(deviceID, index)
+	u.metrics.record(deviceID, "get-event", start, err, false)
+
+	return response, err
+}
+
+func (u *instrumented) GetEventIndex(deviceID uint32) (*types.EventIndex, error) {
+	start := time.Now()
+	response, err := u.uhppote.GetEventIndex(deviceID)
+	u.metrics.record(deviceID, "get-event-index", start, err, false)
+
+	return response, err
+}
+
+func (u *instrumented) SetEventIndex(deviceID, index uint32) (*types.EventIndexResult, error) {
+	start := time.Now()
+	response, err := u.uhppote.SetEventIndex(deviceID, index)
+	u.metrics.record(deviceID, "set-event-index", start, err, false)
+
+	return response, err
+}
+
+func (u *instrumented) OpenDoor(deviceID uint32, door uint8) (*types.Result, error) {
+	start := time.Now()
+	response, err := u.uhppote.OpenDoor(deviceID, door)
+	u.metrics.record(deviceID, "open-door", start, err, response != nil && !response.Succeeded)
+
+	return response, err
+}
@@ -0,0 +1,146 @@
+package monitoring
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/uhppoted/uhppote-core/types"
+	"github.com/uhppoted/uhppoted-api/logging"
+)
+
+type Severity string
+
+const (
+	Info    Severity = "info"
+	Warning Severity = "warning"
+	Error   Severity = "error"
+)
+
+// Stable alert codes, e.g. for routing or suppressing alerts by type.
+type AlertCode string
+
+const (
+	DeviceMissing      AlertCode = "device-missing"
+	UnexpectedDevice   AlertCode = "unexpected-device"
+	NoResponse         AlertCode = "no-response"
+	ClockDrift         AlertCode = "clock-drift"
+	NoListener         AlertCode = "no-listener"
+	WrongListener      AlertCode = "wrong-listener"
+	HealthCheckStalled AlertCode = "healthcheck-stalled"
+)
+
+type AlertState string
+
+const (
+	Raised  AlertState = "raised"
+	Cleared AlertState = "cleared"
+)
+
+// Alert is a structured health-check or watchdog alert. An alert is raised once when the
+// condition is detected and cleared once when the condition is resolved. DeviceID is 0 for
+// system alerts (e.g. healthcheck-stalled) and Values holds the measured values (e.g. the
+// clock drift) that triggered the alert. Message is the human readable alert text passed to
+// MonitoringHandler.Alert.
+type Alert struct {
+	Timestamp time.Time              `json:"timestamp"`
+	Code      AlertCode              `json:"code"`
+	Severity  Severity               `json:"severity"`
+	State     AlertState             `json:"state"`
+	DeviceID  uint32                 `json:"device-id,omitempty"`
+	Values    map[string]interface{} `json:"values,omitempty"`
+	Message   string                 `json:"message"`
+}
+
+// AlertHandler is an optional interface for a MonitoringHandler that wants structured alerts.
+// Alerts are passed to handlers that do not implement AlertHandler as the alert message.
+type AlertHandler interface {
+	Notify(Monitor, Alert) error
+}
+
+type legacy struct {
+	handler MonitoringHandler
+}
+
+// Returns an AlertHandler for a MonitoringHandler, which is either the MonitoringHandler itself
+// if it implements AlertHandler or an adapter that passes the alert message to
+// MonitoringHandler.Alert.
+func NewAlertAdapter(handler MonitoringHandler) AlertHandler {
+	if h, ok := handler.(AlertHandler); ok {
+		return h
+	}
+
+	return &legacy{handler}
+}
+
+func (l *legacy) Notify(m Monitor, alert Alert) error {
+	return l.handler.Alert(m, alert.Message)
+}
+
+func (a Alert) String() string {
+	return a.Message
+}
+
+// Default severity for a raised alert. Error alerts for unconfigured devices are downgraded to
+// warnings.
+var severities = map[AlertCode]Severity{
+	DeviceMissing:      Error,
+	UnexpectedDevice:   Warning,
+	NoResponse:         Error,
+	ClockDrift:         Error,
+	NoListener:         Warning,
+	WrongListener:      Warning,
+	HealthCheckStalled: Error,
+}
+
+func raise(h *HealthCheck, handler MonitoringHandler, deviceID uint32, code AlertCode, values map[string]interface{}, message string) bool {
+	severity := severities[code]
+	if severity == Error && !h.isKnown(deviceID) {
+		severity = Warning
+	}
+
+	return notify(h, h.log, handler, Alert{
+		Timestamp: time.Now(),
+		Code:      code,
+		Severity:  severity,
+		State:     Raised,
+		DeviceID:  deviceID,
+		Values:    values,
+		Message:   fmt.Sprintf("UTC0311-L0x %s %s", types.SerialNumber(deviceID), message),
+	})
+}
+
+func resolve(h *HealthCheck, handler MonitoringHandler, deviceID uint32, code AlertCode, values map[string]interface{}, message string) bool {
+	return notify(h, h.log, handler, Alert{
+		Timestamp: time.Now(),
+		Code:      code,
+		Severity:  Info,
+		State:     Cleared,
+		DeviceID:  deviceID,
+		Values:    values,
+		Message:   fmt.Sprintf("UTC0311-L0x %s %s", types.SerialNumber(deviceID), message),
+	})
+}
+
+// Logs the alert and passes it to the handler. Returns false if the handler could not process
+// the alert.
+func notify(m Monitor, log logging.Logger, handler MonitoringHandler, alert Alert) bool {
+	fields := []logging.Field{logging.Operation(m.ID()), logging.KV("alert", alert.Code)}
+	if alert.DeviceID != 0 {
+		fields = append(fields, logging.DeviceID(alert.DeviceID))
+	}
+
+	switch alert.Severity {
+	case Error:
+		log.Error(alert.Message, fields...)
+	case Warning:
+		log.Warn(alert.Message, fields...)
+	default:
+		log.Info(alert.Message, fields...)
+	}
+
+	if err := NewAlertAdapter(handler).Notify(m, alert); err != nil {
+		return false
+	}
+
+	return true
+}
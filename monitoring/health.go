@@ -0,0 +1,171 @@
+package monitoring
+
+import (
+	"net"
+	"sort"
+	"time"
+
+	"github.com/uhppoted/uhppoted-api/internal/wallclock"
+)
+
+// Health is a snapshot of the health-check and watchdog state, e.g. for rendering system health
+// on a dashboard.
+type Health struct {
+	Timestamp   time.Time         `json:"timestamp"`
+	HealthCheck HealthCheckHealth `json:"health-check"`
+	Watchdog    *WatchdogHealth   `json:"watchdog,omitempty"`
+	Devices     []DeviceHealth    `json:"devices"`
+}
+
+type HealthCheckHealth struct {
+	Started  time.Time  `json:"started"`
+	LastRun  *time.Time `json:"last-run,omitempty"`
+	Warnings uint       `json:"warnings"`
+	Errors   uint       `json:"errors"`
+}
+
+type WatchdogHealth struct {
+	Started            time.Time `json:"started"`
+	HealthCheckRunning bool      `json:"health-check-running"`
+	Alerts             []string  `json:"alerts"`
+}
+
+// DeviceHealth is the most recent health-check state for a device. Drift is the controller clock
+// offset (in the controller time zone) from the time it was polled and Alerts are the currently
+// active alerts for the device.
+type DeviceHealth struct {
+	DeviceID        uint32         `json:"device-id"`
+	Known           bool           `json:"known"`
+	LastSeen        *time.Time     `json:"last-seen,omitempty"`
+	Drift           *time.Duration `json:"drift,omitempty"`
+	Listener        *net.UDPAddr   `json:"listener,omitempty"`
+	ListenerCorrect bool           `json:"listener-correct"`
+	Alerts          []string       `json:"alerts"`
+	Warnings        uint           `json:"warnings"`
+	Errors          uint           `json:"errors"`
+}
+
+// Returns a snapshot of the health-check state for the configured and discovered devices.
+func (h *HealthCheck) GetHealth() Health {
+	h.guard.RLock()
+	health := Health{
+		Timestamp: time.Now(),
+		HealthCheck: HealthCheckHealth{
+			Started:  h.state.Started,
+			LastRun:  h.state.Touched,
+			Warnings: h.state.Warnings,
+			Errors:   h.state.Errors,
+		},
+		Devices: []DeviceHealth{},
+	}
+	h.guard.RUnlock()
+
+	configured := h.uhppote.DeviceList()
+	devices := map[uint32]bool{}
+
+	for id := range configured {
+		devices[id] = true
+	}
+
+	h.state.Devices.Status.Range(func(key, value interface{}) bool {
+		devices[key.(uint32)] = true
+		return true
+	})
+
+	counts := h.Counts()
+	expected := h.uhppote.ListenAddr()
+
+	for id := range devices {
+		_, known := configured[id]
+		device := DeviceHealth{
+			DeviceID: id,
+			Known:    known,
+			Alerts:   []string{},
+			Warnings: counts[id].Warnings,
+			Errors:   counts[id].Errors,
+		}
+
+		if v, ok := h.state.Devices.Status.Load(id); ok {
+			tz := time.Local
+			if d, ok := configured[id]; ok && d.TimeZone != nil {
+				tz = d.TimeZone
+			}
+
+			touched := v.(status).Touched
+			drift := wallclock.In(time.Time(v.(status).Status.SystemDateTime), tz).Sub(touched).Round(time.Second)
+
+			device.LastSeen = &touched
+			device.Drift = &drift
+		}
+
+		if v, ok := h.state.Devices.Listener.Load(id); ok {
+			address := v.(listener).Address
+
+			device.Listener = &address
+			device.ListenerCorrect = expected == nil || (expected.IP.Equal(address.IP) && expected.Port == address.Port)
+		}
+
+		if v, ok := h.state.Devices.Errors.Load(id); ok {
+			device.Alerts = v.(alerts).active()
+		}
+
+		health.Devices = append(health.Devices, device)
+	}
+
+	sort.Slice(health.Devices, func(i, j int) bool { return health.Devices[i].DeviceID < health.Devices[j].DeviceID })
+
+	return health
+}
+
+// Returns a snapshot of the health-check state and the watchdog state.
+func (w *Watchdog) GetHealth() Health {
+	health := w.healthcheck.GetHealth()
+
+	watchdog := WatchdogHealth{
+		Started:            w.state.Started,
+		HealthCheckRunning: w.healthCheckRunning(),
+		Alerts:             []string{},
+	}
+
+	w.guard.RLock()
+	alerted := w.state.HealthCheck.Alerted
+	w.guard.RUnlock()
+
+	if alerted {
+		watchdog.Alerts = append(watchdog.Alerts, string(HealthCheckStalled))
+	}
+
+	health.Watchdog = &watchdog
+
+	return health
+}
+
+func (a alerts) active() []string {
+	list := []string{}
+
+	if a.missing {
+		list = append(list, string(DeviceMissing))
+	}
+
+	if a.unexpected {
+		list = append(list, string(UnexpectedDevice))
+	}
+
+	if a.touched {
+		list = append(list, string(NoResponse))
+	}
+
+	if a.synchronized {
+		list = append(list, string(ClockDrift))
+	}
+
+	if a.nolistener {
+		list = append(list, string(NoListener))
+	}
+
+	if a.listener {
+		list = append(list, string(WrongListener))
+	}
+
+	return list
+}
@@ -4,25 +4,34 @@ import (
 	"fmt"
 	"github.com/uhppoted/uhppote-core/types"
 	"github.com/uhppoted/uhppote-core/uhppote"
+	"github.com/uhppoted/uhppoted-api/internal/wallclock"
+	"github.com/uhppoted/uhppoted-api/logging"
 	"log"
 	"math"
 	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 type HealthCheck struct {
-	uhppote    uhppote.IUHPPOTE
-	idleTime   time.Duration
-	ignoreTime time.Duration
-	log        *log.Logger
-	state      struct {
+	uhppote     uhppote.IUHPPOTE
+	idleTime    time.Duration
+	ignoreTime  time.Duration
+	log         logging.Logger
+	remediation *Remediation
+	polling     Polling
+	guard       sync.RWMutex
+	state       struct {
 		Started time.Time
 		Touched *time.Time
 		Devices struct {
-			Status   sync.Map
-			Listener sync.Map
-			Errors   sync.Map
+			Status     sync.Map
+			Listener   sync.Map
+			Errors     sync.Map
+			Counts     sync.Map
+			Remediated sync.Map
+			Polls      sync.Map
 		}
 		Warnings uint
 		Errors   uint
@@ -34,6 +43,43 @@ type status struct {
 	Status  types.Status
 }
 
+// Per-device health-check warning and error counts for the most recent health-check.
+type Counts struct {
+	Warnings uint
+	Errors   uint
+}
+
+// Per-device health-check poll statistics. Latency is the time taken to poll the device in the
+// most recent health-check and Timeouts is the number of polls that did not get a reply to every
+// request or were not completed within the health-check deadline.
+type Polls struct {
+	Latency  time.Duration
+	Polls    uint64
+	Timeouts uint64
+}
+
+// Health-check polling configuration. Workers is the maximum number of devices polled
+// concurrently (defaults to WORKERS) and Deadline is the maximum time allowed for polling all
+// the devices in a single health-check (defaults to DEADLINE).
+type Polling struct {
+	Workers  int
+	Deadline time.Duration
+}
+
+type poll struct {
+	latency  int64
+	polls    uint64
+	timeouts uint64
+}
+
+// The result of polling a single device (status and listener are nil if the request failed).
+type pollResult struct {
+	status   *status
+	listener *listener
+	latency  time.Duration
+	ok       bool
+}
+
 type listener struct {
 	Touched time.Time
 	Address net.UDPAddr
@@ -49,6 +95,15 @@ type alerts struct {
 }
 
 func NewHealthCheck(u uhppote.IUHPPOTE, idleTime, ignoreTime time.Duration, l *log.Logger) HealthCheck {
+	return NewHealthCheckWithLogger(u, idleTime, ignoreTime, logging.NewLogAdapter(l, logging.DEBUG))
+}
+
+// Creates a HealthCheck that logs to a structured logger.
+func NewHealthCheckWithLogger(u uhppote.IUHPPOTE, idleTime, ignoreTime time.Duration, l logging.Logger) HealthCheck {
+	if l == nil {
+		l = logging.NewNopLogger()
+	}
+
 	return HealthCheck{
 		uhppote:    u,
 		idleTime:   idleTime,
@@ -58,9 +113,12 @@ func NewHealthCheck(u uhppote.IUHPPOTE, idleTime, ignoreTime time.Duration, l *l
 			Started time.Time
 			Touched *time.Time
 			Devices struct {
-				Status   sync.Map
-				Listener sync.Map
-				Errors   sync.Map
+				Status     sync.Map
+				Listener   sync.Map
+				Errors     sync.Map
+				Counts     sync.Map
+				Remediated sync.Map
+				Polls      sync.Map
 			}
 			Warnings uint
 			Errors   uint
@@ -68,13 +126,19 @@ func NewHealthCheck(u uhppote.IUHPPOTE, idleTime, ignoreTime time.Duration, l *l
 			Started: time.Now(),
 			Touched: nil,
 			Devices: struct {
-				Status   sync.Map
-				Listener sync.Map
-				Errors   sync.Map
+				Status     sync.Map
+				Listener   sync.Map
+				Errors     sync.Map
+				Counts     sync.Map
+				Remediated sync.Map
+				Polls      sync.Map
 			}{
-				Status:   sync.Map{},
-				Listener: sync.Map{},
-				Errors:   sync.Map{},
+				Status:     sync.Map{},
+				Listener:   sync.Map{},
+				Errors:     sync.Map{},
+				Counts:     sync.Map{},
+				Remediated: sync.Map{},
+				Polls:      sync.Map{},
 			},
 			Warnings: 0,
 			Errors:   0,
@@ -86,8 +150,45 @@ func (h *HealthCheck) ID() string {
 	return "health-check"
 }
 
+// Returns the warning and error counts for each device from the most recent health-check.
+func (h *HealthCheck) Counts() map[uint32]Counts {
+	counts := map[uint32]Counts{}
+
+	h.state.Devices.Counts.Range(func(key, value interface{}) bool {
+		counts[key.(uint32)] = value.(Counts)
+		return true
+	})
+
+	return counts
+}
+
+// Returns the poll latency and timeout counts for each device.
+func (h *HealthCheck) Polls() map[uint32]Polls {
+	polls := map[uint32]Polls{}
+
+	h.state.Devices.Polls.Range(func(key, value interface{}) bool {
+		p := value.(*poll)
+		polls[key.(uint32)] = Polls{
+			Latency:  time.Duration(atomic.LoadInt64(&p.latency)),
+			Polls:    atomic.LoadUint64(&p.polls),
+			Timeouts: atomic.LoadUint64(&p.timeouts),
+		}
+
+		return true
+	})
+
+	return polls
+}
+
+// Sets the number of concurrent device polls and the per-cycle polling deadline.
+func (h *HealthCheck) SetPolling(polling Polling) {
+	h.guard.Lock()
+	h.polling = polling
+	h.guard.Unlock()
+}
+
 func (h *HealthCheck) Exec(handler MonitoringHandler) {
-	h.log.Printf("DEBUG %-20s", "health-check")
+	h.log.Debug("health-check", logging.Operation("health-check"))
 
 	now := time.Now()
 	errors := uint(0)
@@ -103,35 +204,40 @@ func (h *HealthCheck) Exec(handler MonitoringHandler) {
 	errors += e
 	warnings += w
 
+	h.guard.Lock()
 	h.state.Warnings = warnings
 	h.state.Errors = errors
+	h.guard.Unlock()
 
 	// 'k, done
 
-	level := "INFO"
 	msg := "OK"
 
 	if errors > 0 && warnings > 0 {
-		level = "WARN"
 		msg = fmt.Sprintf("%s, %s", Errors(errors), Warnings(warnings))
 	} else if errors > 0 {
-		level = "WARN"
 		msg = fmt.Sprintf("%s", Errors(errors))
 	} else if warnings > 0 {
-		level = "WARN"
 		msg = fmt.Sprintf("%s", Warnings(warnings))
 	}
 
-	h.log.Printf("%-5s %-12s %s", level, "health-check", msg)
+	if errors > 0 || warnings > 0 {
+		h.log.Warn(msg, logging.Operation("health-check"))
+	} else {
+		h.log.Info(msg, logging.Operation("health-check"))
+	}
 	handler.Alive(h, msg)
 }
 
+// Polls the known and discovered devices concurrently (bounded by the polling 'workers') and
+// waits for at most the polling 'deadline'. Devices that have not been polled by the deadline
+// are counted as timeouts and retain their previous status and listener.
 func (h *HealthCheck) update(now time.Time) {
 	devices := make(map[uint32]bool)
 
 	found, err := h.uhppote.GetDevices()
 	if err != nil {
-		h.log.Printf("WARN  'keep-alive' error: %v", err)
+		h.log.Warn(fmt.Sprintf("'keep-alive' error: %v", err), logging.Operation("health-check"))
 	}
 
 	if found != nil {
@@ -144,25 +250,143 @@ func (h *HealthCheck) update(now time.Time) {
 		devices[id] = true
 	}
 
+	h.guard.RLock()
+	polling := h.polling
+	h.guard.RUnlock()
+
+	workers := polling.Workers
+	if workers <= 0 {
+		workers = WORKERS
+	}
+
+	deadline := polling.Deadline
+	if deadline <= 0 {
+		deadline = DEADLINE
+	}
+
+	queue := make(chan uint32, len(devices))
 	for id, _ := range devices {
-		s, err := h.uhppote.GetStatus(id)
-		if err == nil {
-			h.state.Devices.Status.Store(id, status{
-				Status:  *s,
-				Touched: now,
-			})
+		queue <- id
+	}
+
+	close(queue)
+
+	var wg sync.WaitGroup
+	var expired int32
+	var pending sync.Map
+
+	for id, _ := range devices {
+		pending.Store(id, true)
+	}
+
+	for i := 0; i < workers && i < len(devices); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range queue {
+				if atomic.LoadInt32(&expired) != 0 {
+					return
+				}
+
+				// ... results that arrive after the deadline are discarded (the device has
+				//     already been counted as a timeout and retains its previous state)
+				result := h.poll(id, now)
+				if atomic.LoadInt32(&expired) != 0 {
+					return
+				}
+
+				if _, loaded := pending.LoadAndDelete(id); loaded {
+					if result.status != nil {
+						h.state.Devices.Status.Store(id, *result.status)
+					}
+
+					if result.listener != nil {
+						h.state.Devices.Listener.Store(id, *result.listener)
+					}
+
+					stats := h.stats(id)
+					stats.polled(result.latency)
+					if !result.ok {
+						stats.timeout()
+					}
+				}
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	timer := time.NewTimer(deadline)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+	case <-timer.C:
+		atomic.StoreInt32(&expired, 1)
+		h.log.Warn(fmt.Sprintf("health-check poll exceeded deadline (%v)", deadline), logging.Operation("health-check"))
+
+		pending.Range(func(key, value interface{}) bool {
+			if _, loaded := pending.LoadAndDelete(key); loaded {
+				h.stats(key.(uint32)).timeout()
+			}
+
+			return true
+		})
+	}
+
+	h.guard.Lock()
+	h.state.Touched = &now
+	h.guard.Unlock()
+}
+
+func (h *HealthCheck) poll(id uint32, now time.Time) pollResult {
+	start := time.Now()
+	result := pollResult{
+		ok: true,
+	}
+
+	s, err := h.uhppote.GetStatus(id)
+	if err == nil && s != nil {
+		result.status = &status{
+			Status:  *s,
+			Touched: now,
 		}
+	} else {
+		result.ok = false
+	}
 
-		l, err := h.uhppote.GetListener(id)
-		if err == nil && l != nil {
-			h.state.Devices.Listener.Store(id, listener{
-				Address: l.Address,
-				Touched: now,
-			})
+	l, err := h.uhppote.GetListener(id)
+	if err == nil && l != nil {
+		result.listener = &listener{
+			Address: l.Address,
+			Touched: now,
 		}
+	} else {
+		result.ok = false
 	}
 
-	h.state.Touched = &now
+	result.latency = time.Since(start)
+
+	return result
+}
+
+func (h *HealthCheck) stats(id uint32) *poll {
+	v, _ := h.state.Devices.Polls.LoadOrStore(id, &poll{})
+
+	return v.(*poll)
+}
+
+func (p *poll) polled(latency time.Duration) {
+	atomic.StoreInt64(&p.latency, int64(latency))
+	atomic.AddUint64(&p.polls, 1)
+}
+
+func (p *poll) timeout() {
+	atomic.AddUint64(&p.timeouts, 1)
 }
 
 // Check known/identified devices
@@ -171,6 +395,7 @@ func (h *HealthCheck) known(now time.Time, handler MonitoringHandler) (uint, uin
 	warnings := uint(0)
 
 	for id, _ := range h.uhppote.DeviceList() {
+		e0, w0 := errors, warnings
 		alerted := alerts{
 			missing:      false,
 			unexpected:   false,
@@ -192,13 +417,13 @@ func (h *HealthCheck) known(now time.Time, handler MonitoringHandler) (uint, uin
 		if _, found := h.state.Devices.Status.Load(id); !found {
 			errors += 1
 			if !alerted.missing {
-				if alert(h, handler, id, "device not found") {
+				if raise(h, handler, id, DeviceMissing, nil, "device not found") {
 					alerted.missing = true
 				}
 			}
 		} else {
 			if alerted.missing {
-				if info(h, handler, id, "device present") {
+				if resolve(h, handler, id, DeviceMissing, nil, "device present") {
 					alerted.missing = false
 				}
 			}
@@ -213,6 +438,7 @@ func (h *HealthCheck) known(now time.Time, handler MonitoringHandler) (uint, uin
 		warnings += w
 
 		h.state.Devices.Errors.Store(id, alerted)
+		h.state.Devices.Counts.Store(id, Counts{Errors: errors - e0, Warnings: warnings - w0})
 	}
 
 	return errors, warnings
@@ -243,7 +469,7 @@ func (h *HealthCheck) unexpected(now time.Time, handler MonitoringHandler) (uint
 		for id, _ := range h.uhppote.DeviceList() {
 			if id == key {
 				if alerted.unexpected {
-					if alert(h, handler, key.(uint32), "added to configuration") {
+					if resolve(h, handler, key.(uint32), UnexpectedDevice, nil, "added to configuration") {
 						alerted.unexpected = false
 						h.state.Devices.Errors.Store(id, alerted)
 					}
@@ -257,14 +483,17 @@ func (h *HealthCheck) unexpected(now time.Time, handler MonitoringHandler) (uint
 		if now.After(touched.Add(h.ignoreTime)) {
 			h.state.Devices.Status.Delete(key)
 			h.state.Devices.Errors.Delete(key)
+			h.state.Devices.Counts.Delete(key)
 
 			if alerted.unexpected {
-				warn(h, handler, key.(uint32), "disappeared")
+				resolve(h, handler, key.(uint32), UnexpectedDevice, nil, "disappeared")
 			}
 		} else {
+			e0, w0 := errors, warnings
+
 			warnings += 1
 			if !alerted.unexpected {
-				if warn(h, handler, key.(uint32), "unexpected device") {
+				if raise(h, handler, key.(uint32), UnexpectedDevice, nil, "unexpected device") {
 					alerted.unexpected = true
 				}
 			}
@@ -278,6 +507,7 @@ func (h *HealthCheck) unexpected(now time.Time, handler MonitoringHandler) (uint
 			warnings += w
 
 			h.state.Devices.Errors.Store(key, alerted)
+			h.state.Devices.Counts.Store(key, Counts{Errors: errors - e0, Warnings: warnings - w0})
 		}
 
 		return true
@@ -293,8 +523,13 @@ func (h *HealthCheck) checkStatus(id uint32, now time.Time, alerted *alerts, han
 	warnings := uint(0)
 
 	if v, found := h.state.Devices.Status.Load(id); found {
+		tz := time.Local
+		if d, ok := h.uhppote.DeviceList()[id]; ok && d.TimeZone != nil {
+			tz = d.TimeZone
+		}
+
 		touched := v.(status).Touched
-		t := time.Time(v.(status).Status.SystemDateTime)
+		t := wallclock.In(time.Time(v.(status).Status.SystemDateTime), tz)
 		dt := time.Since(t).Round(time.Second)
 		dtt := int64(math.Abs(time.Since(touched).Seconds()))
 
@@ -306,15 +541,16 @@ func (h *HealthCheck) checkStatus(id uint32, now time.Time, alerted *alerts, han
 			}
 
 			if !alerted.touched {
-				msg := fmt.Sprintf("no response for %s", time.Since(touched).Round(time.Second))
-				if alert(h, handler, id, msg) {
+				elapsed := time.Since(touched).Round(time.Second)
+				values := map[string]interface{}{"last-seen": touched, "elapsed": elapsed}
+				msg := fmt.Sprintf("no response for %s", elapsed)
+				if raise(h, handler, id, NoResponse, values, msg) {
 					alerted.touched = true
-					alerted.synchronized = false
 				}
 			}
 		} else {
 			if alerted.touched {
-				if info(h, handler, id, "connected") {
+				if resolve(h, handler, id, NoResponse, nil, "connected") {
 					alerted.touched = false
 				}
 			}
@@ -329,15 +565,21 @@ func (h *HealthCheck) checkStatus(id uint32, now time.Time, alerted *alerts, han
 				}
 
 				if !alerted.synchronized {
+					values := map[string]interface{}{"date-time": types.DateTime(t), "drift": dt}
 					msg := fmt.Sprintf("system time not synchronized:%v (%v)", types.DateTime(t), dt)
-					if alert(h, handler, id, msg) {
+					if raise(h, handler, id, ClockDrift, values, msg) {
 						alerted.synchronized = true
 					}
 				}
+
+				if known {
+					h.remediateTime(id, t, tz, handler)
+				}
 			} else {
 				if alerted.synchronized {
+					values := map[string]interface{}{"date-time": types.DateTime(t), "drift": dt}
 					msg := fmt.Sprintf("system time synchronized:%v (%v)", types.DateTime(t), dt)
-					if info(h, handler, id, msg) {
+					if resolve(h, handler, id, ClockDrift, values, msg) {
 						alerted.synchronized = false
 					}
 				}
@@ -369,14 +611,16 @@ func (h *HealthCheck) checkListener(id uint32, now time.Time, alerted *alerts, h
 			}
 
 			if !alerted.nolistener {
-				msg := fmt.Sprintf("no reply to 'get-listener' for %s", time.Since(touched).Round(time.Second))
-				if warn(h, handler, id, msg) {
+				elapsed := time.Since(touched).Round(time.Second)
+				values := map[string]interface{}{"last-seen": touched, "elapsed": elapsed}
+				msg := fmt.Sprintf("no reply to 'get-listener' for %s", elapsed)
+				if raise(h, handler, id, NoListener, values, msg) {
 					alerted.nolistener = true
 				}
 			}
 		} else {
 			if alerted.nolistener {
-				if info(h, handler, id, "listener identified") {
+				if resolve(h, handler, id, NoListener, nil, "listener identified") {
 					alerted.nolistener = false
 				}
 			}
@@ -390,14 +634,19 @@ func (h *HealthCheck) checkListener(id uint32, now time.Time, alerted *alerts, h
 			}
 
 			if !alerted.listener {
+				values := map[string]interface{}{"listener": address.String(), "expected": expected.String()}
 				msg := fmt.Sprintf("incorrect listener address/port: %s", &address)
-				if warn(h, handler, id, msg) {
+				if raise(h, handler, id, WrongListener, values, msg) {
 					alerted.listener = true
 				}
 			}
+
+			if known {
+				h.remediateListener(id, address, handler)
+			}
 		} else {
 			if alerted.listener {
-				if info(h, handler, id, "listener address/port correct") {
+				if resolve(h, handler, id, WrongListener, map[string]interface{}{"listener": address.String()}, "listener address/port correct") {
 					alerted.listener = false
 				}
 			}
@@ -407,47 +656,8 @@ func (h *HealthCheck) checkListener(id uint32, now time.Time, alerted *alerts, h
 	return errors, warnings
 }
 
-func info(h *HealthCheck, handler MonitoringHandler, deviceID uint32, message string) bool {
-	msg := fmt.Sprintf("UTC0311-L0x %s %s", types.SerialNumber(deviceID), message)
-
-	h.log.Printf("%-5s %s", "INFO", msg)
-	if err := handler.Alert(h, msg); err != nil {
-		return false
-	}
-
-	return true
-}
-
-func warn(h *HealthCheck, handler MonitoringHandler, deviceID uint32, message string) bool {
-	msg := fmt.Sprintf("UTC0311-L0x %s %s", types.SerialNumber(deviceID), message)
-
-	h.log.Printf("%-5s %s", "WARN", msg)
-	if err := handler.Alert(h, msg); err != nil {
-		return false
-	}
-
-	return true
-}
-
-func alert(h *HealthCheck, handler MonitoringHandler, deviceID uint32, message string) bool {
-	msg := fmt.Sprintf("UTC0311-L0x %s %s", types.SerialNumber(deviceID), message)
-	known := false
-
-	for id, _ := range h.uhppote.DeviceList() {
-		if deviceID == id {
-			known = true
-		}
-	}
-
-	if known {
-		h.log.Printf("%-5s %s", "ERROR", msg)
-	} else {
-		h.log.Printf("%-5s %s", "WARN", msg)
-	}
-
-	if err := handler.Alert(h, msg); err != nil {
-		return false
-	}
+func (h *HealthCheck) isKnown(deviceID uint32) bool {
+	_, ok := h.uhppote.DeviceList()[deviceID]
 
-	return true
+	return ok
 }
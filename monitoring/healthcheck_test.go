@@ -0,0 +1,391 @@
+package monitoring
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/uhppoted/uhppoted-api/logging"
+	"github.com/uhppoted/uhppoted-api/simulator"
+)
+
+type handler struct {
+	alive  []string
+	alerts []string
+}
+
+func (h *handler) Alive(m Monitor, msg string) error {
+	h.alive = append(h.alive, msg)
+	return nil
+}
+
+func (h *handler) Alert(m Monitor, msg string) error {
+	h.alerts = append(h.alerts, msg)
+	return nil
+}
+
+func TestHealthCheckWithSimulator(t *testing.T) {
+	listen := net.UDPAddr{IP: net.IPv4(192, 168, 1, 100), Port: 60001}
+
+	known := simulator.NewDevice(405419896, net.IPv4(192, 168, 1, 125))
+	known.Listener = net.UDPAddr{IP: net.IPv4(192, 168, 1, 100), Port: 60002}
+
+	unknown := simulator.NewDevice(303986753, net.IPv4(192, 168, 1, 126))
+	unknown.Listener = listen
+	unknown.Unconfigured = true
+
+	s := simulator.NewSimulator(&listen, known, unknown)
+	h := handler{}
+
+	healthcheck := NewHealthCheck(s, IDLE, IGNORE, log.New(ioutil.Discard, "", 0))
+	healthcheck.Exec(&h)
+
+	expected := []string{
+		"UTC0311-L0x 405419896  incorrect listener address/port: 192.168.1.100:60002",
+		"UTC0311-L0x 303986753  unexpected device",
+	}
+
+	if len(h.alerts) != len(expected) {
+		t.Fatalf("Incorrect alerts - expected:%v, got:%v", expected, h.alerts)
+	}
+
+	for i, v := range expected {
+		if h.alerts[i] != v {
+			t.Errorf("Incorrect alert %v - expected:'%v', got:'%v'", i+1, v, h.alerts[i])
+		}
+	}
+
+	if len(h.alive) != 1 || !strings.Contains(h.alive[0], "1 error, 1 warning") {
+		t.Errorf("Incorrect health-check summary - expected:'%v', got:%v", "1 error, 1 warning", h.alive)
+	}
+
+	// ... fix listener and time out the unexpected device
+	s.SetListener(405419896, listen)
+	s.Remove(303986753)
+
+	h = handler{}
+	healthcheck.ignoreTime = 0
+	time.Sleep(10 * time.Millisecond)
+	healthcheck.Exec(&h)
+
+	if len(h.alerts) != 2 || h.alerts[0] != "UTC0311-L0x 405419896  listener address/port correct" || h.alerts[1] != "UTC0311-L0x 303986753  disappeared" {
+		t.Errorf("Incorrect alerts - got:%v", h.alerts)
+	}
+}
+
+type auditor struct {
+	handler
+	records []AuditRecord
+}
+
+func (a *auditor) Audit(m Monitor, record AuditRecord) error {
+	a.records = append(a.records, record)
+	return nil
+}
+
+func TestHealthCheckRemediation(t *testing.T) {
+	listen := net.UDPAddr{IP: net.IPv4(192, 168, 1, 100), Port: 60001}
+
+	device := simulator.NewDevice(405419896, net.IPv4(192, 168, 1, 125))
+	device.Listener = net.UDPAddr{IP: net.IPv4(192, 168, 1, 100), Port: 60002}
+
+	s := simulator.NewSimulator(&listen, device)
+	s.SetTime(405419896, time.Now().Add(10*time.Minute))
+
+	a := auditor{}
+
+	healthcheck := NewHealthCheckWithLogger(s, IDLE, IGNORE, logging.NewNopLogger())
+	healthcheck.SetRemediation(Remediation{Listener: true, Time: true})
+	healthcheck.Exec(&a)
+
+	if len(a.records) != 2 {
+		t.Fatalf("Incorrect audit records - expected:%v, got:%v", 2, a.records)
+	}
+
+	if r := a.records[0]; r.Action != "set-time" || r.DeviceID != 405419896 || r.DryRun || r.Error != "" {
+		t.Errorf("Incorrect audit record - got:%+v", r)
+	}
+
+	if r := a.records[1]; r.Action != "set-listener" || r.Before != "192.168.1.100:60002" || r.After != "192.168.1.100:60001" || r.Error != "" {
+		t.Errorf("Incorrect audit record - got:%+v", r)
+	}
+
+	if l, _ := s.GetListener(405419896); l == nil || l.Address.Port != 60001 {
+		t.Errorf("Listener not restored - got:%v", l)
+	}
+
+	if v, _ := s.GetTime(405419896); time.Since(time.Time(v.DateTime)) > 5*time.Second || time.Since(time.Time(v.DateTime)) < -5*time.Second {
+		t.Errorf("System time not synchronized - got:%v", v.DateTime)
+	}
+
+	// ... rate limited
+	s.SetListener(405419896, net.UDPAddr{IP: net.IPv4(192, 168, 1, 100), Port: 60002})
+	a.records = nil
+	healthcheck.Exec(&a)
+
+	if len(a.records) != 0 {
+		t.Errorf("Expected rate limited remediation - got:%v", a.records)
+	}
+}
+
+func TestHealthCheckRemediationDryRun(t *testing.T) {
+	listen := net.UDPAddr{IP: net.IPv4(192, 168, 1, 100), Port: 60001}
+
+	device := simulator.NewDevice(405419896, net.IPv4(192, 168, 1, 125))
+	device.Listener = net.UDPAddr{IP: net.IPv4(192, 168, 1, 100), Port: 60002}
+
+	s := simulator.NewSimulator(&listen, device)
+	h := handler{}
+
+	healthcheck := NewHealthCheckWithLogger(s, IDLE, IGNORE, logging.NewNopLogger())
+	healthcheck.SetRemediation(Remediation{Listener: true, DryRun: true})
+	healthcheck.Exec(&h)
+
+	expected := []string{
+		"UTC0311-L0x 405419896  incorrect listener address/port: 192.168.1.100:60002",
+		"UTC0311-L0x 405419896  remediation: set-listener (incorrect listener address/port) 192.168.1.100:60002 -> 192.168.1.100:60001 [dry-run]",
+	}
+
+	if len(h.alerts) != len(expected) {
+		t.Fatalf("Incorrect alerts - expected:%v, got:%v", expected, h.alerts)
+	}
+
+	for i, v := range expected {
+		if h.alerts[i] != v {
+			t.Errorf("Incorrect alert %v - expected:'%v', got:'%v'", i+1, v, h.alerts[i])
+		}
+	}
+
+	if l, _ := s.GetListener(405419896); l == nil || l.Address.Port != 60002 {
+		t.Errorf("Listener updated in dry-run mode - got:%v", l)
+	}
+}
+
+func TestHealthCheckPollingDeadline(t *testing.T) {
+	listen := net.UDPAddr{IP: net.IPv4(192, 168, 1, 100), Port: 60001}
+
+	s := simulator.NewSimulator(&listen,
+		simulator.NewDevice(405419896, net.IPv4(192, 168, 1, 125)),
+		simulator.NewDevice(303986753, net.IPv4(192, 168, 1, 126)),
+		simulator.NewDevice(201020304, net.IPv4(192, 168, 1, 127)),
+		simulator.NewDevice(102030405, net.IPv4(192, 168, 1, 128)))
+
+	s.Inject(simulator.Fault{Type: simulator.Timeout, DeviceID: 405419896, Operation: "get-status", Delay: 500 * time.Millisecond})
+	s.Inject(simulator.Fault{Type: simulator.Timeout, DeviceID: 303986753, Operation: "get-listener"})
+
+	healthcheck := NewHealthCheckWithLogger(s, IDLE, IGNORE, logging.NewNopLogger())
+	healthcheck.SetPolling(Polling{Workers: 2, Deadline: 100 * time.Millisecond})
+
+	start := time.Now()
+	healthcheck.Exec(&handler{})
+
+	if dt := time.Since(start); dt > 400*time.Millisecond {
+		t.Errorf("Health-check not bounded by polling deadline - took %v", dt)
+	}
+
+	expected := map[uint32]struct{ polls, timeouts uint64 }{
+		405419896: {0, 1},
+		303986753: {1, 1},
+		201020304: {1, 0},
+		102030405: {1, 0},
+	}
+
+	polls := healthcheck.Polls()
+	for id, v := range expected {
+		if p := polls[id]; p.Polls != v.polls || p.Timeouts != v.timeouts {
+			t.Errorf("Incorrect poll stats for %v - expected:%+v, got:%+v", id, v, p)
+		}
+	}
+}
+
+func TestHealthCheckPollingDeadlineDiscardsLateResults(t *testing.T) {
+	listen := net.UDPAddr{IP: net.IPv4(192, 168, 1, 100), Port: 60001}
+
+	s := simulator.NewSimulator(&listen, simulator.NewDevice(405419896, net.IPv4(192, 168, 1, 125)))
+	s.Inject(simulator.Fault{Type: simulator.Timeout, DeviceID: 405419896, Operation: "get-listener", Delay: 200 * time.Millisecond})
+
+	healthcheck := NewHealthCheckWithLogger(s, IDLE, IGNORE, logging.NewNopLogger())
+	healthcheck.SetPolling(Polling{Workers: 1, Deadline: 50 * time.Millisecond})
+	healthcheck.Exec(&handler{})
+
+	time.Sleep(300 * time.Millisecond)
+
+	if v, ok := healthcheck.state.Devices.Status.Load(uint32(405419896)); ok {
+		t.Errorf("Late poll result not discarded - got:%+v", v)
+	}
+
+	if p := healthcheck.Polls()[405419896]; p.Polls != 0 || p.Timeouts != 1 {
+		t.Errorf("Incorrect poll stats - expected:%+v, got:%+v", struct{ polls, timeouts uint64 }{0, 1}, p)
+	}
+}
+
+func TestGetHealth(t *testing.T) {
+	listen := net.UDPAddr{IP: net.IPv4(192, 168, 1, 100), Port: 60001}
+
+	known := simulator.NewDevice(405419896, net.IPv4(192, 168, 1, 125))
+	known.Listener = net.UDPAddr{IP: net.IPv4(192, 168, 1, 100), Port: 60002}
+
+	unknown := simulator.NewDevice(303986753, net.IPv4(192, 168, 1, 126))
+	unknown.Listener = listen
+	unknown.Unconfigured = true
+
+	s := simulator.NewSimulator(&listen, known, unknown)
+	s.SetTime(405419896, time.Now().Add(5*time.Minute))
+
+	healthcheck := NewHealthCheckWithLogger(s, IDLE, IGNORE, logging.NewNopLogger())
+	watchdog := NewWatchdogWithLogger(&healthcheck, logging.NewNopLogger())
+
+	healthcheck.Exec(&handler{})
+
+	health := watchdog.GetHealth()
+
+	if health.HealthCheck.LastRun == nil || health.HealthCheck.Errors != 2 || health.HealthCheck.Warnings != 1 {
+		t.Errorf("Incorrect health-check state - got:%+v", health.HealthCheck)
+	}
+
+	if health.Watchdog == nil || !health.Watchdog.HealthCheckRunning || len(health.Watchdog.Alerts) != 0 {
+		t.Errorf("Incorrect watchdog state - got:%+v", health.Watchdog)
+	}
+
+	if len(health.Devices) != 2 {
+		t.Fatalf("Incorrect number of devices - expected:%v, got:%v", 2, len(health.Devices))
+	}
+
+	d := health.Devices[1]
+	if d.DeviceID != 405419896 || !d.Known || d.LastSeen == nil || d.ListenerCorrect {
+		t.Errorf("Incorrect device health - got:%+v", d)
+	}
+
+	if d.Drift == nil || *d.Drift < 295*time.Second || *d.Drift > 305*time.Second {
+		t.Errorf("Incorrect clock drift - expected:%v, got:%v", 5*time.Minute, d.Drift)
+	}
+
+	if strings.Join(d.Alerts, ",") != "clock-drift,wrong-listener" {
+		t.Errorf("Incorrect alerts - expected:%v, got:%v", "clock-drift,wrong-listener", d.Alerts)
+	}
+
+	d = health.Devices[0]
+	if d.DeviceID != 303986753 || d.Known || !d.ListenerCorrect || strings.Join(d.Alerts, ",") != "unexpected-device" {
+		t.Errorf("Incorrect device health - got:%+v", d)
+	}
+}
+
+type notifier struct {
+	handler
+	notified []Alert
+}
+
+func (n *notifier) Notify(m Monitor, alert Alert) error {
+	n.notified = append(n.notified, alert)
+	return nil
+}
+
+func TestStructuredAlerts(t *testing.T) {
+	listen := net.UDPAddr{IP: net.IPv4(192, 168, 1, 100), Port: 60001}
+
+	known := simulator.NewDevice(405419896, net.IPv4(192, 168, 1, 125))
+	known.Listener = net.UDPAddr{IP: net.IPv4(192, 168, 1, 100), Port: 60002}
+
+	unknown := simulator.NewDevice(303986753, net.IPv4(192, 168, 1, 126))
+	unknown.Listener = listen
+	unknown.Unconfigured = true
+
+	s := simulator.NewSimulator(&listen, known, unknown)
+	n := notifier{}
+
+	healthcheck := NewHealthCheckWithLogger(s, IDLE, IGNORE, logging.NewNopLogger())
+	healthcheck.Exec(&n)
+
+	if len(n.alerts) != 0 {
+		t.Errorf("Structured alerts passed to string handler - got:%v", n.alerts)
+	}
+
+	if len(n.notified) != 2 {
+		t.Fatalf("Incorrect alerts - expected:%v, got:%+v", 2, n.notified)
+	}
+
+	a := n.notified[0]
+	if a.Code != WrongListener || a.Severity != Warning || a.State != Raised || a.DeviceID != 405419896 {
+		t.Errorf("Incorrect alert - got:%+v", a)
+	}
+
+	if a.Values["listener"] != "192.168.1.100:60002" || a.Values["expected"] != "192.168.1.100:60001" {
+		t.Errorf("Incorrect alert values - got:%v", a.Values)
+	}
+
+	if a := n.notified[1]; a.Code != UnexpectedDevice || a.Severity != Warning || a.State != Raised || a.DeviceID != 303986753 {
+		t.Errorf("Incorrect alert - got:%+v", a)
+	}
+
+	// ... clear
+	s.SetListener(405419896, listen)
+	n.notified = nil
+	healthcheck.Exec(&n)
+
+	if len(n.notified) != 1 || n.notified[0].Code != WrongListener || n.notified[0].State != Cleared || n.notified[0].Severity != Info {
+		t.Errorf("Incorrect alerts - got:%+v", n.notified)
+	}
+}
+
+func TestWatchdogStalledAlert(t *testing.T) {
+	s := simulator.NewSimulator(nil, simulator.NewDevice(405419896, net.IPv4(192, 168, 1, 125)))
+	n := notifier{}
+
+	healthcheck := NewHealthCheckWithLogger(s, IDLE, IGNORE, logging.NewNopLogger())
+	watchdog := NewWatchdogWithLogger(&healthcheck, logging.NewNopLogger())
+	watchdog.state.Started = time.Now().Add(-2 * DELAY * time.Second)
+
+	watchdog.Exec(&n)
+
+	if len(n.notified) != 1 || n.notified[0].Code != HealthCheckStalled || n.notified[0].Severity != Error || n.notified[0].State != Raised {
+		t.Fatalf("Incorrect alerts - got:%+v", n.notified)
+	}
+
+	if health := watchdog.GetHealth(); len(health.Watchdog.Alerts) != 1 || health.Watchdog.Alerts[0] != "healthcheck-stalled" {
+		t.Errorf("Incorrect watchdog health - got:%+v", health.Watchdog)
+	}
+
+	n.notified = nil
+	healthcheck.Exec(&n)
+	watchdog.Exec(&n)
+
+	if len(n.notified) != 1 || n.notified[0].Code != HealthCheckStalled || n.notified[0].State != Cleared {
+		t.Errorf("Incorrect alerts - got:%+v", n.notified)
+	}
+}
+
+func TestClockDriftAlertWithNoResponse(t *testing.T) {
+	listen := net.UDPAddr{IP: net.IPv4(192, 168, 1, 100), Port: 60001}
+
+	device := simulator.NewDevice(405419896, net.IPv4(192, 168, 1, 125))
+	device.Listener = listen
+
+	s := simulator.NewSimulator(&listen, device)
+	s.SetTime(405419896, time.Now().Add(5*time.Minute))
+
+	n := notifier{}
+	healthcheck := NewHealthCheckWithLogger(s, 50*time.Millisecond, IGNORE, logging.NewNopLogger())
+
+	healthcheck.Exec(&n)
+
+	s.Inject(simulator.Fault{Type: simulator.Timeout, Operation: "get-status", Count: 1})
+	time.Sleep(100 * time.Millisecond)
+	healthcheck.Exec(&n)
+	healthcheck.Exec(&n)
+
+	codes := []string{}
+	for _, a := range n.notified {
+		codes = append(codes, fmt.Sprintf("%v:%v", a.Code, a.State))
+	}
+
+	// ... clock drift is raised once and not raised again after the device responds
+	expected := []string{"clock-drift:raised", "no-response:raised", "no-response:cleared"}
+	if !reflect.DeepEqual(codes, expected) {
+		t.Errorf("Incorrect alerts\n   expected:%v\n   got:     %v", expected, codes)
+	}
+}
@@ -34,8 +34,11 @@ func (w Warnings) String() string {
 }
 
 const (
-	IDLE   = time.Duration(60 * time.Second)
-	IGNORE = time.Duration(5 * time.Minute)
-	DELTA  = 60
-	DELAY  = 30
+	IDLE        = time.Duration(60 * time.Second)
+	IGNORE      = time.Duration(5 * time.Minute)
+	REMEDIATION = time.Duration(15 * time.Minute)
+	DEADLINE    = time.Duration(DELAY/2) * time.Second
+	WORKERS     = 8
+	DELTA       = 60
+	DELAY       = 30
 )
@@ -0,0 +1,162 @@
+package monitoring
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/uhppoted/uhppote-core/types"
+	"github.com/uhppoted/uhppoted-api/logging"
+)
+
+// Remediation is the (opt-in) policy for correcting health-check findings on configured
+// controllers. Listener restores the listener address/port to the configured listen address
+// and Time resynchronises the controller clock to the current time in the controller time
+// zone. Interval is the minimum time between corrective actions of the same type for a
+// controller (defaults to REMEDIATION) and DryRun reports the corrective actions without
+// sending them to the controller.
+type Remediation struct {
+	Listener bool
+	Time     bool
+	Interval time.Duration
+	DryRun   bool
+}
+
+// Auditor is an optional interface for a MonitoringHandler that wants a structured record of
+// each corrective action. Corrective actions are reported to handlers that do not implement
+// Auditor as an alert message.
+type Auditor interface {
+	Audit(Monitor, AuditRecord) error
+}
+
+type AuditRecord struct {
+	Timestamp time.Time `json:"timestamp"`
+	DeviceID  uint32    `json:"device-id"`
+	Action    string    `json:"action"`
+	Reason    string    `json:"reason"`
+	Before    string    `json:"before"`
+	After     string    `json:"after"`
+	DryRun    bool      `json:"dry-run"`
+	Error     string    `json:"error,omitempty"`
+}
+
+func (r AuditRecord) String() string {
+	s := fmt.Sprintf("%s (%s) %v -> %v", r.Action, r.Reason, r.Before, r.After)
+
+	if r.DryRun {
+		s += " [dry-run]"
+	}
+
+	if r.Error != "" {
+		s += fmt.Sprintf(" failed: %v", r.Error)
+	}
+
+	return s
+}
+
+// Enables auto-remediation of health-check findings for the configured controllers.
+func (h *HealthCheck) SetRemediation(policy Remediation) {
+	if policy.Interval <= 0 {
+		policy.Interval = REMEDIATION
+	}
+
+	h.guard.Lock()
+	h.remediation = &policy
+	h.guard.Unlock()
+}
+
+// Returns the current remediation policy (nil if not enabled). SetRemediation replaces rather
+// than updates the policy so the returned policy is safe to use without holding the lock.
+func (h *HealthCheck) policy() *Remediation {
+	h.guard.RLock()
+	defer h.guard.RUnlock()
+
+	return h.remediation
+}
+
+func (h *HealthCheck) remediateListener(id uint32, address net.UDPAddr, handler MonitoringHandler) {
+	policy := h.policy()
+	expected := h.uhppote.ListenAddr()
+	if policy == nil || !policy.Listener || expected == nil || !h.throttle(id, "set-listener", policy.Interval) {
+		return
+	}
+
+	record := AuditRecord{
+		Timestamp: time.Now(),
+		DeviceID:  id,
+		Action:    "set-listener",
+		Reason:    "incorrect listener address/port",
+		Before:    address.String(),
+		After:     expected.String(),
+		DryRun:    policy.DryRun,
+	}
+
+	if !record.DryRun {
+		if result, err := h.uhppote.SetListener(id, *expected); err != nil {
+			record.Error = err.Error()
+		} else if result == nil || !result.Succeeded {
+			record.Error = "request rejected"
+		}
+	}
+
+	audit(h, handler, record)
+}
+
+func (h *HealthCheck) remediateTime(id uint32, datetime time.Time, tz *time.Location, handler MonitoringHandler) {
+	policy := h.policy()
+	if policy == nil || !policy.Time || !h.throttle(id, "set-time", policy.Interval) {
+		return
+	}
+
+	now := time.Now().In(tz)
+	record := AuditRecord{
+		Timestamp: time.Now(),
+		DeviceID:  id,
+		Action:    "set-time",
+		Reason:    "system time not synchronized",
+		Before:    fmt.Sprintf("%v", types.DateTime(datetime)),
+		After:     fmt.Sprintf("%v %v", types.DateTime(now), tz),
+		DryRun:    policy.DryRun,
+	}
+
+	if !record.DryRun {
+		if _, err := h.uhppote.SetTime(id, now); err != nil {
+			record.Error = err.Error()
+		}
+	}
+
+	audit(h, handler, record)
+}
+
+// Returns true if a corrective action is allowed by the rate limit, recording the time of the
+// action if it is.
+func (h *HealthCheck) throttle(id uint32, action string, interval time.Duration) bool {
+	key := fmt.Sprintf("%v:%v", id, action)
+	now := time.Now()
+
+	if v, ok := h.state.Devices.Remediated.Load(key); ok && now.Before(v.(time.Time).Add(interval)) {
+		return false
+	}
+
+	h.state.Devices.Remediated.Store(key, now)
+
+	return true
+}
+
+func audit(h *HealthCheck, handler MonitoringHandler, record AuditRecord) {
+	msg := fmt.Sprintf("UTC0311-L0x %s remediation: %v", types.SerialNumber(record.DeviceID), record)
+
+	if record.Error != "" {
+		h.log.Warn(msg, logging.Operation("health-check"), logging.DeviceID(record.DeviceID))
+	} else {
+		h.log.Info(msg, logging.Operation("health-check"), logging.DeviceID(record.DeviceID))
+	}
+
+	if auditor, ok := handler.(Auditor); ok {
+		if err := auditor.Audit(h, record); err != nil {
+			h.log.Warn(fmt.Sprintf("error reporting corrective action (%v)", err), logging.Operation("health-check"), logging.DeviceID(record.DeviceID))
+		}
+	} else {
+		handler.Alert(h, msg)
+	}
+}
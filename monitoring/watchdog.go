@@ -3,14 +3,17 @@ package monitoring
 import (
 	"fmt"
 	"github.com/uhppoted/uhppote-core/types"
+	"github.com/uhppoted/uhppoted-api/logging"
 	"log"
 	"math"
+	"sync"
 	"time"
 )
 
 type Watchdog struct {
 	healthcheck *HealthCheck
-	log         *log.Logger
+	log         logging.Logger
+	guard       sync.RWMutex
 	state       struct {
 		Started     time.Time
 		HealthCheck struct {
@@ -20,6 +23,15 @@ type Watchdog struct {
 }
 
 func NewWatchdog(h *HealthCheck, l *log.Logger) Watchdog {
+	return NewWatchdogWithLogger(h, logging.NewLogAdapter(l, logging.DEBUG))
+}
+
+// Creates a Watchdog that logs to a structured logger.
+func NewWatchdogWithLogger(h *HealthCheck, l logging.Logger) Watchdog {
+	if l == nil {
+		l = logging.NewNopLogger()
+	}
+
 	return Watchdog{
 		healthcheck: h,
 		log:         l,
@@ -44,61 +56,98 @@ func (w *Watchdog) ID() string {
 }
 
 func (w *Watchdog) Exec(handler MonitoringHandler) error {
-	w.log.Printf("DEBUG %-20s", "watchdog")
+	w.log.Debug("watchdog", logging.Operation("watchdog"))
 
 	warnings := uint(0)
 	errors := uint(0)
-	healthCheckRunning := false
+	healthCheckRunning := w.healthCheckRunning()
 
 	// Verify health-check
+	w.healthcheck.guard.RLock()
 	dt := time.Since(w.state.Started).Round(time.Second)
 	if w.healthcheck.state.Touched != nil {
 		dt = time.Since(*w.healthcheck.state.Touched)
-		if int64(math.Abs(dt.Seconds())) < DELAY {
-			healthCheckRunning = true
-		}
 	}
+	w.healthcheck.guard.RUnlock()
 
 	if int64(math.Abs(dt.Seconds())) > DELAY {
 		errors += 1
 		if !w.state.HealthCheck.Alerted {
-			msg := fmt.Sprintf("'health-check' subsystem has not run since %v (%v)", types.DateTime(w.state.Started), dt)
+			alert := Alert{
+				Timestamp: time.Now(),
+				Code:      HealthCheckStalled,
+				Severity:  Error,
+				State:     Raised,
+				Values:    map[string]interface{}{"started": w.state.Started, "elapsed": dt},
+				Message:   fmt.Sprintf("'health-check' subsystem has not run since %v (%v)", types.DateTime(w.state.Started), dt),
+			}
 
-			w.log.Printf("ERROR %s", msg)
-			if err := handler.Alert(w, msg); err == nil {
+			if notify(w, w.log, handler, alert) {
+				w.guard.Lock()
 				w.state.HealthCheck.Alerted = true
+				w.guard.Unlock()
 			}
 		}
 	} else {
 		if w.state.HealthCheck.Alerted {
-			w.log.Printf("INFO  'health-check' subsystem is running")
+			alert := Alert{
+				Timestamp: time.Now(),
+				Code:      HealthCheckStalled,
+				Severity:  Info,
+				State:     Cleared,
+				Message:   "'health-check' subsystem is running",
+			}
+
+			// NOTE: string based handlers have never been notified when the health-check resumes
+			if _, ok := handler.(AlertHandler); ok {
+				notify(w, w.log, handler, alert)
+			} else {
+				w.log.Info(alert.Message, logging.Operation("watchdog"))
+			}
+
+			w.guard.Lock()
 			w.state.HealthCheck.Alerted = false
+			w.guard.Unlock()
 		}
 	}
 
 	// Report on known devices
 	if healthCheckRunning {
+		w.healthcheck.guard.RLock()
 		warnings += w.healthcheck.state.Warnings
 		errors += w.healthcheck.state.Errors
+		w.healthcheck.guard.RUnlock()
 	}
 
 	// 'k, done
-	level := "INFO"
 	msg := "OK"
 
 	if errors > 0 && warnings > 0 {
-		level = "WARN"
 		msg = fmt.Sprintf("%s, %s", Errors(errors), Warnings(warnings))
 	} else if errors > 0 {
-		level = "WARN"
 		msg = fmt.Sprintf("%s", Errors(errors))
 	} else if warnings > 0 {
-		level = "WARN"
 		msg = fmt.Sprintf("%s", Warnings(warnings))
 	}
 
-	w.log.Printf("%-5s %-12s %s", level, "watchdog", msg)
+	if errors > 0 || warnings > 0 {
+		w.log.Warn(msg, logging.Operation("watchdog"))
+	} else {
+		w.log.Info(msg, logging.Operation("watchdog"))
+	}
 	handler.Alive(w, msg)
 
 	return nil
 }
+
+// Returns true if the health-check has run within the last DELAY seconds.
+func (w *Watchdog) healthCheckRunning() bool {
+	w.healthcheck.guard.RLock()
+	defer w.healthcheck.guard.RUnlock()
+
+	if touched := w.healthcheck.state.Touched; touched != nil {
+		return int64(math.Abs(time.Since(*touched).Seconds())) < DELAY
+	}
+
+	return false
+}
@@ -0,0 +1,166 @@
+package retry
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/uhppoted/uhppote-core/uhppote"
+	"github.com/uhppoted/uhppoted-api/config"
+	"github.com/uhppoted/uhppoted-api/uhppoted"
+)
+
+type Stats struct {
+	Requests uint64 `json:"requests"`
+	Retries  uint64 `json:"retries"`
+	Failures uint64 `json:"failures"`
+}
+
+// Retry wraps an IUHPPOTE and resends idempotent requests that fail (typically because
+// the UDP request or reply was lost) according to the global or per-device retry policy.
+type Retry struct {
+	uhppote uhppote.IUHPPOTE
+	policy  config.Retry
+	devices map[uint32]config.Retry
+	stats   *stats
+	retries *uint64
+	parent  *Retry
+}
+
+var _ uhppoted.RetryTracker = (*Retry)(nil)
+
+type stats struct {
+	sync.Mutex
+	devices map[uint32]map[string]*Stats
+}
+
+func NewRetry(u uhppote.IUHPPOTE, policy config.Retry, devices map[uint32]config.Retry) *Retry {
+	r := Retry{
+		uhppote: u,
+		policy:  policy,
+		devices: map[uint32]config.Retry{},
+		stats: &stats{
+			devices: map[uint32]map[string]*Stats{},
+		},
+		retries: new(uint64),
+	}
+
+	for k, v := range devices {
+		r.devices[k] = v
+	}
+
+	return &r
+}
+
+// Creates a Retry using the system 'retry.*' policy and any device specific overrides.
+func NewRetryFromConfig(u uhppote.IUHPPOTE, c *config.Config) *Retry {
+	devices := map[uint32]config.Retry{}
+	for id, d := range c.Devices {
+		if d != nil && d.Retry != nil {
+			devices[id] = *d.Retry
+		}
+	}
+
+	return NewRetry(u, c.Retry, devices)
+}
+
+// Returns a Retry that shares the policy and statistics of this Retry but counts its own
+// retries (which are also counted by this Retry), e.g. to report the number of retries for
+// a single API request in the response.
+func (r *Retry) Track() uhppoted.TrackedUHPPOTE {
+	return &Retry{
+		uhppote: r.uhppote,
+		policy:  r.policy,
+		devices: r.devices,
+		stats:   r.stats,
+		retries: new(uint64),
+		parent:  r,
+	}
+}
+
+// Returns the number of retries made through this Retry (and any Track'ed Retry).
+func (r *Retry) Retries() uint64 {
+	return atomic.LoadUint64(r.retries)
+}
+
+// Returns a snapshot of the request, retry and failure counts by device ID and operation.
+func (r *Retry) Stats() map[uint32]map[string]Stats {
+	r.stats.Lock()
+	defer r.stats.Unlock()
+
+	snapshot := map[uint32]map[string]Stats{}
+	for id, ops := range r.stats.devices {
+		snapshot[id] = map[string]Stats{}
+		for op, s := range ops {
+			snapshot[id][op] = *s
+		}
+	}
+
+	return snapshot
+}
+
+func (r *Retry) exec(deviceID uint32, op string, f func() error) error {
+	policy := r.policy
+	if p, ok := r.devices[deviceID]; ok {
+		policy = p
+	}
+
+	attempts := 1
+	if uhppoted.Idempotent(op) && policy.Attempts > 1 {
+		attempts = policy.Attempts
+	}
+
+	backoff := policy.Backoff
+
+	var err error
+	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			for p := r; p != nil; p = p.parent {
+				atomic.AddUint64(p.retries, 1)
+			}
+			r.stats.update(deviceID, op, func(s *Stats) { s.Retries++ })
+
+			delay := backoff
+			if policy.Jitter > 0 {
+				delay += time.Duration(rand.Int63n(int64(policy.Jitter)))
+			}
+
+			time.Sleep(delay)
+			backoff *= 2
+		}
+
+		r.stats.update(deviceID, op, func(s *Stats) { s.Requests++ })
+		if err = f(); err == nil {
+			return nil
+		}
+	}
+
+	r.stats.update(deviceID, op, func(s *Stats) { s.Failures++ })
+
+	if attempts > 1 {
+		return fmt.Errorf("%w (%v attempts)", err, attempts)
+	}
+
+	return err
+}
+
+func (s *stats) update(deviceID uint32, op string, f func(*Stats)) {
+	s.Lock()
+	defer s.Unlock()
+
+	ops, ok := s.devices[deviceID]
+	if !ok {
+		ops = map[string]*Stats{}
+		s.devices[deviceID] = ops
+	}
+
+	v, ok := ops[op]
+	if !ok {
+		v = &Stats{}
+		ops[op] = v
+	}
+
+	f(v)
+}
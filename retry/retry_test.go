@@ -0,0 +1,106 @@
+package retry
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/uhppoted/uhppoted-api/config"
+	"github.com/uhppoted/uhppoted-api/simulator"
+	"github.com/uhppoted/uhppoted-api/uhppoted"
+)
+
+func TestRetryIdempotentRequest(t *testing.T) {
+	s := simulator.NewSimulator(nil, simulator.NewDevice(405419896, net.IPv4(192, 168, 1, 125)))
+	s.Inject(simulator.Fault{Type: simulator.DroppedReply, Operation: "get-cards", Count: 2})
+
+	r := NewRetry(s, config.Retry{Attempts: 3, Backoff: time.Millisecond}, nil)
+	tracked := r.Track()
+
+	if _, err := tracked.GetCards(405419896); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if tracked.Retries() != 2 {
+		t.Errorf("Incorrect retry count - expected:%v, got:%v", 2, tracked.Retries())
+	}
+
+	if r.Retries() != 2 {
+		t.Errorf("Incorrect total retry count - expected:%v, got:%v", 2, r.Retries())
+	}
+
+	expected := Stats{Requests: 3, Retries: 2, Failures: 0}
+	if stats := r.Stats()[405419896]["get-cards"]; stats != expected {
+		t.Errorf("Incorrect stats - expected:%+v, got:%+v", expected, stats)
+	}
+}
+
+func TestRetryNonIdempotentRequest(t *testing.T) {
+	s := simulator.NewSimulator(nil, simulator.NewDevice(405419896, net.IPv4(192, 168, 1, 125)))
+	s.Inject(simulator.Fault{Type: simulator.DroppedReply, Operation: "open-door", Count: 1})
+
+	r := NewRetry(s, config.Retry{Attempts: 3, Backoff: time.Millisecond}, nil)
+
+	if _, err := r.OpenDoor(405419896, 1); !errors.Is(err, simulator.ErrTimeout) {
+		t.Fatalf("Expected timeout error, got:%v", err)
+	}
+
+	expected := Stats{Requests: 1, Retries: 0, Failures: 1}
+	if stats := r.Stats()[405419896]["open-door"]; stats != expected {
+		t.Errorf("Incorrect stats - expected:%+v, got:%+v", expected, stats)
+	}
+}
+
+func TestRetryWithDevicePolicy(t *testing.T) {
+	s := simulator.NewSimulator(nil,
+		simulator.NewDevice(405419896, net.IPv4(192, 168, 1, 125)),
+		simulator.NewDevice(303986753, net.IPv4(192, 168, 1, 126)))
+
+	s.Inject(simulator.Fault{Type: simulator.Timeout, Operation: "get-time"})
+
+	c := config.NewConfig()
+	c.Retry = config.Retry{Attempts: 2, Backoff: time.Millisecond}
+	c.Devices = config.DeviceMap{
+		303986753: &config.Device{Retry: &config.Retry{Attempts: 4, Backoff: time.Millisecond, Jitter: time.Millisecond}},
+	}
+
+	r := NewRetryFromConfig(s, c)
+
+	r.GetTime(405419896)
+	r.GetTime(303986753)
+
+	stats := r.Stats()
+	if v := stats[405419896]["get-time"]; v.Requests != 2 || v.Failures != 1 {
+		t.Errorf("Incorrect stats for global policy - got:%+v", v)
+	}
+
+	if v := stats[303986753]["get-time"]; v.Requests != 4 || v.Retries != 3 || v.Failures != 1 {
+		t.Errorf("Incorrect stats for device policy - got:%+v", v)
+	}
+}
+
+func TestRetryCountInResponse(t *testing.T) {
+	s := simulator.NewSimulator(nil, simulator.NewDevice(405419896, net.IPv4(192, 168, 1, 125)))
+	s.Inject(simulator.Fault{Type: simulator.DroppedReply, Operation: "get-time", Count: 1})
+
+	r := NewRetry(s, config.Retry{Attempts: 3, Backoff: time.Millisecond}, nil)
+	u := uhppoted.UHPPOTED{
+		UHPPOTE: r,
+	}
+
+	response, err := u.GetTime(uhppoted.GetTimeRequest{DeviceID: 405419896})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if response.Retries != 1 {
+		t.Errorf("Incorrect retry count in response - expected:%v, got:%v", 1, response.Retries)
+	}
+
+	if response, err = u.GetTime(uhppoted.GetTimeRequest{DeviceID: 405419896}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	} else if response.Retries != 0 {
+		t.Errorf("Incorrect retry count in response - expected:%v, got:%v", 0, response.Retries)
+	}
+}
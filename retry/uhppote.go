@@ -0,0 +1,238 @@
+package retry
+
+import (
+	"net"
+	"os"
+	"time"
+
+	"github.com/uhppoted/uhppote-core/types"
+	"github.com/uhppoted/uhppote-core/uhppote"
+)
+
+func (r *Retry) DeviceList() map[uint32]uhppote.Device {
+	return r.uhppote.DeviceList()
+}
+
+func (r *Retry) ListenAddr() *net.UDPAddr {
+	return r.uhppote.ListenAddr()
+}
+
+func (r *Retry) GetDevices() (response []types.Device, err error) {
+	err = r.exec(0, "get-devices", func() (err error) {
+		response, err = r.uhppote.GetDevices()
+		return
+	})
+
+	return
+}
+
+func (r *Retry) GetDevice(deviceID uint32) (response *types.Device, err error) {
+	err = r.exec(deviceID, "get-device", func() (err error) {
+		response, err = r.uhppote.GetDevice(deviceID)
+		return
+	})
+
+	return
+}
+
+func (r *Retry) SetAddress(deviceID uint32, address, mask, gateway net.IP) (response *types.Result, err error) {
+	err = r.exec(deviceID, "set-address", func() (err error) {
+		response, err = r.uhppote.SetAddress(deviceID, address, mask, gateway)
+		return
+	})
+
+	return
+}
+
+func (r *Retry) GetTime(deviceID uint32) (response *types.Time, err error) {
+	err = r.exec(deviceID, "get-time", func() (err error) {
+		response, err = r.uhppote.GetTime(deviceID)
+		return
+	})
+
+	return
+}
+
+func (r *Retry) SetTime(deviceID uint32, datetime time.Time) (response *types.Time, err error) {
+	err = r.exec(deviceID, "set-time", func() (err error) {
+		response, err = r.uhppote.SetTime(deviceID, datetime)
+		return
+	})
+
+	return
+}
+
+func (r *Retry) GetDoorControlState(deviceID uint32, door byte) (response *types.DoorControlState, err error) {
+	err = r.exec(deviceID, "get-door-control", func() (err error) {
+		response, err = r.uhppote.GetDoorControlState(deviceID, door)
+		return
+	})
+
+	return
+}
+
+func (r *Retry) SetDoorControlState(deviceID uint32, door uint8, state uint8, delay uint8) (response *types.DoorControlState, err error) {
+	err = r.exec(deviceID, "set-door-control", func() (err error) {
+		response, err = r.uhppote.SetDoorControlState(deviceID, door, state, delay)
+		return
+	})
+
+	return
+}
+
+func (r *Retry) GetListener(deviceID uint32) (response *types.Listener, err error) {
+	err = r.exec(deviceID, "get-listener", func() (err error) {
+		response, err = r.uhppote.GetListener(deviceID)
+		return
+	})
+
+	return
+}
+
+func (r *Retry) SetListener(deviceID uint32, address net.UDPAddr) (response *types.Result, err error) {
+	err = r.exec(deviceID, "set-listener", func() (err error) {
+		response, err = r.uhppote.SetListener(deviceID, address)
+		return
+	})
+
+	return
+}
+
+func (r *Retry) GetStatus(deviceID uint32) (response *types.Status, err error) {
+	err = r.exec(deviceID, "get-status", func() (err error) {
+		response, err = r.uhppote.GetStatus(deviceID)
+		return
+	})
+
+	return
+}
+
+func (r *Retry) GetCards(deviceID uint32) (response uint32, err error) {
+	err = r.exec(deviceID, "get-cards", func() (err error) {
+		response, err = r.uhppote.GetCards(deviceID)
+		return
+	})
+
+	return
+}
+
+func (r *Retry) GetCardByIndex(deviceID, index uint32) (response *types.Card, err error) {
+	err = r.exec(deviceID, "get-card-by-index", func() (err error) {
+		response, err = r.uhppote.GetCardByIndex(deviceID, index)
+		return
+	})
+
+	return
+}
+
+func (r *Retry) GetCardByID(deviceID, cardNumber uint32) (response *types.Card, err error) {
+	err = r.exec(deviceID, "get-card-by-id", func() (err error) {
+		response, err = r.uhppote.GetCardByID(deviceID, cardNumber)
+		return
+	})
+
+	return
+}
+
+func (r *Retry) PutCard(deviceID uint32, card types.Card) (response bool, err error) {
+	err = r.exec(deviceID, "put-card", func() (err error) {
+		response, err = r.uhppote.PutCard(deviceID, card)
+		return
+	})
+
+	return
+}
+
+func (r *Retry) DeleteCard(deviceID uint32, cardNumber uint32) (response bool, err error) {
+	err = r.exec(deviceID, "delete-card", func() (err error) {
+		response, err = r.uhppote.DeleteCard(deviceID, cardNumber)
+		return
+	})
+
+	return
+}
+
+func (r *Retry) DeleteCards(deviceID uint32) (response bool, err error) {
+	err = r.exec(deviceID, "delete-cards", func() (err error) {
+		response, err = r.uhppote.DeleteCards(deviceID)
+		return
+	})
+
+	return
+}
+
+func (r *Retry) GetTimeProfile(deviceID uint32, profileID uint8) (response *types.TimeProfile, err error) {
+	err = r.exec(deviceID, "get-time-profile", func() (err error) {
+		response, err = r.uhppote.GetTimeProfile(deviceID, profileID)
+		return
+	})
+
+	return
+}
+
+func (r *Retry) SetTimeProfile(deviceID uint32, profile types.TimeProfile) (response bool, err error) {
+	err = r.exec(deviceID, "set-time-profile", func() (err error) {
+		response, err = r.uhppote.SetTimeProfile(deviceID, profile)
+		return
+	})
+
+	return
+}
+
+func (r *Retry) ClearTimeProfiles(deviceID uint32) (response bool, err error) {
+	err = r.exec(deviceID, "clear-time-profiles", func() (err error) {
+		response, err = r.uhppote.ClearTimeProfiles(deviceID)
+		return
+	})
+
+	return
+}
+
+func (r *Retry) RecordSpecialEvents(deviceID uint32, enable bool) (response bool, err error) {
+	err = r.exec(deviceID, "record-special-events", func() (err error) {
+		response, err = r.uhppote.RecordSpecialEvents(deviceID, enable)
+		return
+	})
+
+	return
+}
+
+func (r *Retry) GetEvent(deviceID, index uint32) (response *types.Event, err error) {
+	err = r.exec(deviceID, "get-event", func() (err error) {
+		response, err = r.uhppote.GetEvent(deviceID, index)
+		return
+	})
+
+	return
+}
+
+func (r *Retry) GetEventIndex(deviceID uint32) (response *types.EventIndex, err error) {
+	err = r.exec(deviceID, "get-event-index", func() (err error) {
+		response, err = r.uhppote.GetEventIndex(deviceID)
+		return
+	})
+
+	return
+}
+
+func (r *Retry) SetEventIndex(deviceID, index uint32) (response *types.EventIndexResult, err error) {
+	err = r.exec(deviceID, "set-event-index", func() (err error) {
+		response, err = r.uhppote.SetEventIndex(deviceID, index)
+		return
+	})
+
+	return
+}
+
+func (r *Retry) Listen(listener uhppote.Listener, q chan os.Signal) error {
+	return r.uhppote.Listen(listener, q)
+}
+
+func (r *Retry) OpenDoor(deviceID uint32, door uint8) (response *types.Result, err error) {
+	err = r.exec(deviceID, "open-door", func() (err error) {
+		response, err = r.uhppote.OpenDoor(deviceID, door)
+		return
+	})
+
+	return
+}
@@ -0,0 +1,166 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+)
+
+type Priority int
+
+const (
+	Bulk Priority = iota
+	Interactive
+)
+
+const (
+	PER_DEVICE     = 1
+	MAX_CONCURRENT = 8
+)
+
+// Default request priorities. Operations not listed here are scheduled as Bulk.
+var priorities = map[string]Priority{
+	"get-device":            Interactive,
+	"set-address":           Interactive,
+	"get-time":              Interactive,
+	"set-time":              Interactive,
+	"get-door-control":      Interactive,
+	"set-door-control":      Interactive,
+	"get-listener":          Interactive,
+	"set-listener":          Interactive,
+	"get-status":            Interactive,
+	"record-special-events": Interactive,
+	"open-door":             Interactive,
+}
+
+type waiter struct {
+	deviceID uint32
+	priority Priority
+	ready    chan struct{}
+}
+
+type queue struct {
+	perDevice     int
+	maxConcurrent int
+	priorities    map[string]Priority
+	guard         sync.Mutex
+	inflight      map[uint32]int
+	total         int
+	waiting       []*waiter
+}
+
+func newQueue(config Config) *queue {
+	q := queue{
+		perDevice:     PER_DEVICE,
+		maxConcurrent: MAX_CONCURRENT,
+		priorities:    map[string]Priority{},
+		inflight:      map[uint32]int{},
+	}
+
+	if config.PerDevice > 0 {
+		q.perDevice = config.PerDevice
+	}
+
+	if config.MaxConcurrent > 0 {
+		q.maxConcurrent = config.MaxConcurrent
+	}
+
+	for k, v := range priorities {
+		q.priorities[k] = v
+	}
+
+	for k, v := range config.Priorities {
+		q.priorities[k] = v
+	}
+
+	return &q
+}
+
+// Blocks until the request can be sent to the controller and returns the function that
+// must be invoked to release the request slot once the request has completed. Returns the
+// context error (and removes the request from the queue) if the context is cancelled while
+// the request is queued.
+func (q *queue) schedule(ctx context.Context, deviceID uint32, op string) (func(), error) {
+	w := waiter{
+		deviceID: deviceID,
+		priority: q.priorities[op],
+		ready:    make(chan struct{}),
+	}
+
+	q.guard.Lock()
+	q.waiting = append(q.waiting, &w)
+	q.dispatch()
+	q.guard.Unlock()
+
+	select {
+	case <-w.ready:
+		return func() {
+			q.release(deviceID)
+		}, nil
+
+	case <-ctx.Done():
+		q.cancel(&w)
+		return nil, ctx.Err()
+	}
+}
+
+func (q *queue) release(deviceID uint32) {
+	q.guard.Lock()
+	defer q.guard.Unlock()
+
+	q.free(deviceID)
+}
+
+// Removes a cancelled request from the queue or, if it was dispatched concurrently with
+// the cancellation, releases the request slot.
+func (q *queue) cancel(w *waiter) {
+	q.guard.Lock()
+	defer q.guard.Unlock()
+
+	select {
+	case <-w.ready:
+		q.free(w.deviceID)
+
+	default:
+		waiting := q.waiting[:0]
+		for _, v := range q.waiting {
+			if v != w {
+				waiting = append(waiting, v)
+			}
+		}
+
+		q.waiting = waiting
+	}
+}
+
+// NOTE: expects the caller to hold the queue lock
+func (q *queue) free(deviceID uint32) {
+	if q.inflight[deviceID] <= 1 {
+		delete(q.inflight, deviceID)
+	} else {
+		q.inflight[deviceID]--
+	}
+
+	q.total--
+	q.dispatch()
+}
+
+// Starts queued requests in priority order (FIFO within a priority) for as long as there
+// is capacity. A request for a busy controller does not block requests for other controllers.
+//
+// NOTE: expects the caller to hold the queue lock
+func (q *queue) dispatch() {
+	for _, p := range []Priority{Interactive, Bulk} {
+		waiting := q.waiting[:0]
+		for _, w := range q.waiting {
+			if w.priority == p && q.total < q.maxConcurrent && q.inflight[w.deviceID] < q.perDevice {
+				q.inflight[w.deviceID]++
+				q.total++
+				close(w.ready)
+			} else {
+				waiting = append(waiting, w)
+			}
+		}
+
+		q.waiting = waiting
+	}
+}
@@ -0,0 +1,346 @@
+package scheduler
+
+import (
+	"context"
+	"net"
+	"os"
+	"time"
+
+	"github.com/uhppoted/uhppote-core/types"
+	"github.com/uhppoted/uhppote-core/uhppote"
+	"github.com/uhppoted/uhppoted-api/uhppoted"
+)
+
+// PerDevice is the maximum number of in-flight requests to a single controller (default 1) and
+// MaxConcurrent is the maximum number of in-flight requests across all controllers (default 8).
+// Priorities overrides the default priority for an operation e.g. "get-card-by-id": Interactive.
+type Config struct {
+	PerDevice     int
+	MaxConcurrent int
+	Priorities    map[string]Priority
+}
+
+// Scheduler wraps an IUHPPOTE and bounds the number of concurrent requests sent to each
+// controller and overall, dispatching interactive requests (e.g. open-door) ahead of
+// queued bulk requests (e.g. ACL synchronisation). Listen is not scheduled.
+type Scheduler struct {
+	uhppote uhppote.IUHPPOTE
+	queue   *queue
+	ctx     context.Context
+}
+
+func NewScheduler(u uhppote.IUHPPOTE, config Config) *Scheduler {
+	return &Scheduler{
+		uhppote: u,
+		queue:   newQueue(config),
+		ctx:     context.Background(),
+	}
+}
+
+// Returns a Scheduler that shares the request queue of this Scheduler but abandons a queued
+// request (returning the context error) if the context is cancelled before the request has
+// been dispatched.
+func (s *Scheduler) WithContext(ctx context.Context) *Scheduler {
+	return &Scheduler{
+		uhppote: s.uhppote,
+		queue:   s.queue,
+		ctx:     ctx,
+	}
+}
+
+// Passes retry tracking through to the wrapped IUHPPOTE (if it is a uhppoted.RetryTracker).
+// The tracked Scheduler shares the request queue of this Scheduler.
+func (s *Scheduler) Track() uhppoted.TrackedUHPPOTE {
+	if t, ok := s.uhppote.(uhppoted.RetryTracker); ok {
+		tracked := t.Track()
+
+		return uhppoted.Tracked(&Scheduler{uhppote: tracked, queue: s.queue, ctx: s.ctx}, tracked.Retries)
+	}
+
+	return uhppoted.Tracked(s, uhppoted.Untracked)
+}
+
+// Returns the number of in-flight and queued requests.
+func (s *Scheduler) Pending() (int, int) {
+	s.queue.guard.Lock()
+	defer s.queue.guard.Unlock()
+
+	return s.queue.total, len(s.queue.waiting)
+}
+
+func (s *Scheduler) DeviceList() map[uint32]uhppote.Device {
+	return s.uhppote.DeviceList()
+}
+
+func (s *Scheduler) ListenAddr() *net.UDPAddr {
+	return s.uhppote.ListenAddr()
+}
+
+// Broadcast requests are scheduled against device ID 0.
+func (s *Scheduler) GetDevices() ([]types.Device, error) {
+	release, err := s.queue.schedule(s.ctx, 0, "get-devices")
+	if err != nil {
+		return nil, err
+	}
+
+	defer release()
+
+	return s.uhppote.GetDevices()
+}
+
+func (s *Scheduler) GetDevice(deviceID uint32) (*types.Device, error) {
+	release, err := s.queue.schedule(s.ctx, deviceID, "get-device")
+	if err != nil {
+		return nil, err
+	}
+
+	defer release()
+
+	return s.uhppote.GetDevice(deviceID)
+}
+
+func (s *Scheduler) SetAddress(deviceID uint32, address, mask, gateway net.IP) (*types.Result, error) {
+	release, err := s.queue.schedule(s.ctx, deviceID, "set-address")
+	if err != nil {
+		return nil, err
+	}
+
+	defer release()
+
+	return s.uhppote.SetAddress(deviceID, address, mask, gateway)
+}
+
+func (s *Scheduler) GetTime(deviceID uint32) (*types.Time, error) {
+	release, err := s.queue.schedule(s.ctx, deviceID, "get-time")
+	if err != nil {
+		return nil, err
+	}
+
+	defer release()
+
+	return s.uhppote.GetTime(deviceID)
+}
+
+func (s *Scheduler) SetTime(deviceID uint32, datetime time.Time) (*types.Time, error) {
+	release, err := s.queue.schedule(s.ctx, deviceID, "set-time")
+	if err != nil {
+		return nil, err
+	}
+
+	defer release()
+
+	return s.uhppote.SetTime(deviceID, datetime)
+}
+
+func (s *Scheduler) GetDoorControlState(deviceID uint32, door byte) (*types.DoorControlState, error) {
+	release, err := s.queue.schedule(s.ctx, deviceID, "get-door-control")
+	if err != nil {
+		return nil, err
+	}
+
+	defer release()
+
+	return s.uhppote.GetDoorControlState(deviceID, door)
+}
+
+func (s *Scheduler) SetDoorControlState(deviceID uint32, door uint8, state uint8, delay uint8) (*types.DoorControlState, error) {
+	release, err := s.queue.schedule(s.ctx, deviceID, "set-door-control")
+	if err != nil {
+		return nil, err
+	}
+
+	defer release()
+
+	return s.uhppote.SetDoorControlState(deviceID, door, state, delay)
+}
+
+func (s *Scheduler) GetListener(deviceID uint32) (*types.Listener, error) {
+	release, err := s.queue.schedule(s.ctx, deviceID, "get-listener")
+	if err != nil {
+		return nil, err
+	}
+
+	defer release()
+
+	return s.uhppote.GetListener(deviceID)
+}
+
+func (s *Scheduler) SetListener(deviceID uint32, address net.UDPAddr) (*types.Result, error) {
+	release, err := s.queue.schedule(s.ctx, deviceID, "set-listener")
+	if err != nil {
+		return nil, err
+	}
+
+	defer release()
+
+	return s.uhppote.SetListener(deviceID, address)
+}
+
+func (s *Scheduler) GetStatus(deviceID uint32) (*types.Status, error) {
+	release, err := s.queue.schedule(s.ctx, deviceID, "get-status")
+	if err != nil {
+		return nil, err
+	}
+
+	defer release()
+
+	return s.uhppote.GetStatus(deviceID)
+}
+
+func (s *Scheduler) GetCards(deviceID uint32) (uint32, error) {
+	release, err := s.queue.schedule(s.ctx, deviceID, "get-cards")
+	if err != nil {
+		return 0, err
+	}
+
+	defer release()
+
+	return s.uhppote.GetCards(deviceID)
+}
+
+func (s *Scheduler) GetCardByIndex(deviceID, index uint32) (*types.Card, error) {
+	release, err := s.queue.schedule(s.ctx, deviceID, "get-card-by-index")
+	if err != nil {
+		return nil, err
+	}
+
+	defer release()
+
+	return s.uhppote.GetCardByIndex(deviceID, index)
+}
+
+func (s *Scheduler) GetCardByID(deviceID, cardNumber uint32) (*types.Card, error) {
+	release, err := s.queue.schedule(s.ctx, deviceID, "get-card-by-id")
+	if err != nil {
+		return nil, err
+	}
+
+	defer release()
+
+	return s.uhppote.GetCardByID(deviceID, cardNumber)
+}
+
+func (s *Scheduler) PutCard(deviceID uint32, card types.Card) (bool, error) {
+	release, err := s.queue.schedule(s.ctx, deviceID, "put-card")
+	if err != nil {
+		return false, err
+	}
+
+	defer release()
+
+	return s.uhppote.PutCard(deviceID, card)
+}
+
+func (s *Scheduler) DeleteCard(deviceID uint32, cardNumber uint32) (bool, error) {
+	release, err := s.queue.schedule(s.ctx, deviceID, "delete-card")
+	if err != nil {
+		return false, err
+	}
+
+	defer release()
+
+	return s.uhppote.DeleteCard(deviceID, cardNumber)
+}
+
+func (s *Scheduler) DeleteCards(deviceID uint32) (bool, error) {
+	release, err := s.queue.schedule(s.ctx, deviceID, "delete-cards")
+	if err != nil {
+		return false, err
+	}
+
+	defer release()
+
+	return s.uhppote.DeleteCards(deviceID)
+}
+
+func (s *Scheduler) GetTimeProfile(deviceID uint32, profileID uint8) (*types.TimeProfile, error) {
+	release, err := s.queue.schedule(s.ctx, deviceID, "get-time-profile")
+	if err != nil {
+		return nil, err
+	}
+
+	defer release()
+
+	return s.uhppote.GetTimeProfile(deviceID, profileID)
+}
+
+func (s *Scheduler) SetTimeProfile(deviceID uint32, profile types.TimeProfile) (bool, error) {
+	release, err := s.queue.schedule(s.ctx, deviceID, "set-time-profile")
+	if err != nil {
+		return false, err
+	}
+
+	defer release()
+
+	return s.uhppote.SetTimeProfile(deviceID, profile)
+}
+
+func (s *Scheduler) ClearTimeProfiles(deviceID uint32) (bool, error) {
+	release, err := s.queue.schedule(s.ctx, deviceID, "clear-time-profiles")
+	if err != nil {
+		return false, err
+	}
+
+	defer release()
+
+	return s.uhppote.ClearTimeProfiles(deviceID)
+}
+
+func (s *Scheduler) RecordSpecialEvents(deviceID uint32, enable bool) (bool, error) {
+	release, err := s.queue.schedule(s.ctx, deviceID, "record-special-events")
+	if err != nil {
+		return false, err
+	}
+
+	defer release()
+
+	return s.uhppote.RecordSpecialEvents(deviceID, enable)
+}
+
+func (s *Scheduler) GetEvent(deviceID, index uint32) (*types.Event, error) {
+	release, err := s.queue.schedule(s.ctx, deviceID, "get-event")
+	if err != nil {
+		return nil, err
+	}
+
+	defer release()
+
+	return s.uhppote.GetEvent(deviceID, index)
+}
+
+func (s *Scheduler) GetEventIndex(deviceID uint32) (*types.EventIndex, error) {
+	release, err := s.queue.schedule(s.ctx, deviceID, "get-event-index")
+	if err != nil {
+		return nil, err
+	}
+
+	defer release()
+
+	return s.uhppote.GetEventIndex(deviceID)
+}
+
+func (s *Scheduler) SetEventIndex(deviceID, index uint32) (*types.EventIndexResult, error) {
+	release, err := s.queue.schedule(s.ctx, deviceID, "set-event-index")
+	if err != nil {
+		return nil, err
+	}
+
+	defer release()
+
+	return s.uhppote.SetEventIndex(deviceID, index)
+}
+
+func (s *Scheduler) Listen(listener uhppote.Listener, q chan os.Signal) error {
+	return s.uhppote.Listen(listener, q)
+}
+
+func (s *Scheduler) OpenDoor(deviceID uint32, door uint8) (*types.Result, error) {
+	release, err := s.queue.schedule(s.ctx, deviceID, "open-door")
+	if err != nil {
+		return nil, err
+	}
+
+	defer release()
+
+	return s.uhppote.OpenDoor(deviceID, door)
+}
@@ -0,0 +1,188 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/uhppoted/uhppote-core/types"
+	"github.com/uhppoted/uhppoted-api/simulator"
+)
+
+// Wraps the simulator to record the order of and the maximum number of concurrent requests
+type monitor struct {
+	*simulator.Simulator
+	guard    sync.Mutex
+	inflight map[uint32]int
+	max      map[uint32]int
+	total    int
+	maxTotal int
+	order    []string
+	delay    time.Duration
+}
+
+func (m *monitor) enter(deviceID uint32, op string) {
+	m.guard.Lock()
+	m.inflight[deviceID]++
+	m.total++
+	if m.inflight[deviceID] > m.max[deviceID] {
+		m.max[deviceID] = m.inflight[deviceID]
+	}
+	if m.total > m.maxTotal {
+		m.maxTotal = m.total
+	}
+	m.order = append(m.order, op)
+	m.guard.Unlock()
+
+	time.Sleep(m.delay)
+
+	m.guard.Lock()
+	m.inflight[deviceID]--
+	m.total--
+	m.guard.Unlock()
+}
+
+func (m *monitor) PutCard(deviceID uint32, card types.Card) (bool, error) {
+	m.enter(deviceID, "put-card")
+	return m.Simulator.PutCard(deviceID, card)
+}
+
+func (m *monitor) OpenDoor(deviceID uint32, door uint8) (*types.Result, error) {
+	m.enter(deviceID, "open-door")
+	return m.Simulator.OpenDoor(deviceID, door)
+}
+
+func newMonitor(delay time.Duration, devices ...uint32) *monitor {
+	list := []simulator.Device{}
+	for i, id := range devices {
+		list = append(list, simulator.NewDevice(id, net.IPv4(192, 168, 1, byte(125+i))))
+	}
+
+	return &monitor{
+		Simulator: simulator.NewSimulator(nil, list...),
+		inflight:  map[uint32]int{},
+		max:       map[uint32]int{},
+		delay:     delay,
+	}
+}
+
+func TestSchedulerConcurrencyLimits(t *testing.T) {
+	m := newMonitor(5*time.Millisecond, 405419896, 303986753, 201020304)
+	s := NewScheduler(m, Config{PerDevice: 1, MaxConcurrent: 2})
+
+	var wg sync.WaitGroup
+	for _, id := range []uint32{405419896, 303986753, 201020304} {
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func(deviceID uint32, card uint32) {
+				defer wg.Done()
+				s.PutCard(deviceID, types.Card{CardNumber: card, From: date("2021-01-01"), To: date("2021-12-31"), Doors: map[uint8]int{1: 1}})
+			}(id, uint32(100+i))
+		}
+	}
+
+	wg.Wait()
+
+	for id, max := range m.max {
+		if max > 1 {
+			t.Errorf("Too many concurrent requests for %v - expected:%v, got:%v", id, 1, max)
+		}
+	}
+
+	if m.maxTotal > 2 {
+		t.Errorf("Too many concurrent requests - expected:%v, got:%v", 2, m.maxTotal)
+	}
+
+	if inflight, queued := s.Pending(); inflight != 0 || queued != 0 {
+		t.Errorf("Incorrect pending requests - expected:0,0, got:%v,%v", inflight, queued)
+	}
+}
+
+func TestSchedulerPrioritisesInteractiveRequests(t *testing.T) {
+	m := newMonitor(10*time.Millisecond, 405419896)
+	s := NewScheduler(m, Config{})
+	card := types.Card{CardNumber: 8165538, From: date("2021-01-01"), To: date("2021-12-31"), Doors: map[uint8]int{1: 1}}
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		s.PutCard(405419896, card)
+	}()
+
+	// ... wait for the first request to be in-flight, then queue bulk and interactive requests
+	for {
+		if inflight, _ := s.Pending(); inflight > 0 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.PutCard(405419896, card)
+		}()
+	}
+
+	for {
+		if _, queued := s.Pending(); queued == 3 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		s.OpenDoor(405419896, 1)
+	}()
+
+	wg.Wait()
+
+	if len(m.order) != 5 || m.order[1] != "open-door" {
+		t.Errorf("Interactive request not prioritised - got:%v", m.order)
+	}
+}
+
+func date(s string) *types.Date {
+	d, _ := types.DateFromString(s)
+
+	return d
+}
+
+func TestSchedulerWithCancelledContext(t *testing.T) {
+	m := newMonitor(50*time.Millisecond, 405419896)
+	s := NewScheduler(m, Config{PerDevice: 1, MaxConcurrent: 1})
+
+	go s.OpenDoor(405419896, 1)
+	time.Sleep(10 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := s.WithContext(ctx).OpenDoor(405419896, 2); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context deadline exceeded error, got:%v", err)
+	}
+
+	if _, queued := s.Pending(); queued != 0 {
+		t.Errorf("Cancelled request not removed from queue - queued:%v", queued)
+	}
+
+	if _, err := s.OpenDoor(405419896, 3); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if inflight, queued := s.Pending(); inflight != 0 || queued != 0 {
+		t.Errorf("Incorrect pending requests - expected:0,0, got:%v,%v", inflight, queued)
+	}
+
+	if len(m.order) != 2 {
+		t.Errorf("Incorrect number of requests sent to controller - expected:%v, got:%v", 2, m.order)
+	}
+}
@@ -0,0 +1,232 @@
+package simulator
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/uhppoted/uhppote-core/types"
+)
+
+const ROLLOVER = uint32(100000)
+
+// Simulated UTO311-L0x controller state. All fields are exported so that the state can be
+// saved to and restored from a JSON file.
+type Device struct {
+	DeviceID      uint32                      `json:"device-id"`
+	Name          string                      `json:"name"`
+	IpAddress     net.IP                      `json:"ip-address"`
+	SubnetMask    net.IP                      `json:"subnet-mask"`
+	Gateway       net.IP                      `json:"gateway"`
+	MacAddress    types.MacAddress            `json:"mac-address"`
+	Version       types.Version               `json:"version"`
+	Date          types.Date                  `json:"date"`
+	Port          int                         `json:"port"`
+	Listener      net.UDPAddr                 `json:"listener"`
+	TimeOffset    time.Duration               `json:"time-offset"`
+	TimeZone      string                      `json:"timezone,omitempty"`
+	Doors         map[uint8]*Door             `json:"doors"`
+	Cards         []types.Card                `json:"cards"`
+	Profiles      map[uint8]types.TimeProfile `json:"profiles"`
+	Events        EventBuffer                 `json:"events"`
+	SpecialEvents bool                        `json:"special-events"`
+	EventIndex    uint32                      `json:"event-index"`
+	Unconfigured  bool                        `json:"unconfigured,omitempty"`
+}
+
+type Door struct {
+	Name         string `json:"name,omitempty"`
+	ControlState uint8  `json:"control"`
+	Delay        uint8  `json:"delay"`
+	Open         bool   `json:"open"`
+	Button       bool   `json:"button"`
+}
+
+// Circular event buffer modelled on the controller event store. Events are kept in
+// chronological order and the oldest event is discarded once the buffer holds 'Rollover'
+// events. Event indices wrap around to 1 after 'Rollover'.
+type EventBuffer struct {
+	Rollover uint32        `json:"rollover"`
+	Events   []types.Event `json:"events"`
+}
+
+const deleted = uint32(0xffffffff)
+
+// Returns a device initialised with the same defaults as a factory reset controller.
+func NewDevice(deviceID uint32, address net.IP) Device {
+	mac, _ := net.ParseMAC("00:12:23:34:45:56")
+
+	return Device{
+		DeviceID:   deviceID,
+		IpAddress:  address,
+		SubnetMask: net.IPv4(255, 255, 255, 0),
+		Gateway:    net.IPv4(0, 0, 0, 0),
+		MacAddress: types.MacAddress(mac),
+		Version:    0x0892,
+		Date:       types.ToDate(2018, time.November, 5),
+		Port:       60000,
+		Doors: map[uint8]*Door{
+			1: &Door{ControlState: 3, Delay: 5},
+			2: &Door{ControlState: 3, Delay: 5},
+			3: &Door{ControlState: 3, Delay: 5},
+			4: &Door{ControlState: 3, Delay: 5},
+		},
+		Cards:    []types.Card{},
+		Profiles: map[uint8]types.TimeProfile{},
+		Events: EventBuffer{
+			Rollover: ROLLOVER,
+			Events:   []types.Event{},
+		},
+	}
+}
+
+func (d *Device) now() time.Time {
+	return time.Now().Add(d.TimeOffset)
+}
+
+func (d *Device) door(door uint8) (*Door, error) {
+	if dd, ok := d.Doors[door]; ok && dd != nil {
+		return dd, nil
+	}
+
+	return nil, fmt.Errorf("Invalid door (%v)", door)
+}
+
+func (d *Device) card(cardNumber uint32) (int, bool) {
+	for i, c := range d.Cards {
+		if c.CardNumber == cardNumber && cardNumber != deleted {
+			return i, true
+		}
+	}
+
+	return 0, false
+}
+
+func (d *Device) cards() uint32 {
+	count := uint32(0)
+	for _, c := range d.Cards {
+		if c.CardNumber != deleted {
+			count++
+		}
+	}
+
+	return count
+}
+
+// Checks a card swipe against the card permissions and time profiles and returns the
+// access granted flag and the controller event reason code.
+func (d *Device) authorised(cardNumber uint32, door uint8, now time.Time) (bool, uint8) {
+	ix, ok := d.card(cardNumber)
+	if !ok {
+		return false, 6
+	}
+
+	card := d.Cards[ix]
+	today := types.Date(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local))
+
+	if card.From == nil || card.To == nil || today.Before(*card.From) || today.After(*card.To) {
+		return false, 6
+	}
+
+	switch permission := card.Doors[door]; {
+	case permission == 1:
+		return true, 1
+
+	case permission >= 2 && permission <= 254:
+		if d.allowed(uint8(permission), today, now) {
+			return true, 1
+		}
+
+		return false, 13
+
+	default:
+		return false, 6
+	}
+}
+
+func (d *Device) allowed(profileID uint8, today types.Date, now time.Time) bool {
+	visited := map[uint8]bool{}
+
+	for id := profileID; id != 0 && !visited[id]; {
+		profile, ok := d.Profiles[id]
+		if !ok {
+			return false
+		}
+
+		visited[id] = true
+
+		if profile.From != nil && profile.To != nil && !today.Before(*profile.From) && !today.After(*profile.To) {
+			if profile.Weekdays[now.Weekday()] {
+				for _, ix := range []uint8{1, 2, 3} {
+					segment := profile.Segments[ix]
+					if !segment.Start.After(types.HHmmFromTime(now)) && !segment.End.Before(types.HHmmFromTime(now)) {
+						if segment.Start != segment.End {
+							return true
+						}
+					}
+				}
+			}
+		}
+
+		id = profile.LinkedProfileID
+	}
+
+	return false
+}
+
+func (b *EventBuffer) rollover() uint32 {
+	if b.Rollover == 0 {
+		return ROLLOVER
+	}
+
+	return b.Rollover
+}
+
+func (b *EventBuffer) first() *types.Event {
+	if len(b.Events) > 0 {
+		return &b.Events[0]
+	}
+
+	return nil
+}
+
+func (b *EventBuffer) last() *types.Event {
+	if N := len(b.Events); N > 0 {
+		return &b.Events[N-1]
+	}
+
+	return nil
+}
+
+func (b *EventBuffer) get(index uint32) *types.Event {
+	first := b.first()
+	if first == nil || index == 0 || index > b.rollover() {
+		return nil
+	}
+
+	rollover := b.rollover()
+	offset := (index + rollover - first.Index) % rollover
+
+	if int(offset) < len(b.Events) && b.Events[offset].Index == index {
+		return &b.Events[offset]
+	}
+
+	return nil
+}
+
+func (b *EventBuffer) add(event types.Event) types.Event {
+	index := uint32(1)
+	if last := b.last(); last != nil && last.Index < b.rollover() {
+		index = last.Index + 1
+	}
+
+	event.Index = index
+
+	if uint32(len(b.Events)) >= b.rollover() {
+		b.Events = append(b.Events[1:], event)
+	} else {
+		b.Events = append(b.Events, event)
+	}
+
+	return event
+}
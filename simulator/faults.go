@@ -0,0 +1,94 @@
+package simulator
+
+import (
+	"time"
+)
+
+type FaultType int
+
+const (
+	// Request is not delivered and no reply is received
+	Timeout FaultType = iota + 1
+
+	// Request is executed but the reply is lost
+	DroppedReply
+
+	// Request is not executed and the reply returns ok=false (write operations only)
+	Rejected
+)
+
+// Fault injection rule. A fault matches a request if the device ID and operation match
+// (a zero DeviceID or empty Operation match any device or operation respectively). The
+// first 'Skip' matching requests are executed normally and the fault is then applied to
+// the next 'Count' matching requests (or to all subsequent matching requests if Count is 0).
+type Fault struct {
+	Type      FaultType
+	DeviceID  uint32
+	Operation string
+	Skip      int
+	Count     int
+	Delay     time.Duration
+}
+
+type fault struct {
+	Fault
+	matched   int
+	triggered int
+}
+
+func (f FaultType) String() string {
+	switch f {
+	case Timeout:
+		return "timeout"
+	case DroppedReply:
+		return "dropped reply"
+	case Rejected:
+		return "rejected"
+	}
+
+	return "unknown"
+}
+
+// Adds a fault injection rule.
+func (s *Simulator) Inject(f Fault) {
+	s.guard.Lock()
+	defer s.guard.Unlock()
+
+	s.faults = append(s.faults, &fault{Fault: f})
+}
+
+// Removes all fault injection rules.
+func (s *Simulator) ClearFaults() {
+	s.guard.Lock()
+	defer s.guard.Unlock()
+
+	s.faults = []*fault{}
+}
+
+// NOTE: expects the caller to hold the simulator lock
+func (s *Simulator) match(deviceID uint32, op string) *fault {
+	for _, f := range s.faults {
+		if f.DeviceID != 0 && f.DeviceID != deviceID {
+			continue
+		}
+
+		if f.Operation != "" && f.Operation != op {
+			continue
+		}
+
+		f.matched++
+		if f.matched <= f.Skip {
+			continue
+		}
+
+		if f.Count > 0 && f.triggered >= f.Count {
+			continue
+		}
+
+		f.triggered++
+
+		return f
+	}
+
+	return nil
+}
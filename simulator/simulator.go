@@ -0,0 +1,333 @@
+package simulator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/uhppoted/uhppote-core/types"
+	"github.com/uhppoted/uhppote-core/uhppote"
+)
+
+var ErrTimeout = errors.New("Timeout waiting for reply")
+
+// In-memory, stateful implementation of uhppote.IUHPPOTE for an arbitrary set of simulated
+// controllers. Intended for testing the API packages end-to-end without hardware.
+type Simulator struct {
+	listenAddr *net.UDPAddr
+	devices    map[uint32]*Device
+	faults     []*fault
+	listeners  map[uhppote.Listener]struct{}
+	guard      sync.Mutex
+}
+
+var _ uhppote.IUHPPOTE = (*Simulator)(nil)
+
+type state struct {
+	Devices []*Device `json:"devices"`
+}
+
+func NewSimulator(listen *net.UDPAddr, devices ...Device) *Simulator {
+	s := Simulator{
+		listenAddr: listen,
+		devices:    map[uint32]*Device{},
+		faults:     []*fault{},
+		listeners:  map[uhppote.Listener]struct{}{},
+	}
+
+	for _, d := range devices {
+		s.Add(d)
+	}
+
+	return &s
+}
+
+// Adds a device to the simulated network (replacing any existing device with the same ID).
+func (s *Simulator) Add(device Device) {
+	s.guard.Lock()
+	defer s.guard.Unlock()
+
+	d := device
+	if d.Doors == nil {
+		d.Doors = NewDevice(d.DeviceID, d.IpAddress).Doors
+	}
+
+	if d.Cards == nil {
+		d.Cards = []types.Card{}
+	}
+
+	if d.Profiles == nil {
+		d.Profiles = map[uint8]types.TimeProfile{}
+	}
+
+	if d.Events.Events == nil {
+		d.Events.Events = []types.Event{}
+	}
+
+	s.devices[d.DeviceID] = &d
+}
+
+// Removes a device from the simulated network.
+func (s *Simulator) Remove(deviceID uint32) {
+	s.guard.Lock()
+	defer s.guard.Unlock()
+
+	delete(s.devices, deviceID)
+}
+
+// Returns a copy of the current state of a simulated device.
+func (s *Simulator) Device(deviceID uint32) (*Device, bool) {
+	s.guard.Lock()
+	defer s.guard.Unlock()
+
+	if d, ok := s.devices[deviceID]; ok {
+		clone := clone(d)
+		return &clone, true
+	}
+
+	return nil, false
+}
+
+// Appends an event to the device event buffer and notifies any active listeners. The
+// event index is assigned by the simulator and the event timestamp defaults to the device
+// system time.
+func (s *Simulator) AddEvent(deviceID uint32, event types.Event) (*types.Event, error) {
+	s.guard.Lock()
+
+	d, ok := s.devices[deviceID]
+	if !ok {
+		s.guard.Unlock()
+		return nil, fmt.Errorf("%v: no such device", deviceID)
+	}
+
+	if time.Time(event.Timestamp).IsZero() {
+		event.Timestamp = types.DateTime(d.now())
+	}
+
+	event.SerialNumber = types.SerialNumber(deviceID)
+
+	e := d.Events.add(event)
+	status := s.status(d)
+
+	s.guard.Unlock()
+
+	s.notify(d, status)
+
+	return &e, nil
+}
+
+// Simulates a card swipe at a door, updating the device event buffer and notifying any
+// active listeners.
+func (s *Simulator) Swipe(deviceID uint32, cardNumber uint32, door uint8, direction uint8) (*types.Event, error) {
+	s.guard.Lock()
+
+	d, ok := s.devices[deviceID]
+	if !ok {
+		s.guard.Unlock()
+		return nil, fmt.Errorf("%v: no such device", deviceID)
+	}
+
+	if _, err := d.door(door); err != nil {
+		s.guard.Unlock()
+		return nil, err
+	}
+
+	now := d.now()
+	granted, reason := d.authorised(cardNumber, door, now)
+
+	e := d.Events.add(types.Event{
+		SerialNumber: types.SerialNumber(deviceID),
+		Type:         1,
+		Granted:      granted,
+		Door:         door,
+		Direction:    direction,
+		CardNumber:   cardNumber,
+		Timestamp:    types.DateTime(now),
+		Reason:       reason,
+	})
+
+	status := s.status(d)
+
+	s.guard.Unlock()
+
+	s.notify(d, status)
+
+	return &e, nil
+}
+
+// Saves the simulator state as JSON.
+func (s *Simulator) Save(w io.Writer) error {
+	s.guard.Lock()
+	defer s.guard.Unlock()
+
+	st := state{
+		Devices: []*Device{},
+	}
+
+	for _, d := range s.devices {
+		st.Devices = append(st.Devices, d)
+	}
+
+	sort.SliceStable(st.Devices, func(i, j int) bool { return st.Devices[i].DeviceID < st.Devices[j].DeviceID })
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(st)
+}
+
+// Replaces the simulator state with the state loaded from JSON.
+func (s *Simulator) Load(r io.Reader) error {
+	st := state{}
+
+	if err := json.NewDecoder(r).Decode(&st); err != nil {
+		return err
+	}
+
+	s.guard.Lock()
+	s.devices = map[uint32]*Device{}
+	s.guard.Unlock()
+
+	for _, d := range st.Devices {
+		if d != nil {
+			s.Add(*d)
+		}
+	}
+
+	return nil
+}
+
+func (s *Simulator) SaveToFile(file string) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+
+	if err := s.Save(f); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+func (s *Simulator) LoadFromFile(file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	return s.Load(f)
+}
+
+// Dispatches a request to a simulated device, applying any matching injected fault. The
+// handler is not invoked if the request 'times out' or is rejected.
+func (s *Simulator) exec(deviceID uint32, op string, f func(d *Device) error) (rejected bool, err error) {
+	s.guard.Lock()
+
+	d, ok := s.devices[deviceID]
+	if !ok {
+		s.guard.Unlock()
+		return false, fmt.Errorf("%v: %w", deviceID, ErrTimeout)
+	}
+
+	fault := s.match(deviceID, op)
+	if fault != nil && fault.Type == Timeout {
+		s.guard.Unlock()
+		time.Sleep(fault.Delay)
+		return false, fmt.Errorf("%v: %w", deviceID, ErrTimeout)
+	}
+
+	if fault != nil && fault.Type == Rejected {
+		s.guard.Unlock()
+		return true, nil
+	}
+
+	err = f(d)
+
+	s.guard.Unlock()
+
+	if fault != nil && fault.Type == DroppedReply {
+		time.Sleep(fault.Delay)
+		return false, fmt.Errorf("%v: %w", deviceID, ErrTimeout)
+	}
+
+	return false, err
+}
+
+func (s *Simulator) status(d *Device) types.Status {
+	status := types.Status{
+		SerialNumber:   types.SerialNumber(d.DeviceID),
+		DoorState:      map[uint8]bool{},
+		DoorButton:     map[uint8]bool{},
+		SystemDateTime: types.DateTime(d.now()),
+		SequenceId:     0,
+	}
+
+	for _, id := range []uint8{1, 2, 3, 4} {
+		if door, ok := d.Doors[id]; ok && door != nil {
+			status.DoorState[id] = door.Open
+			status.DoorButton[id] = door.Button
+		} else {
+			status.DoorState[id] = false
+			status.DoorButton[id] = false
+		}
+	}
+
+	if e := d.Events.last(); e != nil {
+		timestamp := e.Timestamp
+		status.Event = &types.StatusEvent{
+			Index:      e.Index,
+			Type:       e.Type,
+			Granted:    e.Granted,
+			Door:       e.Door,
+			Direction:  e.Direction,
+			CardNumber: e.CardNumber,
+			Timestamp:  &timestamp,
+			Reason:     e.Reason,
+		}
+	}
+
+	return status
+}
+
+// Sends an event to all active listeners if the device listener address matches the
+// simulator listen address (i.e. events are 'lost' if the device listener is misconfigured).
+func (s *Simulator) notify(d *Device, status types.Status) {
+	s.guard.Lock()
+
+	if s.listenAddr != nil {
+		if !s.listenAddr.IP.Equal(d.Listener.IP) || s.listenAddr.Port != d.Listener.Port {
+			s.guard.Unlock()
+			return
+		}
+	}
+
+	listeners := []uhppote.Listener{}
+	for l := range s.listeners {
+		listeners = append(listeners, l)
+	}
+
+	s.guard.Unlock()
+
+	for _, l := range listeners {
+		event := status
+		l.OnEvent(&event)
+	}
+}
+
+func clone(d *Device) Device {
+	bytes, _ := json.Marshal(d)
+	device := Device{}
+	json.Unmarshal(bytes, &device)
+
+	return device
+}
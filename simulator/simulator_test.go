@@ -0,0 +1,179 @@
+package simulator
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/uhppoted/uhppote-core/types"
+)
+
+func TestEventBufferRollover(t *testing.T) {
+	s := NewSimulator(nil, NewDevice(405419896, net.IPv4(192, 168, 1, 100)))
+	s.devices[405419896].Events.Rollover = 5
+
+	for i := 0; i < 7; i++ {
+		if _, err := s.AddEvent(405419896, types.Event{Type: 1, CardNumber: uint32(65537 + i)}); err != nil {
+			t.Fatalf("Unexpected error adding event: %v", err)
+		}
+	}
+
+	first, err := s.GetEvent(405419896, 0)
+	if err != nil {
+		t.Fatalf("Unexpected error retrieving first event: %v", err)
+	} else if first == nil || first.Index != 3 || first.CardNumber != 65539 {
+		t.Errorf("Incorrect first event - expected:%v, got:%v", 3, first)
+	}
+
+	last, err := s.GetEvent(405419896, 0xffffffff)
+	if err != nil {
+		t.Fatalf("Unexpected error retrieving last event: %v", err)
+	} else if last == nil || last.Index != 2 || last.CardNumber != 65543 {
+		t.Errorf("Incorrect last event - expected:%v, got:%v", 2, last)
+	}
+
+	vector := []struct {
+		index uint32
+		card  uint32
+	}{
+		{1, 65542},
+		{2, 65543},
+		{3, 65539},
+		{5, 65541},
+	}
+
+	for _, v := range vector {
+		if e, err := s.GetEvent(405419896, v.index); err != nil {
+			t.Errorf("Unexpected error retrieving event %v: %v", v.index, err)
+		} else if e == nil || e.Index != v.index || e.CardNumber != v.card {
+			t.Errorf("Incorrect event %v - expected card %v, got %v", v.index, v.card, e)
+		}
+	}
+
+	if e, err := s.GetEvent(405419896, 6); err != nil {
+		t.Errorf("Unexpected error retrieving event %v: %v", 6, err)
+	} else if e != nil {
+		t.Errorf("Expected no event for index %v, got %v", 6, e)
+	}
+}
+
+func TestSwipe(t *testing.T) {
+	from := types.ToDate(2020, time.January, 1)
+	to := types.ToDate(2099, time.December, 31)
+
+	s := NewSimulator(nil, NewDevice(405419896, net.IPv4(192, 168, 1, 100)))
+
+	if ok, err := s.PutCard(405419896, types.Card{CardNumber: 8165538, From: &from, To: &to, Doors: map[uint8]int{1: 1, 2: 0, 3: 0, 4: 0}}); err != nil || !ok {
+		t.Fatalf("Error adding card (%v,%v)", ok, err)
+	}
+
+	vector := []struct {
+		card    uint32
+		door    uint8
+		granted bool
+		reason  uint8
+	}{
+		{8165538, 1, true, 1},
+		{8165538, 2, false, 6},
+		{8165539, 1, false, 6},
+	}
+
+	for _, v := range vector {
+		e, err := s.Swipe(405419896, v.card, v.door, 1)
+		if err != nil {
+			t.Fatalf("Unexpected error swiping card %v: %v", v.card, err)
+		}
+
+		if e.Granted != v.granted || e.Reason != v.reason || e.Door != v.door {
+			t.Errorf("Incorrect swipe event for card %v, door %v - expected:%v/%v, got:%v/%v", v.card, v.door, v.granted, v.reason, e.Granted, e.Reason)
+		}
+	}
+}
+
+func TestFaultInjection(t *testing.T) {
+	from := types.ToDate(2020, time.January, 1)
+	to := types.ToDate(2099, time.December, 31)
+	card := types.Card{CardNumber: 8165538, From: &from, To: &to, Doors: map[uint8]int{1: 1, 2: 0, 3: 0, 4: 0}}
+
+	s := NewSimulator(nil, NewDevice(405419896, net.IPv4(192, 168, 1, 100)))
+
+	s.Inject(Fault{Type: Rejected, Operation: "put-card", Count: 1})
+	s.Inject(Fault{Type: Timeout, Operation: "get-cards", Skip: 1, Count: 1})
+	s.Inject(Fault{Type: DroppedReply, Operation: "delete-card", Count: 1})
+
+	if ok, err := s.PutCard(405419896, card); err != nil || ok {
+		t.Errorf("Expected 'rejected' put-card, got (%v,%v)", ok, err)
+	}
+
+	if ok, err := s.PutCard(405419896, card); err != nil || !ok {
+		t.Errorf("Expected successful put-card, got (%v,%v)", ok, err)
+	}
+
+	if N, err := s.GetCards(405419896); err != nil || N != 1 {
+		t.Errorf("Expected successful get-cards, got (%v,%v)", N, err)
+	}
+
+	if _, err := s.GetCards(405419896); !errors.Is(err, ErrTimeout) {
+		t.Errorf("Expected get-cards timeout, got %v", err)
+	}
+
+	if _, err := s.DeleteCard(405419896, 8165538); !errors.Is(err, ErrTimeout) {
+		t.Errorf("Expected delete-card timeout, got %v", err)
+	}
+
+	if N, err := s.GetCards(405419896); err != nil || N != 0 {
+		t.Errorf("Expected card to have been deleted despite dropped reply, got (%v,%v)", N, err)
+	}
+
+	if _, err := s.GetCards(12345); !errors.Is(err, ErrTimeout) {
+		t.Errorf("Expected timeout for unknown device, got %v", err)
+	}
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	from := types.ToDate(2020, time.January, 1)
+	to := types.ToDate(2099, time.December, 31)
+
+	s := NewSimulator(nil, NewDevice(405419896, net.IPv4(192, 168, 1, 100)), NewDevice(303986753, net.IPv4(192, 168, 1, 101)))
+
+	s.PutCard(405419896, types.Card{CardNumber: 8165538, From: &from, To: &to, Doors: map[uint8]int{1: 1, 2: 0, 3: 29, 4: 0}})
+	s.SetTimeProfile(405419896, types.TimeProfile{
+		ID:       29,
+		From:     &from,
+		To:       &to,
+		Weekdays: types.Weekdays{time.Monday: true, time.Tuesday: true},
+		Segments: types.Segments{
+			1: types.Segment{Start: types.NewHHmm(8, 30), End: types.NewHHmm(17, 00)},
+			2: types.Segment{},
+			3: types.Segment{},
+		},
+	})
+	s.Swipe(405419896, 8165538, 1, 1)
+	s.SetDoorControlState(303986753, 2, 1, 7)
+
+	var b bytes.Buffer
+	if err := s.Save(&b); err != nil {
+		t.Fatalf("Unexpected error saving simulator state: %v", err)
+	}
+
+	r := NewSimulator(nil)
+	if err := r.Load(&b); err != nil {
+		t.Fatalf("Unexpected error loading simulator state: %v", err)
+	}
+
+	for _, id := range []uint32{405419896, 303986753} {
+		p, _ := s.Device(id)
+		q, ok := r.Device(id)
+
+		if !ok {
+			t.Fatalf("Missing device %v in reloaded simulator", id)
+		}
+
+		if !reflect.DeepEqual(p, q) {
+			t.Errorf("Incorrectly reloaded device %v\n   expected:%+v\n   got:     %+v", id, p, q)
+		}
+	}
+}
@@ -0,0 +1,485 @@
+package simulator
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/uhppoted/uhppote-core/types"
+	"github.com/uhppoted/uhppote-core/uhppote"
+)
+
+func (s *Simulator) DeviceList() map[uint32]uhppote.Device {
+	s.guard.Lock()
+	defer s.guard.Unlock()
+
+	list := map[uint32]uhppote.Device{}
+	for id, d := range s.devices {
+		if d.Unconfigured {
+			continue
+		}
+
+		doors := []string{}
+		for _, door := range []uint8{1, 2, 3, 4} {
+			if dd, ok := d.Doors[door]; ok && dd != nil {
+				doors = append(doors, dd.Name)
+			} else {
+				doors = append(doors, "")
+			}
+		}
+
+		tz := time.Local
+		if d.TimeZone != "" {
+			if l, err := time.LoadLocation(d.TimeZone); err == nil {
+				tz = l
+			}
+		}
+
+		list[id] = uhppote.Device{
+			Name:     d.Name,
+			DeviceID: d.DeviceID,
+			Address:  &net.UDPAddr{IP: d.IpAddress, Port: d.Port},
+			Rollover: d.Events.rollover(),
+			Doors:    doors,
+			TimeZone: tz,
+		}
+	}
+
+	return list
+}
+
+func (s *Simulator) ListenAddr() *net.UDPAddr {
+	return s.listenAddr
+}
+
+func (s *Simulator) GetDevices() ([]types.Device, error) {
+	s.guard.Lock()
+	defer s.guard.Unlock()
+
+	devices := []types.Device{}
+	for _, d := range s.devices {
+		if f := s.match(d.DeviceID, "get-devices"); f == nil || f.Type == Rejected {
+			devices = append(devices, device(d))
+		}
+	}
+
+	sort.SliceStable(devices, func(i, j int) bool { return devices[i].SerialNumber < devices[j].SerialNumber })
+
+	return devices, nil
+}
+
+func (s *Simulator) GetDevice(deviceID uint32) (*types.Device, error) {
+	var reply *types.Device
+
+	_, err := s.exec(deviceID, "get-device", func(d *Device) error {
+		v := device(d)
+		reply = &v
+		return nil
+	})
+
+	return reply, err
+}
+
+func (s *Simulator) SetAddress(deviceID uint32, address, mask, gateway net.IP) (*types.Result, error) {
+	rejected, err := s.exec(deviceID, "set-address", func(d *Device) error {
+		d.IpAddress = address
+		d.SubnetMask = mask
+		d.Gateway = gateway
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.Result{SerialNumber: types.SerialNumber(deviceID), Succeeded: !rejected}, nil
+}
+
+func (s *Simulator) GetTime(deviceID uint32) (*types.Time, error) {
+	var reply *types.Time
+
+	_, err := s.exec(deviceID, "get-time", func(d *Device) error {
+		reply = &types.Time{
+			SerialNumber: types.SerialNumber(deviceID),
+			DateTime:     types.DateTime(d.now().Truncate(time.Second)),
+		}
+		return nil
+	})
+
+	return reply, err
+}
+
+func (s *Simulator) SetTime(deviceID uint32, datetime time.Time) (*types.Time, error) {
+	var reply *types.Time
+
+	_, err := s.exec(deviceID, "set-time", func(d *Device) error {
+		// controller clock is 'wall clock' time and is decoded as local time
+		local := time.Date(datetime.Year(), datetime.Month(), datetime.Day(), datetime.Hour(), datetime.Minute(), datetime.Second(), 0, time.Local)
+
+		d.TimeOffset = time.Until(local).Round(time.Second)
+		reply = &types.Time{
+			SerialNumber: types.SerialNumber(deviceID),
+			DateTime:     types.DateTime(d.now().Truncate(time.Second)),
+		}
+		return nil
+	})
+
+	return reply, err
+}
+
+func (s *Simulator) GetDoorControlState(deviceID uint32, door byte) (*types.DoorControlState, error) {
+	var reply *types.DoorControlState
+
+	_, err := s.exec(deviceID, "get-door-control", func(d *Device) error {
+		dd, err := d.door(door)
+		if err != nil {
+			return err
+		}
+
+		reply = &types.DoorControlState{
+			SerialNumber: types.SerialNumber(deviceID),
+			Door:         door,
+			ControlState: dd.ControlState,
+			Delay:        dd.Delay,
+		}
+
+		return nil
+	})
+
+	return reply, err
+}
+
+func (s *Simulator) SetDoorControlState(deviceID uint32, door uint8, state uint8, delay uint8) (*types.DoorControlState, error) {
+	var reply *types.DoorControlState
+
+	rejected, err := s.exec(deviceID, "set-door-control", func(d *Device) error {
+		dd, err := d.door(door)
+		if err != nil {
+			return err
+		}
+
+		dd.ControlState = state
+		dd.Delay = delay
+
+		reply = &types.DoorControlState{
+			SerialNumber: types.SerialNumber(deviceID),
+			Door:         door,
+			ControlState: dd.ControlState,
+			Delay:        dd.Delay,
+		}
+
+		return nil
+	})
+
+	if rejected {
+		return nil, fmt.Errorf("%v: failed to set door %v control state", deviceID, door)
+	}
+
+	return reply, err
+}
+
+func (s *Simulator) GetListener(deviceID uint32) (*types.Listener, error) {
+	var reply *types.Listener
+
+	_, err := s.exec(deviceID, "get-listener", func(d *Device) error {
+		reply = &types.Listener{
+			SerialNumber: types.SerialNumber(deviceID),
+			Address:      d.Listener,
+		}
+		return nil
+	})
+
+	return reply, err
+}
+
+func (s *Simulator) SetListener(deviceID uint32, address net.UDPAddr) (*types.Result, error) {
+	rejected, err := s.exec(deviceID, "set-listener", func(d *Device) error {
+		d.Listener = address
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.Result{SerialNumber: types.SerialNumber(deviceID), Succeeded: !rejected}, nil
+}
+
+func (s *Simulator) GetStatus(deviceID uint32) (*types.Status, error) {
+	var reply *types.Status
+
+	_, err := s.exec(deviceID, "get-status", func(d *Device) error {
+		status := s.status(d)
+		reply = &status
+		return nil
+	})
+
+	return reply, err
+}
+
+func (s *Simulator) GetCards(deviceID uint32) (uint32, error) {
+	var N uint32
+
+	_, err := s.exec(deviceID, "get-cards", func(d *Device) error {
+		N = d.cards()
+		return nil
+	})
+
+	return N, err
+}
+
+func (s *Simulator) GetCardByIndex(deviceID, index uint32) (*types.Card, error) {
+	var reply *types.Card
+
+	_, err := s.exec(deviceID, "get-card-by-index", func(d *Device) error {
+		if index > 0 && int(index) <= len(d.Cards) {
+			if card := d.Cards[index-1]; card.CardNumber != deleted {
+				c := card.Clone()
+				reply = &c
+			}
+		}
+		return nil
+	})
+
+	return reply, err
+}
+
+func (s *Simulator) GetCardByID(deviceID, cardNumber uint32) (*types.Card, error) {
+	var reply *types.Card
+
+	_, err := s.exec(deviceID, "get-card-by-id", func(d *Device) error {
+		if ix, ok := d.card(cardNumber); ok {
+			c := d.Cards[ix].Clone()
+			reply = &c
+		}
+		return nil
+	})
+
+	return reply, err
+}
+
+func (s *Simulator) PutCard(deviceID uint32, card types.Card) (bool, error) {
+	rejected, err := s.exec(deviceID, "put-card", func(d *Device) error {
+		if card.CardNumber == 0 || card.CardNumber == deleted {
+			return fmt.Errorf("Invalid card number (%v)", card.CardNumber)
+		}
+
+		if ix, ok := d.card(card.CardNumber); ok {
+			d.Cards[ix] = card.Clone()
+		} else {
+			d.Cards = append(d.Cards, card.Clone())
+		}
+
+		return nil
+	})
+
+	return !rejected && err == nil, err
+}
+
+func (s *Simulator) DeleteCard(deviceID uint32, cardNumber uint32) (bool, error) {
+	found := false
+
+	rejected, err := s.exec(deviceID, "delete-card", func(d *Device) error {
+		if ix, ok := d.card(cardNumber); ok {
+			d.Cards[ix].CardNumber = deleted
+			found = true
+		}
+
+		return nil
+	})
+
+	return !rejected && found, err
+}
+
+func (s *Simulator) DeleteCards(deviceID uint32) (bool, error) {
+	rejected, err := s.exec(deviceID, "delete-cards", func(d *Device) error {
+		d.Cards = []types.Card{}
+		return nil
+	})
+
+	return !rejected && err == nil, err
+}
+
+func (s *Simulator) GetTimeProfile(deviceID uint32, profileID uint8) (*types.TimeProfile, error) {
+	var reply *types.TimeProfile
+
+	_, err := s.exec(deviceID, "get-time-profile", func(d *Device) error {
+		if profile, ok := d.Profiles[profileID]; ok {
+			p := profile
+			reply = &p
+		}
+		return nil
+	})
+
+	return reply, err
+}
+
+func (s *Simulator) SetTimeProfile(deviceID uint32, profile types.TimeProfile) (bool, error) {
+	ok := false
+
+	rejected, err := s.exec(deviceID, "set-time-profile", func(d *Device) error {
+		if profile.ID >= 2 && profile.ID <= 254 {
+			d.Profiles[profile.ID] = profile
+			ok = true
+		}
+		return nil
+	})
+
+	return !rejected && ok, err
+}
+
+func (s *Simulator) ClearTimeProfiles(deviceID uint32) (bool, error) {
+	rejected, err := s.exec(deviceID, "clear-time-profiles", func(d *Device) error {
+		d.Profiles = map[uint8]types.TimeProfile{}
+		return nil
+	})
+
+	return !rejected && err == nil, err
+}
+
+func (s *Simulator) RecordSpecialEvents(deviceID uint32, enable bool) (bool, error) {
+	rejected, err := s.exec(deviceID, "record-special-events", func(d *Device) error {
+		d.SpecialEvents = enable
+		return nil
+	})
+
+	return !rejected && err == nil, err
+}
+
+// Index 0 returns the first (oldest) event in the buffer and index 0xffffffff returns the
+// last (most recent) event, matching the controller behaviour.
+func (s *Simulator) GetEvent(deviceID, index uint32) (*types.Event, error) {
+	var reply *types.Event
+
+	_, err := s.exec(deviceID, "get-event", func(d *Device) error {
+		var e *types.Event
+
+		switch index {
+		case 0:
+			e = d.Events.first()
+		case 0xffffffff:
+			e = d.Events.last()
+		default:
+			e = d.Events.get(index)
+		}
+
+		if e != nil {
+			event := *e
+			reply = &event
+		}
+
+		return nil
+	})
+
+	return reply, err
+}
+
+func (s *Simulator) GetEventIndex(deviceID uint32) (*types.EventIndex, error) {
+	var reply *types.EventIndex
+
+	_, err := s.exec(deviceID, "get-event-index", func(d *Device) error {
+		reply = &types.EventIndex{
+			SerialNumber: types.SerialNumber(deviceID),
+			Index:        d.EventIndex,
+		}
+		return nil
+	})
+
+	return reply, err
+}
+
+func (s *Simulator) SetEventIndex(deviceID, index uint32) (*types.EventIndexResult, error) {
+	var reply *types.EventIndexResult
+
+	rejected, err := s.exec(deviceID, "set-event-index", func(d *Device) error {
+		changed := d.EventIndex != index
+		d.EventIndex = index
+		reply = &types.EventIndexResult{
+			SerialNumber: types.SerialNumber(deviceID),
+			Index:        index,
+			Changed:      changed,
+		}
+		return nil
+	})
+
+	if rejected {
+		return &types.EventIndexResult{SerialNumber: types.SerialNumber(deviceID), Index: index, Changed: false}, nil
+	}
+
+	return reply, err
+}
+
+// Registers the listener for device events and blocks until the quit channel is signalled
+// or closed.
+func (s *Simulator) Listen(listener uhppote.Listener, q chan os.Signal) error {
+	s.guard.Lock()
+	s.listeners[listener] = struct{}{}
+	s.guard.Unlock()
+
+	defer func() {
+		s.guard.Lock()
+		delete(s.listeners, listener)
+		s.guard.Unlock()
+	}()
+
+	listener.OnConnected()
+
+	<-q
+
+	return nil
+}
+
+func (s *Simulator) OpenDoor(deviceID uint32, door uint8) (*types.Result, error) {
+	var status *types.Status
+	var device *Device
+
+	rejected, err := s.exec(deviceID, "open-door", func(d *Device) error {
+		if _, err := d.door(door); err != nil {
+			return err
+		}
+
+		d.Events.add(types.Event{
+			SerialNumber: types.SerialNumber(deviceID),
+			Type:         2,
+			Granted:      true,
+			Door:         door,
+			Direction:    1,
+			CardNumber:   0,
+			Timestamp:    types.DateTime(d.now()),
+			Reason:       44,
+		})
+
+		st := s.status(d)
+		status = &st
+		device = d
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if status != nil {
+		s.notify(device, *status)
+	}
+
+	return &types.Result{SerialNumber: types.SerialNumber(deviceID), Succeeded: !rejected}, nil
+}
+
+func device(d *Device) types.Device {
+	return types.Device{
+		Name:         d.Name,
+		SerialNumber: types.SerialNumber(d.DeviceID),
+		IpAddress:    d.IpAddress,
+		SubnetMask:   d.SubnetMask,
+		Gateway:      d.Gateway,
+		MacAddress:   d.MacAddress,
+		Version:      d.Version,
+		Date:         d.Date,
+		Address:      net.UDPAddr{IP: d.IpAddress, Port: d.Port},
+		TimeZone:     time.Local,
+	}
+}
@@ -1,6 +1,7 @@
 package uhppoted
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/uhppoted/uhppote-core/types"
@@ -13,16 +14,27 @@ type GetCardRecordsRequest struct {
 type GetCardRecordsResponse struct {
 	DeviceID DeviceID `json:"device-id"`
 	Cards    uint32   `json:"cards"`
+	Retries  uint64   `json:"retries,omitempty"`
 }
 
 func (u *UHPPOTED) GetCardRecords(request GetCardRecordsRequest) (*GetCardRecordsResponse, error) {
+	return u.GetCardRecordsWithContext(context.Background(), request)
+}
+
+func (u *UHPPOTED) GetCardRecordsWithContext(ctx context.Context, request GetCardRecordsRequest) (*GetCardRecordsResponse, error) {
 	u.debug("get-card-records", fmt.Sprintf("request  %+v", request))
 
+	u, retries := u.track()
+
 	device := uint32(request.DeviceID)
 
+	if err := cancelled(ctx); err != nil {
+		return nil, err
+	}
+
 	N, err := u.UHPPOTE.GetCards(device)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", InternalServerError, fmt.Errorf("Error retrieving number of cards from %v (%w)", device, err))
+		return nil, internalError("get-card-records", device, fmt.Errorf("Error retrieving number of cards from %v (%w)", device, err))
 	}
 
 	response := GetCardRecordsResponse{
@@ -30,6 +42,8 @@ func (u *UHPPOTED) GetCardRecords(request GetCardRecordsRequest) (*GetCardRecord
 		Cards:    N,
 	}
 
+	response.Retries = retries()
+
 	u.debug("get-card-records", fmt.Sprintf("response %+v", response))
 
 	return &response, nil
@@ -42,25 +56,40 @@ type GetCardsRequest struct {
 type GetCardsResponse struct {
 	DeviceID DeviceID `json:"device-id"`
 	Cards    []uint32 `json:"cards"`
+	Retries  uint64   `json:"retries,omitempty"`
 }
 
 func (u *UHPPOTED) GetCards(request GetCardsRequest) (*GetCardsResponse, error) {
+	return u.GetCardsWithContext(context.Background(), request)
+}
+
+func (u *UHPPOTED) GetCardsWithContext(ctx context.Context, request GetCardsRequest) (*GetCardsResponse, error) {
 	u.debug("get-cards", fmt.Sprintf("request  %+v", request))
 
+	u, retries := u.track()
+
 	device := uint32(request.DeviceID)
 
+	if err := cancelled(ctx); err != nil {
+		return nil, err
+	}
+
 	N, err := u.UHPPOTE.GetCards(device)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", InternalServerError, fmt.Errorf("Error retrieving cards from %v (%w)", device, err))
+		return nil, internalError("get-cards", device, fmt.Errorf("Error retrieving cards from %v (%w)", device, err))
 	}
 
 	cards := make([]uint32, 0)
 
 	var index uint32 = 1
 	for count := uint32(0); count < N; {
+		if err := cancelled(ctx); err != nil {
+			return nil, err
+		}
+
 		record, err := u.UHPPOTE.GetCardByIndex(device, index)
 		if err != nil {
-			return nil, fmt.Errorf("%w: %v", InternalServerError, fmt.Errorf("Error retrieving cards from %v (%w)", device, err))
+			return nil, internalError("get-cards", device, fmt.Errorf("Error retrieving cards from %v (%w)", device, err))
 		}
 
 		if record != nil {
@@ -76,6 +105,8 @@ func (u *UHPPOTED) GetCards(request GetCardsRequest) (*GetCardsResponse, error)
 		Cards:    cards,
 	}
 
+	response.Retries = retries()
+
 	u.debug("get-cards", fmt.Sprintf("response %+v", response))
 
 	return &response, nil
@@ -88,16 +119,27 @@ type DeleteCardsRequest struct {
 type DeleteCardsResponse struct {
 	DeviceID DeviceID `json:"device-id"`
 	Deleted  bool     `json:"deleted"`
+	Retries  uint64   `json:"retries,omitempty"`
 }
 
 func (u *UHPPOTED) DeleteCards(request DeleteCardsRequest) (*DeleteCardsResponse, error) {
+	return u.DeleteCardsWithContext(context.Background(), request)
+}
+
+func (u *UHPPOTED) DeleteCardsWithContext(ctx context.Context, request DeleteCardsRequest) (*DeleteCardsResponse, error) {
 	u.debug("delete-cards", fmt.Sprintf("request  %+v", request))
 
+	u, retries := u.track()
+
 	deviceID := uint32(request.DeviceID)
 
+	if err := cancelled(ctx); err != nil {
+		return nil, err
+	}
+
 	deleted, err := u.UHPPOTE.DeleteCards(deviceID)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", InternalServerError, fmt.Errorf("Error deleting cards from %v (%w)", deviceID, err))
+		return nil, internalError("delete-cards", deviceID, fmt.Errorf("Error deleting cards from %v (%w)", deviceID, err))
 	}
 
 	response := DeleteCardsResponse{
@@ -105,6 +147,8 @@ func (u *UHPPOTED) DeleteCards(request DeleteCardsRequest) (*DeleteCardsResponse
 		Deleted:  deleted,
 	}
 
+	response.Retries = retries()
+
 	u.debug("delete-cards", fmt.Sprintf("response %+v", response))
 
 	return &response, nil
@@ -118,21 +162,32 @@ type GetCardRequest struct {
 type GetCardResponse struct {
 	DeviceID DeviceID   `json:"device-id"`
 	Card     types.Card `json:"card"`
+	Retries  uint64     `json:"retries,omitempty"`
 }
 
 func (u *UHPPOTED) GetCard(request GetCardRequest) (*GetCardResponse, error) {
+	return u.GetCardWithContext(context.Background(), request)
+}
+
+func (u *UHPPOTED) GetCardWithContext(ctx context.Context, request GetCardRequest) (*GetCardResponse, error) {
 	u.debug("get-card", fmt.Sprintf("request  %+v", request))
 
+	u, retries := u.track()
+
 	device := uint32(request.DeviceID)
 	cardID := request.CardNumber
 
+	if err := cancelled(ctx); err != nil {
+		return nil, err
+	}
+
 	card, err := u.UHPPOTE.GetCardByID(device, cardID)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", InternalServerError, fmt.Errorf("Error retrieving card %v from %v (%w)", card.CardNumber, device, err))
+		return nil, internalError("get-card", device, fmt.Errorf("Error retrieving card %v from %v (%w)", cardID, device, err)).withCard(cardID)
 	}
 
 	if card == nil {
-		return nil, fmt.Errorf("%w: %v", NotFound, fmt.Errorf("Error retrieving card %v from %v", request.CardNumber, device))
+		return nil, notFound("get-card", device, fmt.Errorf("Error retrieving card %v from %v", request.CardNumber, device)).withCard(request.CardNumber)
 	}
 
 	response := GetCardResponse{
@@ -140,6 +195,8 @@ func (u *UHPPOTED) GetCard(request GetCardRequest) (*GetCardResponse, error) {
 		Card:     *card,
 	}
 
+	response.Retries = retries()
+
 	u.debug("get-card", fmt.Sprintf("response %+v", response))
 
 	return &response, nil
@@ -153,21 +210,32 @@ type PutCardRequest struct {
 type PutCardResponse struct {
 	DeviceID DeviceID   `json:"device-id"`
 	Card     types.Card `json:"card"`
+	Retries  uint64     `json:"retries,omitempty"`
 }
 
 func (u *UHPPOTED) PutCard(request PutCardRequest) (*PutCardResponse, error) {
+	return u.PutCardWithContext(context.Background(), request)
+}
+
+func (u *UHPPOTED) PutCardWithContext(ctx context.Context, request PutCardRequest) (*PutCardResponse, error) {
 	u.debug("put-card", fmt.Sprintf("request  %+v", request))
 
+	u, retries := u.track()
+
 	deviceID := uint32(request.DeviceID)
 	card := request.Card
 
+	if err := cancelled(ctx); err != nil {
+		return nil, err
+	}
+
 	authorised, err := u.UHPPOTE.PutCard(deviceID, card)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", InternalServerError, fmt.Errorf("Error writing card %v to %v (%w)", card.CardNumber, deviceID, err))
+		return nil, internalError("put-card", deviceID, fmt.Errorf("Error writing card %v to %v (%w)", card.CardNumber, deviceID, err)).withCard(card.CardNumber)
 	}
 
 	if !authorised {
-		return nil, fmt.Errorf("%w: %v", InternalServerError, fmt.Errorf("Failed to write card %v to %v (%w)", card.CardNumber, deviceID, err))
+		return nil, internalError("put-card", deviceID, fmt.Errorf("Failed to write card %v to %v", card.CardNumber, deviceID)).withCard(card.CardNumber).permanent()
 	}
 
 	response := PutCardResponse{
@@ -175,6 +243,8 @@ func (u *UHPPOTED) PutCard(request PutCardRequest) (*PutCardResponse, error) {
 		Card:     card,
 	}
 
+	response.Retries = retries()
+
 	u.debug("put-card", fmt.Sprintf("response %+v", response))
 
 	return &response, nil
@@ -189,17 +259,28 @@ type DeleteCardResponse struct {
 	DeviceID   DeviceID `json:"device-id"`
 	CardNumber uint32   `json:"card-number"`
 	Deleted    bool     `json:"deleted"`
+	Retries    uint64   `json:"retries,omitempty"`
 }
 
 func (u *UHPPOTED) DeleteCard(request DeleteCardRequest) (*DeleteCardResponse, error) {
+	return u.DeleteCardWithContext(context.Background(), request)
+}
+
+func (u *UHPPOTED) DeleteCardWithContext(ctx context.Context, request DeleteCardRequest) (*DeleteCardResponse, error) {
 	u.debug("delete-card", fmt.Sprintf("request  %+v", request))
 
+	u, retries := u.track()
+
 	deviceID := uint32(request.DeviceID)
 	cardNo := request.CardNumber
 
+	if err := cancelled(ctx); err != nil {
+		return nil, err
+	}
+
 	deleted, err := u.UHPPOTE.DeleteCard(deviceID, cardNo)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", InternalServerError, fmt.Errorf("Error deleting card %v from %v (%w)", cardNo, deviceID, err))
+		return nil, internalError("delete-card", deviceID, fmt.Errorf("Error deleting card %v from %v (%w)", cardNo, deviceID, err)).withCard(cardNo)
 	}
 
 	response := DeleteCardResponse{
@@ -208,6 +289,8 @@ func (u *UHPPOTED) DeleteCard(request DeleteCardRequest) (*DeleteCardResponse, e
 		Deleted:    deleted,
 	}
 
+	response.Retries = retries()
+
 	u.debug("delete-card", fmt.Sprintf("response %+v", response))
 
 	return &response, nil
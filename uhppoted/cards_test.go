@@ -0,0 +1,46 @@
+package uhppoted
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/uhppoted/uhppote-core/types"
+	"github.com/uhppoted/uhppoted-api/simulator"
+)
+
+func TestGetCardsWithCancelledContext(t *testing.T) {
+	device := simulator.NewDevice(405419896, net.IPv4(192, 168, 1, 125))
+	device.Cards = []types.Card{
+		types.Card{CardNumber: 65537, From: date("2020-01-02"), To: date("2020-10-31"), Doors: map[uint8]int{1: 1, 2: 0, 3: 0, 4: 0}},
+		types.Card{CardNumber: 65538, From: date("2020-02-03"), To: date("2020-11-30"), Doors: map[uint8]int{1: 1, 2: 0, 3: 0, 4: 1}},
+	}
+
+	s := simulator.NewSimulator(nil, device)
+	u := UHPPOTED{
+		UHPPOTE: s,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	response, err := u.GetCardsWithContext(ctx, GetCardsRequest{DeviceID: 405419896})
+	if err == nil {
+		t.Fatalf("Expected 'cancelled' error, got %v", response)
+	}
+
+	if !errors.Is(err, Cancelled) {
+		t.Errorf("Expected 'cancelled' error, got %v", err)
+	}
+
+	if errors.Is(err, InternalServerError) {
+		t.Errorf("Expected 'cancelled' error to be distinguishable from internal server error, got %v", err)
+	}
+
+	if response, err := u.GetCards(GetCardsRequest{DeviceID: 405419896}); err != nil {
+		t.Fatalf("Unexpected error retrieving cards: %v", err)
+	} else if len(response.Cards) != 2 {
+		t.Errorf("Incorrect cards - expected:%v, got:%v", 2, response.Cards)
+	}
+}
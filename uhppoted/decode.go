@@ -0,0 +1,128 @@
+package uhppoted
+
+import (
+	"fmt"
+)
+
+type EventType uint8
+type EventReason uint8
+type Direction uint8
+
+// Decoded event code, with a stable symbolic name and a human readable description.
+type Decoded struct {
+	Code uint8  `json:"code"`
+	Name string `json:"name"`
+	Text string `json:"text"`
+}
+
+// Decoded event type, reason and direction. Included in events when UHPPOTED.DecodeEvents
+// is enabled.
+type EventInfo struct {
+	Type      Decoded `json:"type"`
+	Reason    Decoded `json:"reason"`
+	Direction Decoded `json:"direction"`
+}
+
+type code struct {
+	name string
+	text string
+}
+
+var eventTypes = map[EventType]code{
+	0x00: {"none", "None"},
+	0x01: {"swipe", "Card swipe"},
+	0x02: {"door", "Door"},
+	0x03: {"alarm", "Alarm"},
+	0xff: {"overwritten", "Overwritten"},
+}
+
+var eventReasons = map[EventReason]code{
+	0:  {"none", "None"},
+	1:  {"swipe", "Swipe"},
+	5:  {"denied", "Swipe denied"},
+	6:  {"no-access-rights", "No access rights"},
+	7:  {"incorrect-password", "Incorrect password"},
+	8:  {"anti-passback", "Anti-passback"},
+	9:  {"more-cards", "More cards"},
+	10: {"first-card-open", "First card open"},
+	11: {"door-normally-closed", "Door is normally closed"},
+	12: {"interlock", "Interlock"},
+	13: {"not-in-allowed-time-period", "Not in allowed time period"},
+	15: {"invalid-timezone", "Invalid timezone"},
+	18: {"access-denied", "Access denied"},
+	20: {"push-button-ok", "Push button ok"},
+	23: {"door-opened", "Door opened"},
+	24: {"door-closed", "Door closed"},
+	25: {"door-opened-supervisor-password", "Door opened (supervisor password)"},
+	28: {"controller-power-on", "Controller power on"},
+	29: {"controller-reset", "Controller reset"},
+	31: {"push-button-invalid-forced-lock", "Push button invalid (forced lock)"},
+	32: {"push-button-invalid-not-online", "Push button invalid (not online)"},
+	33: {"push-button-invalid-interlock", "Push button invalid (interlock)"},
+	34: {"push-button-invalid-threat", "Push button invalid (threat)"},
+	37: {"door-open-too-long", "Door open too long"},
+	38: {"forced-open", "Forced open"},
+	39: {"fire", "Fire"},
+	40: {"forced-close", "Forced close"},
+	41: {"theft-prevention", "Theft prevention"},
+	42: {"24x7-zone", "24x7 zone"},
+	43: {"emergency", "Emergency"},
+	44: {"remote-open-door", "Remote open door"},
+	45: {"remote-open-door-usb-reader", "Remote open door (USB reader)"},
+}
+
+var directions = map[Direction]code{
+	0: {"none", "None"},
+	1: {"in", "In"},
+	2: {"out", "Out"},
+}
+
+func (t EventType) Name() string {
+	return lookup(eventTypes[t], uint8(t)).name
+}
+
+func (t EventType) String() string {
+	return lookup(eventTypes[t], uint8(t)).text
+}
+
+func (r EventReason) Name() string {
+	return lookup(eventReasons[r], uint8(r)).name
+}
+
+func (r EventReason) String() string {
+	return lookup(eventReasons[r], uint8(r)).text
+}
+
+func (d Direction) Name() string {
+	return lookup(directions[d], uint8(d)).name
+}
+
+func (d Direction) String() string {
+	return lookup(directions[d], uint8(d)).text
+}
+
+// Decodes the raw event type, reason and direction codes.
+func DecodeEvent(eventType, reason, direction uint8) EventInfo {
+	return EventInfo{
+		Type:      Decoded{Code: eventType, Name: EventType(eventType).Name(), Text: EventType(eventType).String()},
+		Reason:    Decoded{Code: reason, Name: EventReason(reason).Name(), Text: EventReason(reason).String()},
+		Direction: Decoded{Code: direction, Name: Direction(direction).Name(), Text: Direction(direction).String()},
+	}
+}
+
+func (u *UHPPOTED) decode(eventType, reason, direction uint8) *EventInfo {
+	if u != nil && u.DecodeEvents {
+		info := DecodeEvent(eventType, reason, direction)
+		return &info
+	}
+
+	return nil
+}
+
+func lookup(c code, v uint8) code {
+	if c.name == "" {
+		return code{"unknown", fmt.Sprintf("Unknown (%v)", v)}
+	}
+
+	return c
+}
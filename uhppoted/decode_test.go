@@ -0,0 +1,76 @@
+package uhppoted
+
+import (
+	"encoding/json"
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/uhppoted/uhppote-core/types"
+	"github.com/uhppoted/uhppoted-api/simulator"
+)
+
+func TestDecodeEvent(t *testing.T) {
+	vector := []struct {
+		eventType uint8
+		reason    uint8
+		direction uint8
+		expected  EventInfo
+	}{
+		{1, 6, 1, EventInfo{
+			Type:      Decoded{Code: 1, Name: "swipe", Text: "Card swipe"},
+			Reason:    Decoded{Code: 6, Name: "no-access-rights", Text: "No access rights"},
+			Direction: Decoded{Code: 1, Name: "in", Text: "In"},
+		}},
+		{2, 44, 2, EventInfo{
+			Type:      Decoded{Code: 2, Name: "door", Text: "Door"},
+			Reason:    Decoded{Code: 44, Name: "remote-open-door", Text: "Remote open door"},
+			Direction: Decoded{Code: 2, Name: "out", Text: "Out"},
+		}},
+		{7, 99, 3, EventInfo{
+			Type:      Decoded{Code: 7, Name: "unknown", Text: "Unknown (7)"},
+			Reason:    Decoded{Code: 99, Name: "unknown", Text: "Unknown (99)"},
+			Direction: Decoded{Code: 3, Name: "unknown", Text: "Unknown (3)"},
+		}},
+	}
+
+	for _, v := range vector {
+		if info := DecodeEvent(v.eventType, v.reason, v.direction); !reflect.DeepEqual(info, v.expected) {
+			t.Errorf("Incorrectly decoded event %v/%v/%v\n   expected:%+v\n   got:     %+v", v.eventType, v.reason, v.direction, v.expected, info)
+		}
+	}
+}
+
+func TestGetEventWithDecoding(t *testing.T) {
+	device := simulator.NewDevice(405419896, net.IPv4(192, 168, 1, 125))
+	s := simulator.NewSimulator(nil, device)
+	s.AddEvent(405419896, types.Event{Type: 1, Granted: false, Door: 1, Direction: 1, CardNumber: 8165538, Reason: 6})
+
+	u := UHPPOTED{
+		UHPPOTE: s,
+	}
+
+	response, err := u.GetEvent(GetEventRequest{DeviceID: 405419896, EventID: 1})
+	if err != nil {
+		t.Fatalf("Unexpected error retrieving event: %v", err)
+	} else if response.Event.Info != nil {
+		t.Errorf("Expected undecoded event, got %+v", response.Event.Info)
+	}
+
+	u.DecodeEvents = true
+
+	response, err = u.GetEvent(GetEventRequest{DeviceID: 405419896, EventID: 1})
+	if err != nil {
+		t.Fatalf("Unexpected error retrieving event: %v", err)
+	}
+
+	bytes, err := json.Marshal(response.Event.Info)
+	if err != nil {
+		t.Fatalf("Unexpected error marshalling decoded event: %v", err)
+	}
+
+	expected := `{"type":{"code":1,"name":"swipe","text":"Card swipe"},"reason":{"code":6,"name":"no-access-rights","text":"No access rights"},"direction":{"code":1,"name":"in","text":"In"}}`
+	if string(bytes) != expected {
+		t.Errorf("Incorrect decoded event JSON\n   expected:%v\n   got:     %v", expected, string(bytes))
+	}
+}
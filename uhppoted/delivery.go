@@ -0,0 +1,128 @@
+package uhppoted
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/uhppoted/uhppoted-api/config"
+)
+
+// Event handler for at-least-once delivery. Returning nil acknowledges the event.
+type AckHandler func(EventMessage) error
+
+// At-least-once event delivery to a set of named subscribers. Each event is delivered to all
+// matching subscribers (concurrently), retrying according to the retry policy until the
+// subscriber acknowledges the event. Events that still fail after the last retry are written
+// to the dead-letter queue.
+//
+// The Handler only returns true once every matching subscriber has either acknowledged the
+// event or the event has been durably dead-lettered, so Listen does not advance the EventMap
+// past events that have not been delivered. Events may therefore be delivered more than once.
+type Delivery struct {
+	retry         config.Retry
+	dlq           *DeadLetterQueue
+	subscriptions *Subscriptions
+	subscribers   map[string]SubscriptionID
+	guard         sync.Mutex
+	log           func(error)
+}
+
+func NewDelivery(u *UHPPOTED, retry config.Retry, dlq *DeadLetterQueue) *Delivery {
+	if retry.Attempts < 1 {
+		retry.Attempts = 1
+	}
+
+	return &Delivery{
+		retry:         retry,
+		dlq:           dlq,
+		subscriptions: NewSubscriptions(),
+		subscribers:   map[string]SubscriptionID{},
+		log: func(err error) {
+			u.warn("delivery", err)
+		},
+	}
+}
+
+// Registers (or replaces) a named subscriber for the events that match the filter.
+func (d *Delivery) Subscribe(name string, filter Filter, handler AckHandler) {
+	d.guard.Lock()
+	defer d.guard.Unlock()
+
+	if id, ok := d.subscribers[name]; ok {
+		d.subscriptions.Unsubscribe(id)
+	}
+
+	d.subscribers[name] = d.subscriptions.SubscribeWithDelivery(filter, func(message EventMessage) bool {
+		return d.send(name, handler, message)
+	}, DeliveryPolicy{Synchronous: true})
+}
+
+func (d *Delivery) Unsubscribe(name string) {
+	d.guard.Lock()
+	defer d.guard.Unlock()
+
+	if id, ok := d.subscribers[name]; ok {
+		d.subscriptions.Unsubscribe(id)
+		delete(d.subscribers, name)
+	}
+}
+
+// Unsubscribes all subscribers.
+func (d *Delivery) Close() {
+	d.guard.Lock()
+	defer d.guard.Unlock()
+
+	d.subscriptions.Close()
+	d.subscribers = map[string]SubscriptionID{}
+}
+
+// Returns an EventHandler for Listen.
+func (d *Delivery) Handler() EventHandler {
+	return d.subscriptions.Handler()
+}
+
+// Delivers the event to the subscriber, returning true if the event was acknowledged or
+// dead-lettered.
+func (d *Delivery) send(name string, handler AckHandler, message EventMessage) bool {
+	var err error
+
+	backoff := d.retry.Backoff
+	for attempt := 1; attempt <= d.retry.Attempts; attempt++ {
+		if err = handler(message); err == nil {
+			return true
+		}
+
+		d.log(fmt.Errorf("%v: event %v delivery attempt %v failed (%w)", name, message.Event.EventID, attempt, err))
+
+		if attempt < d.retry.Attempts {
+			delay := backoff
+			if d.retry.Jitter > 0 {
+				delay += time.Duration(rand.Int63n(int64(d.retry.Jitter)))
+			}
+
+			time.Sleep(delay)
+			backoff *= 2
+		}
+	}
+
+	if d.dlq == nil {
+		return false
+	}
+
+	letter := DeadLetter{
+		Subscriber: name,
+		Event:      message,
+		Attempts:   d.retry.Attempts,
+		Error:      fmt.Sprintf("%v", err),
+		Timestamp:  time.Now(),
+	}
+
+	if err := d.dlq.Put(letter); err != nil {
+		d.log(fmt.Errorf("%v: error writing event %v to dead-letter queue (%w)", name, message.Event.EventID, err))
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,147 @@
+package uhppoted
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/uhppoted/uhppote-core/types"
+	"github.com/uhppoted/uhppoted-api/config"
+	"github.com/uhppoted/uhppoted-api/simulator"
+)
+
+func TestAtLeastOnceDelivery(t *testing.T) {
+	device := simulator.NewDevice(405419896, net.IPv4(192, 168, 1, 125))
+	s := simulator.NewSimulator(nil, device)
+	for i := 0; i < 5; i++ {
+		s.AddEvent(405419896, types.Event{Type: 1, Granted: true, Door: uint8(1 + i%2), CardNumber: 8165538, Reason: 1})
+	}
+
+	u := UHPPOTED{
+		UHPPOTE: s,
+	}
+
+	file := filepath.Join(t.TempDir(), "dlq.json")
+	dlq, err := NewDeadLetterQueue(file)
+	if err != nil {
+		t.Fatalf("Unexpected error creating dead-letter queue: %v", err)
+	}
+
+	guard := sync.Mutex{}
+	attempts := map[uint32]int{}
+
+	delivery := NewDelivery(&u, config.Retry{Attempts: 3, Backoff: time.Millisecond}, dlq)
+	defer delivery.Close()
+
+	delivery.Subscribe("flaky", Filter{}, func(m EventMessage) error {
+		guard.Lock()
+		defer guard.Unlock()
+
+		attempts[m.Event.EventID]++
+		if attempts[m.Event.EventID] < 3 {
+			return fmt.Errorf("unavailable")
+		}
+
+		return nil
+	})
+
+	delivery.Subscribe("broken", Filter{Doors: []uint8{2}}, func(m EventMessage) error {
+		return fmt.Errorf("broken")
+	})
+
+	if retrieved, _ := u.fetch(405419896, 1, 5, delivery.Handler()); retrieved != 5 {
+		t.Errorf("Incorrect retrieved event index - expected:%v, got:%v", 5, retrieved)
+	}
+
+	for i := uint32(1); i <= 5; i++ {
+		if attempts[i] != 3 {
+			t.Errorf("Incorrect delivery attempts for event %v - expected:%v, got:%v", i, 3, attempts[i])
+		}
+	}
+
+	// ... reload dead-letter queue from file
+	if dlq, err = NewDeadLetterQueue(file); err != nil {
+		t.Fatalf("Unexpected error reloading dead-letter queue: %v", err)
+	}
+
+	letters := dlq.List()
+	if len(letters) != 2 || letters[0].Event.Event.EventID != 2 || letters[1].Event.Event.EventID != 4 || letters[0].Subscriber != "broken" || letters[0].Attempts != 3 {
+		t.Errorf("Incorrect dead letters - got:%+v", letters)
+	}
+}
+
+func TestDeliveryWithoutAcknowledgement(t *testing.T) {
+	device := simulator.NewDevice(405419896, net.IPv4(192, 168, 1, 125))
+	s := simulator.NewSimulator(nil, device)
+	for i := 0; i < 5; i++ {
+		s.AddEvent(405419896, types.Event{Type: 1, Granted: true, Door: 1, CardNumber: 8165538, Reason: 1})
+	}
+
+	u := UHPPOTED{
+		UHPPOTE: s,
+	}
+
+	delivery := NewDelivery(&u, config.Retry{Attempts: 2, Backoff: time.Millisecond}, nil)
+	defer delivery.Close()
+
+	delivery.Subscribe("subscriber", Filter{}, func(m EventMessage) error {
+		if m.Event.EventID == 3 {
+			return fmt.Errorf("unavailable")
+		}
+
+		return nil
+	})
+
+	if retrieved, _ := u.fetch(405419896, 1, 5, delivery.Handler()); retrieved != 2 {
+		t.Errorf("Incorrect retrieved event index - expected:%v, got:%v", 2, retrieved)
+	}
+}
+
+func TestDeadLetterQueueWithPartialLastRecord(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "dlq.json")
+	records := `{"subscriber":"webhook","event":{"event":{"event-id":1}},"attempts":3}
+{"subscriber":"webhook","event":{"eve`
+
+	if err := os.WriteFile(file, []byte(records), 0660); err != nil {
+		t.Fatalf("Unexpected error writing dead-letter queue file: %v", err)
+	}
+
+	dlq, err := NewDeadLetterQueue(file)
+	if err != nil {
+		t.Fatalf("Unexpected error loading dead-letter queue: %v", err)
+	}
+
+	if letters := dlq.List(); len(letters) != 1 {
+		t.Fatalf("Incorrect number of dead letters - expected:%v, got:%v", 1, len(letters))
+	}
+
+	if err := dlq.Put(DeadLetter{Subscriber: "webhook", Event: EventMessage{Event: ListenEvent{DeviceID: 405419896, EventID: 2}}, Attempts: 3}); err != nil {
+		t.Fatalf("Unexpected error adding dead letter: %v", err)
+	}
+
+	if dlq, err = NewDeadLetterQueue(file); err != nil {
+		t.Fatalf("Unexpected error reloading dead-letter queue: %v", err)
+	} else if letters := dlq.List(); len(letters) != 2 {
+		t.Errorf("Incorrect number of dead letters - expected:%v, got:%v", 2, len(letters))
+	}
+}
+
+func TestDeadLetterQueueWithMalformedRecord(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "dlq.json")
+	records := `{"subscriber":"webhook","event":{"event":{"event-id":1}},"attempts":3}
+{"subscriber":"webhook","event":{"eve
+{"subscriber":"webhook","event":{"event":{"event-id":3}},"attempts":3}
+`
+
+	if err := os.WriteFile(file, []byte(records), 0660); err != nil {
+		t.Fatalf("Unexpected error writing dead-letter queue file: %v", err)
+	}
+
+	if _, err := NewDeadLetterQueue(file); err == nil {
+		t.Errorf("Expected error loading dead-letter queue with malformed record")
+	}
+}
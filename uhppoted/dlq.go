@@ -0,0 +1,161 @@
+package uhppoted
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Event that could not be delivered to a subscriber after all retries.
+type DeadLetter struct {
+	Subscriber string       `json:"subscriber"`
+	Event      EventMessage `json:"event"`
+	Attempts   int          `json:"attempts"`
+	Error      string       `json:"error"`
+	Timestamp  time.Time    `json:"timestamp"`
+}
+
+// Persistent dead-letter queue, stored as a JSON Lines file. An empty file name creates a
+// memory only queue.
+type DeadLetterQueue struct {
+	file    string
+	letters []DeadLetter
+	guard   sync.Mutex
+}
+
+func NewDeadLetterQueue(file string) (*DeadLetterQueue, error) {
+	q := DeadLetterQueue{
+		file:    file,
+		letters: []DeadLetter{},
+	}
+
+	if file != "" {
+		letters, err := q.load()
+		if err != nil {
+			return nil, err
+		}
+
+		q.letters = letters
+	}
+
+	return &q, nil
+}
+
+// Appends the dead letter to the queue. The dead letter is written and synced to disk before
+// Put returns.
+func (q *DeadLetterQueue) Put(letter DeadLetter) error {
+	q.guard.Lock()
+	defer q.guard.Unlock()
+
+	if q.file != "" {
+		bytes, err := json.Marshal(letter)
+		if err != nil {
+			return err
+		}
+
+		if err := os.MkdirAll(filepath.Dir(q.file), 0770); err != nil {
+			return err
+		}
+
+		f, err := os.OpenFile(q.file, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0660)
+		if err != nil {
+			return err
+		}
+
+		if _, err := f.Write(append(bytes, '\n')); err != nil {
+			f.Close()
+			return err
+		}
+
+		if err := f.Sync(); err != nil {
+			f.Close()
+			return err
+		}
+
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+
+	q.letters = append(q.letters, letter)
+
+	return nil
+}
+
+func (q *DeadLetterQueue) List() []DeadLetter {
+	q.guard.Lock()
+	defer q.guard.Unlock()
+
+	return append([]DeadLetter{}, q.letters...)
+}
+
+// Removes all dead letters from the queue (e.g. after they have been replayed).
+func (q *DeadLetterQueue) Clear() error {
+	q.guard.Lock()
+	defer q.guard.Unlock()
+
+	if q.file != "" {
+		if err := os.Remove(q.file); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	q.letters = []DeadLetter{}
+
+	return nil
+}
+
+// Loads the dead letters from the queue file. A malformed record is an error unless it is the
+// (incomplete) last line in the file, which is discarded so that it does not corrupt the next
+// record appended by Put.
+func (q *DeadLetterQueue) load() ([]DeadLetter, error) {
+	letters := []DeadLetter{}
+
+	f, err := os.Open(q.file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return letters, nil
+		}
+
+		return nil, err
+	}
+
+	defer f.Close()
+
+	offset := int64(0)
+	line := 0
+	reader := bufio.NewReader(f)
+
+	for {
+		record, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			if len(record) > 0 {
+				if err := os.Truncate(q.file, offset); err != nil {
+					return nil, err
+				}
+			}
+
+			return letters, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		line++
+		if text := bytes.TrimSpace(record); len(text) > 0 {
+			letter := DeadLetter{}
+			if err := json.Unmarshal(text, &letter); err != nil {
+				return nil, fmt.Errorf("%v: invalid dead letter at line %v (%v)", q.file, line, err)
+			}
+
+			letters = append(letters, letter)
+		}
+
+		offset += int64(len(record))
+	}
+}
@@ -1,6 +1,7 @@
 package uhppoted
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 )
@@ -53,16 +54,27 @@ type GetDoorDelayResponse struct {
 	DeviceID DeviceID `json:"device-id"`
 	Door     uint8    `json:"door"`
 	Delay    uint8    `json:"delay"`
+	Retries  uint64   `json:"retries,omitempty"`
 }
 
 func (u *UHPPOTED) GetDoorDelay(request GetDoorDelayRequest) (*GetDoorDelayResponse, error) {
+	return u.GetDoorDelayWithContext(context.Background(), request)
+}
+
+func (u *UHPPOTED) GetDoorDelayWithContext(ctx context.Context, request GetDoorDelayRequest) (*GetDoorDelayResponse, error) {
 	u.debug("get-door-delay", fmt.Sprintf("request  %+v", request))
 
+	u, retries := u.track()
+
 	device := uint32(request.DeviceID)
 	door := request.Door
+	if err := cancelled(ctx); err != nil {
+		return nil, err
+	}
+
 	result, err := u.UHPPOTE.GetDoorControlState(device, door)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", InternalServerError, fmt.Errorf("Error getting door %v delay for %v (%w)", door, device, err))
+		return nil, internalError("get-door-delay", device, fmt.Errorf("Error getting door %v delay for %v (%w)", door, device, err)).withDoor(door)
 	}
 
 	response := GetDoorDelayResponse{
@@ -71,6 +83,8 @@ func (u *UHPPOTED) GetDoorDelay(request GetDoorDelayRequest) (*GetDoorDelayRespo
 		Delay:    result.Delay,
 	}
 
+	response.Retries = retries()
+
 	u.debug("get-door-delay", fmt.Sprintf("response %+v", response))
 
 	return &response, nil
@@ -86,21 +100,36 @@ type SetDoorDelayResponse struct {
 	DeviceID DeviceID `json:"device-id"`
 	Door     uint8    `json:"door"`
 	Delay    uint8    `json:"delay"`
+	Retries  uint64   `json:"retries,omitempty"`
 }
 
 func (u *UHPPOTED) SetDoorDelay(request SetDoorDelayRequest) (*SetDoorDelayResponse, error) {
+	return u.SetDoorDelayWithContext(context.Background(), request)
+}
+
+func (u *UHPPOTED) SetDoorDelayWithContext(ctx context.Context, request SetDoorDelayRequest) (*SetDoorDelayResponse, error) {
 	u.debug("set-door-delay", fmt.Sprintf("request  %+v", request))
 
+	u, retries := u.track()
+
 	device := uint32(request.DeviceID)
 	door := request.Door
+	if err := cancelled(ctx); err != nil {
+		return nil, err
+	}
+
 	state, err := u.UHPPOTE.GetDoorControlState(device, door)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", InternalServerError, fmt.Errorf("Error getting door %v delay for %v (%w)", door, device, err))
+		return nil, internalError("set-door-delay", device, fmt.Errorf("Error getting door %v delay for %v (%w)", door, device, err)).withDoor(door)
+	}
+
+	if err := cancelled(ctx); err != nil {
+		return nil, err
 	}
 
 	result, err := u.UHPPOTE.SetDoorControlState(uint32(request.DeviceID), request.Door, state.ControlState, request.Delay)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", InternalServerError, fmt.Errorf("Error setting door %v delay %v for %v (%w)", door, state.ControlState, device, err))
+		return nil, internalError("set-door-delay", device, fmt.Errorf("Error setting door %v delay %v for %v (%w)", door, state.ControlState, device, err)).withDoor(door)
 	}
 
 	response := SetDoorDelayResponse{
@@ -109,6 +138,8 @@ func (u *UHPPOTED) SetDoorDelay(request SetDoorDelayRequest) (*SetDoorDelayRespo
 		Delay:    result.Delay,
 	}
 
+	response.Retries = retries()
+
 	u.debug("get-door-delay", fmt.Sprintf("response %+v", response))
 
 	return &response, nil
@@ -123,16 +154,27 @@ type GetDoorControlResponse struct {
 	DeviceID DeviceID     `json:"device-id"`
 	Door     uint8        `json:"door"`
 	Control  ControlState `json:"control"`
+	Retries  uint64       `json:"retries,omitempty"`
 }
 
 func (u *UHPPOTED) GetDoorControl(request GetDoorControlRequest) (*GetDoorControlResponse, error) {
+	return u.GetDoorControlWithContext(context.Background(), request)
+}
+
+func (u *UHPPOTED) GetDoorControlWithContext(ctx context.Context, request GetDoorControlRequest) (*GetDoorControlResponse, error) {
 	u.debug("get-door-control", fmt.Sprintf("request  %+v", request))
 
+	u, retries := u.track()
+
 	device := uint32(request.DeviceID)
 	door := request.Door
+	if err := cancelled(ctx); err != nil {
+		return nil, err
+	}
+
 	result, err := u.UHPPOTE.GetDoorControlState(device, door)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", InternalServerError, fmt.Errorf("Error getting door %v control for %v (%w)", door, device, err))
+		return nil, internalError("get-door-control", device, fmt.Errorf("Error getting door %v control for %v (%w)", door, device, err)).withDoor(door)
 	}
 
 	response := GetDoorControlResponse{
@@ -141,6 +183,8 @@ func (u *UHPPOTED) GetDoorControl(request GetDoorControlRequest) (*GetDoorContro
 		Control:  ControlState(result.ControlState),
 	}
 
+	response.Retries = retries()
+
 	u.debug("get-door-control", fmt.Sprintf("response %+v", response))
 
 	return &response, nil
@@ -156,21 +200,36 @@ type SetDoorControlResponse struct {
 	DeviceID DeviceID     `json:"device-id"`
 	Door     uint8        `json:"door"`
 	Control  ControlState `json:"control"`
+	Retries  uint64       `json:"retries,omitempty"`
 }
 
 func (u *UHPPOTED) SetDoorControl(request SetDoorControlRequest) (*SetDoorControlResponse, error) {
+	return u.SetDoorControlWithContext(context.Background(), request)
+}
+
+func (u *UHPPOTED) SetDoorControlWithContext(ctx context.Context, request SetDoorControlRequest) (*SetDoorControlResponse, error) {
 	u.debug("set-door-control", fmt.Sprintf("request  %+v", request))
 
+	u, retries := u.track()
+
 	device := uint32(request.DeviceID)
 	door := request.Door
+	if err := cancelled(ctx); err != nil {
+		return nil, err
+	}
+
 	state, err := u.UHPPOTE.GetDoorControlState(device, door)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", InternalServerError, fmt.Errorf("Error getting door %v control for %v (%w)", door, device, err))
+		return nil, internalError("set-door-control", device, fmt.Errorf("Error getting door %v control for %v (%w)", door, device, err)).withDoor(door)
+	}
+
+	if err := cancelled(ctx); err != nil {
+		return nil, err
 	}
 
 	result, err := u.UHPPOTE.SetDoorControlState(device, door, uint8(request.Control), state.Delay)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", InternalServerError, fmt.Errorf("Error setting door %v control %v for %v (%w)", door, request.Control, device, err))
+		return nil, internalError("set-door-control", device, fmt.Errorf("Error setting door %v control %v for %v (%w)", door, request.Control, device, err)).withDoor(door)
 	}
 
 	response := SetDoorControlResponse{
@@ -179,6 +238,8 @@ func (u *UHPPOTED) SetDoorControl(request SetDoorControlRequest) (*SetDoorContro
 		Control:  ControlState(result.ControlState),
 	}
 
+	response.Retries = retries()
+
 	u.debug("set-door-control", fmt.Sprintf("response %+v", response))
 
 	return &response, nil
@@ -193,16 +254,27 @@ type OpenDoorResponse struct {
 	DeviceID DeviceID `json:"device-id"`
 	Door     uint8    `json:"door"`
 	Opened   bool     `json:"opened"`
+	Retries  uint64   `json:"retries,omitempty"`
 }
 
 func (u *UHPPOTED) OpenDoor(request OpenDoorRequest) (*OpenDoorResponse, error) {
+	return u.OpenDoorWithContext(context.Background(), request)
+}
+
+func (u *UHPPOTED) OpenDoorWithContext(ctx context.Context, request OpenDoorRequest) (*OpenDoorResponse, error) {
 	u.debug("open-door", fmt.Sprintf("request  %+v", request))
 
+	u, retries := u.track()
+
 	device := uint32(request.DeviceID)
 	door := request.Door
+	if err := cancelled(ctx); err != nil {
+		return nil, err
+	}
+
 	result, err := u.UHPPOTE.OpenDoor(device, door)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", InternalServerError, fmt.Errorf("Error opening door %v on %v (%w)", door, device, err))
+		return nil, internalError("open-door", device, fmt.Errorf("Error opening door %v on %v (%w)", door, device, err)).withDoor(door)
 	}
 
 	response := OpenDoorResponse{
@@ -211,6 +283,8 @@ func (u *UHPPOTED) OpenDoor(request OpenDoorRequest) (*OpenDoorResponse, error)
 		Opened:   result.Succeeded,
 	}
 
+	response.Retries = retries()
+
 	u.debug("open-door", fmt.Sprintf("response %+v", response))
 
 	return &response, nil
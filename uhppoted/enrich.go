@@ -0,0 +1,58 @@
+package uhppoted
+
+import (
+	"fmt"
+
+	"github.com/uhppoted/uhppoted-api/kvs"
+)
+
+// Cardholder name lookup used to enrich events. Returns false if the card number is unknown.
+type CardholderLookup func(cardNumber uint32) (string, bool)
+
+type enrichment struct {
+	device     string
+	door       string
+	cardholder string
+}
+
+// Loads a cardholder lookup from a file of 'card number  name' lines (separated by at least
+// two spaces).
+func LoadCardholders(file string) (CardholderLookup, error) {
+	store := kvs.NewKeyValueStore("cardholders", func(v string) (interface{}, error) { return v, nil })
+	if err := store.LoadFromFile(file); err != nil {
+		return nil, err
+	}
+
+	return func(cardNumber uint32) (string, bool) {
+		if v, ok := store.Get(fmt.Sprintf("%v", cardNumber)); ok {
+			return fmt.Sprintf("%v", v), true
+		}
+
+		return "", false
+	}, nil
+}
+
+// Looks up the configured device and door names and the cardholder name for an event. Returns
+// an empty enrichment unless UHPPOTED.EnrichEvents is enabled.
+func (u *UHPPOTED) enrich(deviceID uint32, door uint8, cardNumber uint32) enrichment {
+	e := enrichment{}
+
+	if u == nil || !u.EnrichEvents {
+		return e
+	}
+
+	if d, ok := u.UHPPOTE.DeviceList()[deviceID]; ok {
+		e.device = d.Name
+		if door >= 1 && int(door) <= len(d.Doors) {
+			e.door = d.Doors[door-1]
+		}
+	}
+
+	if u.Cardholders != nil && cardNumber != 0 {
+		if name, ok := u.Cardholders(cardNumber); ok {
+			e.cardholder = name
+		}
+	}
+
+	return e
+}
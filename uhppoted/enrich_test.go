@@ -0,0 +1,66 @@
+package uhppoted
+
+import (
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/uhppoted/uhppote-core/types"
+	"github.com/uhppoted/uhppoted-api/simulator"
+)
+
+func TestEnrichEvents(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "cardholders")
+	if err := ioutil.WriteFile(file, []byte("8165538  Alice\n8165539  Bob\n"), 0660); err != nil {
+		t.Fatalf("Error creating cardholders file: %v", err)
+	}
+
+	cardholders, err := LoadCardholders(file)
+	if err != nil {
+		t.Fatalf("Unexpected error loading cardholders: %v", err)
+	}
+
+	device := simulator.NewDevice(405419896, net.IPv4(192, 168, 1, 125))
+	device.Name = "Main"
+	device.Doors[1].Name = "Front Door"
+
+	s := simulator.NewSimulator(nil, device)
+	s.AddEvent(405419896, types.Event{Type: 1, Granted: true, Door: 1, Direction: 1, CardNumber: 8165538, Reason: 1})
+	s.AddEvent(405419896, types.Event{Type: 1, Granted: false, Door: 2, Direction: 1, CardNumber: 8165540, Reason: 6})
+
+	u := UHPPOTED{
+		UHPPOTE:      s,
+		EnrichEvents: true,
+		Cardholders:  cardholders,
+	}
+
+	vector := []struct {
+		index      uint32
+		device     string
+		door       string
+		cardholder string
+	}{
+		{1, "Main", "Front Door", "Alice"},
+		{2, "Main", "", ""},
+	}
+
+	for _, v := range vector {
+		response, err := u.GetEvent(GetEventRequest{DeviceID: 405419896, EventID: v.index})
+		if err != nil {
+			t.Fatalf("Unexpected error retrieving event %v: %v", v.index, err)
+		}
+
+		e := response.Event
+		if e.DeviceName != v.device || e.DoorName != v.door || e.Cardholder != v.cardholder {
+			t.Errorf("Incorrectly enriched event %v - expected:%v/%v/%v, got:%v/%v/%v", v.index, v.device, v.door, v.cardholder, e.DeviceName, e.DoorName, e.Cardholder)
+		}
+	}
+
+	status, err := u.GetStatus(GetStatusRequest{DeviceID: 405419896})
+	if err != nil {
+		t.Fatalf("Unexpected error retrieving status: %v", err)
+	} else if status.Status.Event == nil || status.Status.Event.DeviceName != "Main" || status.Status.Event.DoorName != "" {
+		t.Errorf("Incorrectly enriched status event - got:%+v", status.Status.Event)
+	}
+}
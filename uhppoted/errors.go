@@ -0,0 +1,183 @@
+package uhppoted
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+type ErrorCode string
+
+const (
+	CodeBadRequest          ErrorCode = "bad-request"
+	CodeNotFound            ErrorCode = "not-found"
+	CodeUnauthorized        ErrorCode = "unauthorized"
+	CodeInternalServerError ErrorCode = "internal-server-error"
+	CodeCancelled           ErrorCode = "cancelled"
+)
+
+// Error is the structured error returned by the UHPPOTED functions. It identifies the operation
+// and the device/door/card/time profile involved (zero values are 'not applicable') and matches
+// the BadRequest, NotFound, Unauthorized, InternalServerError and Cancelled sentinels with
+// errors.Is. Unwrap returns the underlying cause.
+type Error struct {
+	Op        string
+	DeviceID  uint32
+	Door      uint8
+	Card      uint32
+	Profile   uint8
+	Code      ErrorCode
+	Retryable bool
+	Err       error
+}
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("%v", e.sentinel())
+	}
+
+	return fmt.Sprintf("%v: %v", e.sentinel(), e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Error) Is(target error) bool {
+	return target != nil && target == e.sentinel()
+}
+
+// Returns the HTTP status code corresponding to the error code.
+func (e *Error) Status() int {
+	switch e.Code {
+	case CodeBadRequest:
+		return http.StatusBadRequest
+	case CodeNotFound:
+		return http.StatusNotFound
+	case CodeUnauthorized:
+		return http.StatusUnauthorized
+	}
+
+	return http.StatusInternalServerError
+}
+
+func (e *Error) MarshalJSON() ([]byte, error) {
+	message := ""
+	if e.Err != nil {
+		message = e.Err.Error()
+	}
+
+	return json.Marshal(struct {
+		Op        string    `json:"operation,omitempty"`
+		DeviceID  uint32    `json:"device-id,omitempty"`
+		Door      uint8     `json:"door,omitempty"`
+		Card      uint32    `json:"card,omitempty"`
+		Profile   uint8     `json:"profile,omitempty"`
+		Code      ErrorCode `json:"code"`
+		Retryable bool      `json:"retryable"`
+		Message   string    `json:"message,omitempty"`
+	}{
+		Op:        e.Op,
+		DeviceID:  e.DeviceID,
+		Door:      e.Door,
+		Card:      e.Card,
+		Profile:   e.Profile,
+		Code:      e.Code,
+		Retryable: e.Retryable,
+		Message:   message,
+	})
+}
+
+func (e *Error) sentinel() error {
+	switch e.Code {
+	case CodeBadRequest:
+		return BadRequest
+	case CodeNotFound:
+		return NotFound
+	case CodeUnauthorized:
+		return Unauthorized
+	case CodeCancelled:
+		return Cancelled
+	}
+
+	return InternalServerError
+}
+
+func (e *Error) withDoor(door uint8) *Error {
+	e.Door = door
+	return e
+}
+
+func (e *Error) withCard(card uint32) *Error {
+	e.Card = card
+	return e
+}
+
+func (e *Error) withProfile(profile uint8) *Error {
+	e.Profile = profile
+	return e
+}
+
+// Marks the error as not retryable e.g. for requests rejected by the controller.
+func (e *Error) permanent() *Error {
+	e.Retryable = false
+	return e
+}
+
+func badRequest(op string, deviceID uint32, err error) *Error {
+	return &Error{Op: op, DeviceID: deviceID, Code: CodeBadRequest, Err: err}
+}
+
+func notFound(op string, deviceID uint32, err error) *Error {
+	return &Error{Op: op, DeviceID: deviceID, Code: CodeNotFound, Err: err}
+}
+
+// Device communication errors are assumed to be transient and are flagged as retryable if the
+// operation is idempotent.
+func internalError(op string, deviceID uint32, err error) *Error {
+	return &Error{Op: op, DeviceID: deviceID, Code: CodeInternalServerError, Retryable: Idempotent(op), Err: err}
+}
+
+// Operations (both UHPPOTED functions and controller requests) that are safe to resend if the
+// reply was lost i.e. resending the request leaves the controller in the same state as sending
+// it once. The only exception is open-door, which unlocks the door again.
+var idempotent = map[string]bool{
+	"get-devices":           true,
+	"get-device":            true,
+	"set-address":           true,
+	"get-time":              true,
+	"set-time":              true,
+	"synchronize-time":      true,
+	"get-door-control":      true,
+	"get-door-delay":        true,
+	"set-door-control":      true,
+	"set-door-delay":        true,
+	"get-listener":          true,
+	"set-listener":          true,
+	"get-status":            true,
+	"get-cards":             true,
+	"get-card":              true,
+	"get-card-records":      true,
+	"get-card-by-index":     true,
+	"get-card-by-id":        true,
+	"put-card":              true,
+	"delete-card":           true,
+	"delete-cards":          true,
+	"get-time-profile":      true,
+	"get-time-profiles":     true,
+	"set-time-profile":      true,
+	"put-time-profile":      true,
+	"put-time-profiles":     true,
+	"clear-time-profiles":   true,
+	"record-special-events": true,
+	"get-event":             true,
+	"get-events":            true,
+	"get-event-range":       true,
+	"get-event-index":       true,
+	"set-event-index":       true,
+}
+
+// Returns true if the operation is safe to retry, e.g. after a timeout.
+func Idempotent(op string) bool {
+	return idempotent[op]
+}
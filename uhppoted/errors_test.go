@@ -0,0 +1,237 @@
+package uhppoted
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/uhppoted/uhppote-core/types"
+	"github.com/uhppoted/uhppoted-api/simulator"
+)
+
+func TestErrorWithDeviceTimeout(t *testing.T) {
+	s := simulator.NewSimulator(nil, simulator.NewDevice(405419896, net.IPv4(192, 168, 1, 125)))
+	s.Inject(simulator.Fault{Type: simulator.Timeout, Operation: "get-status"})
+
+	u := UHPPOTED{
+		UHPPOTE: s,
+	}
+
+	_, err := u.GetStatus(GetStatusRequest{DeviceID: 405419896})
+	if err == nil {
+		t.Fatalf("Expected error, got nil")
+	}
+
+	if !errors.Is(err, InternalServerError) {
+		t.Errorf("Expected InternalServerError, got %v", err)
+	}
+
+	var e *Error
+	if !errors.As(err, &e) {
+		t.Fatalf("Expected *Error, got %T", err)
+	}
+
+	if e.Op != "get-status" || e.DeviceID != 405419896 || e.Code != CodeInternalServerError || !e.Retryable {
+		t.Errorf("Incorrect error - got:%+v", e)
+	}
+
+	if e.Status() != http.StatusInternalServerError {
+		t.Errorf("Incorrect HTTP status - expected:%v, got:%v", http.StatusInternalServerError, e.Status())
+	}
+}
+
+func TestErrorWithRejectedRequest(t *testing.T) {
+	s := simulator.NewSimulator(nil, simulator.NewDevice(405419896, net.IPv4(192, 168, 1, 125)))
+	s.Inject(simulator.Fault{Type: simulator.Rejected, Operation: "put-card"})
+
+	u := UHPPOTED{
+		UHPPOTE: s,
+	}
+
+	_, err := u.PutCard(PutCardRequest{
+		DeviceID: 405419896,
+		Card:     types.Card{CardNumber: 8165538, From: date("2021-01-01"), To: date("2021-12-31"), Doors: map[uint8]int{1: 1, 2: 0, 3: 0, 4: 0}},
+	})
+
+	var e *Error
+	if !errors.As(err, &e) {
+		t.Fatalf("Expected *Error, got %v", err)
+	}
+
+	if e.Op != "put-card" || e.Card != 8165538 || e.Retryable {
+		t.Errorf("Incorrect error - got:%+v", e)
+	}
+
+	if strings.Contains(err.Error(), "%!") {
+		t.Errorf("Incorrectly formatted error message - got:%v", err)
+	}
+}
+
+func TestGetCardWithDeviceTimeout(t *testing.T) {
+	s := simulator.NewSimulator(nil, simulator.NewDevice(405419896, net.IPv4(192, 168, 1, 125)))
+	s.Inject(simulator.Fault{Type: simulator.Timeout, Operation: "get-card-by-id"})
+
+	u := UHPPOTED{
+		UHPPOTE: s,
+	}
+
+	_, err := u.GetCard(GetCardRequest{DeviceID: 405419896, CardNumber: 8165538})
+
+	var e *Error
+	if !errors.As(err, &e) {
+		t.Fatalf("Expected *Error, got %v", err)
+	}
+
+	if e.Op != "get-card" || e.Card != 8165538 || e.Code != CodeInternalServerError {
+		t.Errorf("Incorrect error - got:%+v", e)
+	}
+}
+
+func TestErrorWithNotFound(t *testing.T) {
+	s := simulator.NewSimulator(nil, simulator.NewDevice(405419896, net.IPv4(192, 168, 1, 125)))
+	u := UHPPOTED{
+		UHPPOTE: s,
+	}
+
+	_, err := u.GetTimeProfile(GetTimeProfileRequest{DeviceID: 405419896, ProfileID: 29})
+
+	if !errors.Is(err, NotFound) || errors.Is(err, InternalServerError) {
+		t.Errorf("Expected NotFound error, got %v", err)
+	}
+
+	var e *Error
+	if !errors.As(err, &e) {
+		t.Fatalf("Expected *Error, got %T", err)
+	}
+
+	if e.Profile != 29 || e.Status() != http.StatusNotFound {
+		t.Errorf("Incorrect error - got:%+v", e)
+	}
+}
+
+func TestErrorWithCancelledContext(t *testing.T) {
+	s := simulator.NewSimulator(nil, simulator.NewDevice(405419896, net.IPv4(192, 168, 1, 125)))
+	u := UHPPOTED{
+		UHPPOTE: s,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := u.GetTimeWithContext(ctx, GetTimeRequest{DeviceID: 405419896})
+
+	if !errors.Is(err, Cancelled) {
+		t.Errorf("Expected Cancelled error, got %v", err)
+	}
+
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected error to wrap context.Canceled, got %v", err)
+	}
+}
+
+func TestPutTimeProfilesWithCircularReferenceCheckTimeout(t *testing.T) {
+	from := date("2021-04-01")
+	to := date("2021-12-31")
+
+	device := simulator.NewDevice(405419896, net.IPv4(192, 168, 1, 125))
+	device.Profiles[30] = types.TimeProfile{ID: 30, From: from, To: to}
+
+	s := simulator.NewSimulator(nil, device)
+	s.Inject(simulator.Fault{Type: simulator.Timeout, Operation: "get-time-profile", Skip: 1})
+
+	u := UHPPOTED{
+		UHPPOTE: s,
+	}
+
+	response, err := u.PutTimeProfiles(PutTimeProfilesRequest{
+		DeviceID: 405419896,
+		Profiles: []types.TimeProfile{
+			types.TimeProfile{ID: 29, LinkedProfileID: 30, From: from, To: to},
+		},
+	})
+
+	if !errors.Is(err, InternalServerError) {
+		t.Fatalf("Expected InternalServerError, got response:%+v, error:%v", response, err)
+	}
+
+	var e *Error
+	if !errors.As(err, &e) || e.Op != "put-time-profiles" || e.Profile != 29 {
+		t.Errorf("Incorrect error - got:%+v", err)
+	}
+}
+
+func TestPutTimeProfilesWithDuplicateProfiles(t *testing.T) {
+	s := simulator.NewSimulator(nil, simulator.NewDevice(405419896, net.IPv4(192, 168, 1, 125)))
+	u := UHPPOTED{
+		UHPPOTE: s,
+	}
+
+	from := date("2021-04-01")
+	to := date("2021-12-31")
+
+	_, err := u.PutTimeProfiles(PutTimeProfilesRequest{
+		DeviceID: 405419896,
+		Profiles: []types.TimeProfile{
+			types.TimeProfile{ID: 29, From: from, To: to},
+			types.TimeProfile{ID: 29, From: from, To: date("2021-06-30")},
+		},
+	})
+
+	if !errors.Is(err, BadRequest) {
+		t.Fatalf("Expected BadRequest error, got %v", err)
+	}
+
+	var e *Error
+	if !errors.As(err, &e) || e.Op != "put-time-profiles" || e.Profile != 29 || e.Status() != http.StatusBadRequest {
+		t.Errorf("Incorrect error - got:%+v", err)
+	}
+}
+
+func TestErrorMarshalJSON(t *testing.T) {
+	err := internalError("get-door-delay", 405419896, errors.New("timeout")).withDoor(3)
+
+	bytes, _ := json.Marshal(err)
+	expected := `{"operation":"get-door-delay","device-id":405419896,"door":3,"code":"internal-server-error","retryable":true,"message":"timeout"}`
+
+	if string(bytes) != expected {
+		t.Errorf("Incorrect JSON\n   expected:%v\n   got:     %v", expected, string(bytes))
+	}
+
+	if err.Error() != "INTERNAL SERVER ERROR: timeout" {
+		t.Errorf("Incorrect error message - got:%v", err.Error())
+	}
+}
+
+func TestErrorRetryableByOperation(t *testing.T) {
+	s := simulator.NewSimulator(nil, simulator.NewDevice(405419896, net.IPv4(192, 168, 1, 125)))
+	s.Inject(simulator.Fault{Type: simulator.Timeout, Operation: "open-door"})
+	s.Inject(simulator.Fault{Type: simulator.Timeout, Operation: "get-door-control"})
+
+	u := UHPPOTED{
+		UHPPOTE: s,
+	}
+
+	var e *Error
+
+	if _, err := u.OpenDoor(OpenDoorRequest{DeviceID: 405419896, Door: 1}); !errors.As(err, &e) {
+		t.Fatalf("Expected *Error, got %v", err)
+	} else if e.Op != "open-door" || e.Retryable {
+		t.Errorf("Incorrect open-door error - got:%+v", e)
+	}
+
+	if _, err := u.GetDoorDelay(GetDoorDelayRequest{DeviceID: 405419896, Door: 1}); !errors.As(err, &e) {
+		t.Fatalf("Expected *Error, got %v", err)
+	} else if e.Op != "get-door-delay" || !e.Retryable {
+		t.Errorf("Incorrect get-door-delay error - got:%+v", e)
+	}
+
+	for op := range idempotent {
+		if op == "open-door" {
+			t.Errorf("Operation %v should not be retryable", op)
+		}
+	}
+}
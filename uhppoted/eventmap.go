@@ -0,0 +1,331 @@
+package uhppoted
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/uhppoted/uhppoted-api/logging"
+)
+
+var InvalidEventMap = errors.New("Invalid event map")
+
+// Persistent map of device ID to the index of the last event retrieved (and acknowledged)
+// from the device.
+//
+// The event map is written to a temporary file in the same directory and then renamed, so an
+// interrupted write leaves the previous version intact. The file includes a CRC32 checksum of
+// the entries so that a corrupted file is detected when it is loaded. If a journal file is configured, each update is appended (and synced) to the
+// journal before the event map is rewritten and the journal is replayed by Load. A journal
+// without an event map file is never truncated, i.e. the journal is the event map.
+type EventMap struct {
+	file      string
+	journal   string
+	retrieved map[uint32]uint32
+	guard     sync.RWMutex
+	writeLock sync.Mutex
+}
+
+var (
+	checksumRE = regexp.MustCompile(`^#\s*checksum\s+([0-9a-fA-F]{8})\s*$`)
+	entryRE    = regexp.MustCompile(`^\s*(.*?)(?::\s*|\s*=\s*|\s+)(\S.*)\s*`)
+)
+
+func NewEventMap(file string) *EventMap {
+	return &EventMap{
+		file:      file,
+		retrieved: map[uint32]uint32{},
+	}
+}
+
+func NewEventMapWithJournal(file, journal string) *EventMap {
+	return &EventMap{
+		file:      file,
+		journal:   journal,
+		retrieved: map[uint32]uint32{},
+	}
+}
+
+// Loads the event map and replays the journal (if any). Returns an error wrapping
+// InvalidEventMap if the event map or journal is corrupt.
+func (m *EventMap) Load(log *log.Logger) error {
+	return m.LoadWithLogger(logging.NewLogAdapter(log, logging.DEBUG))
+}
+
+// Load with a structured logger for the journal replay.
+func (m *EventMap) LoadWithLogger(log logging.Logger) error {
+	m.writeLock.Lock()
+	defer m.writeLock.Unlock()
+
+	retrieved := map[uint32]uint32{}
+
+	if m.file != "" {
+		if f, err := os.Open(m.file); err != nil && !os.IsNotExist(err) {
+			return err
+		} else if err == nil {
+			err := parseEventMap(f, retrieved)
+			f.Close()
+
+			if err != nil {
+				return fmt.Errorf("%w: %v (%v)", InvalidEventMap, m.file, err)
+			}
+		}
+	}
+
+	if m.journal != "" {
+		if f, err := os.Open(m.journal); err != nil && !os.IsNotExist(err) {
+			return err
+		} else if err == nil {
+			N, err := replay(f, retrieved)
+			f.Close()
+
+			if err != nil {
+				return fmt.Errorf("%w: %v (%v)", InvalidEventMap, m.journal, err)
+			}
+
+			if N > 0 && log != nil {
+				log.Info(fmt.Sprintf("replayed %v event map journal entries from %v", N, m.journal), logging.Operation("event-map"))
+			}
+		}
+	}
+
+	m.guard.Lock()
+	m.retrieved = retrieved
+	m.guard.Unlock()
+
+	return nil
+}
+
+func (m *EventMap) get(deviceID uint32) (uint32, bool) {
+	m.guard.RLock()
+	defer m.guard.RUnlock()
+
+	index, ok := m.retrieved[deviceID]
+
+	return index, ok
+}
+
+// Records the last retrieved event for a device, journalling the update (if a journal is
+// configured) before rewriting the event map file. 'first' and 'last' are the indices of the
+// first and last events in the device event buffer - the update is ignored if the current
+// index is still in the event buffer and is not before the new index, so that a slower
+// (concurrent) retrieval cannot move the event map backwards.
+func (m *EventMap) update(deviceID, index, first, last uint32) error {
+	m.writeLock.Lock()
+	defer m.writeLock.Unlock()
+
+	if current, ok := m.get(deviceID); ok {
+		if current == index {
+			return nil
+		}
+
+		if ix := EventIndex(current); ix.in(first, last) && !ix.in(first, index) {
+			return nil
+		}
+	}
+
+	if err := m.append(deviceID, index); err != nil {
+		return err
+	}
+
+	m.guard.Lock()
+	m.retrieved[deviceID] = index
+	m.guard.Unlock()
+
+	if err := m.store(); err != nil {
+		return err
+	}
+
+	if m.file != "" && !IsDevNull(m.file) && m.journal != "" && !IsDevNull(m.journal) {
+		if err := os.Truncate(m.journal, 0); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *EventMap) append(deviceID, index uint32) error {
+	if m.journal == "" || IsDevNull(m.journal) {
+		return nil
+	}
+
+	f, err := os.OpenFile(m.journal, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0660)
+	if err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintf(f, "%-16d %v\n", deviceID, index); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// Ref. https://www.joeshaw.org/dont-defer-close-on-writable-files/
+func (m *EventMap) store() error {
+	if m.file == "" || IsDevNull(m.file) {
+		return nil
+	}
+
+	m.guard.RLock()
+	devices := []uint32{}
+	for k := range m.retrieved {
+		devices = append(devices, k)
+	}
+
+	sort.Slice(devices, func(i, j int) bool { return devices[i] < devices[j] })
+
+	var entries bytes.Buffer
+	for _, k := range devices {
+		fmt.Fprintf(&entries, "%-16d %v\n", k, m.retrieved[k])
+	}
+	m.guard.RUnlock()
+
+	dir := filepath.Dir(m.file)
+	if err := os.MkdirAll(dir, 0770); err != nil {
+		return err
+	}
+
+	f, err := ioutil.TempFile(dir, filepath.Base(m.file)+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(f.Name())
+
+	if _, err := fmt.Fprintf(f, "%s# checksum %08x\n", entries.Bytes(), crc32.ChecksumIEEE(entries.Bytes())); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(f.Name(), m.file); err != nil {
+		return err
+	}
+
+	// ... sync the directory so that the rename is durable
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+
+	return nil
+}
+
+// Parses an event map file. Files written before the checksum was added are accepted without
+// a checksum.
+func parseEventMap(r io.Reader, retrieved map[uint32]uint32) error {
+	var checksum *uint32
+	var entries bytes.Buffer
+
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := s.Text()
+
+		if match := checksumRE.FindStringSubmatch(line); match != nil {
+			if v, err := strconv.ParseUint(match[1], 16, 32); err != nil {
+				return err
+			} else {
+				crc := uint32(v)
+				checksum = &crc
+			}
+			continue
+		}
+
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+
+		if err := parseEntry(line, retrieved); err != nil {
+			return err
+		}
+
+		entries.WriteString(line + "\n")
+	}
+
+	if err := s.Err(); err != nil {
+		return err
+	}
+
+	if checksum != nil && *checksum != crc32.ChecksumIEEE(entries.Bytes()) {
+		return fmt.Errorf("checksum mismatch")
+	}
+
+	return nil
+}
+
+// Replays the journal entries, ignoring an incomplete last entry (from an interrupted write).
+func replay(r io.Reader, retrieved map[uint32]uint32) (int, error) {
+	count := 0
+	reader := bufio.NewReader(r)
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err == io.EOF {
+			return count, nil
+		} else if err != nil {
+			return count, err
+		}
+
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		if err := parseEntry(line, retrieved); err != nil {
+			return count, err
+		}
+
+		count++
+	}
+}
+
+func parseEntry(line string, retrieved map[uint32]uint32) error {
+	match := entryRE.FindStringSubmatch(line)
+	if len(match) != 3 {
+		return fmt.Errorf("invalid entry '%s'", strings.TrimSpace(line))
+	}
+
+	key := strings.TrimSpace(match[1])
+	value := strings.TrimSpace(match[2])
+
+	device, err := strconv.ParseUint(key, 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid entry '%s' (%v)", strings.TrimSpace(line), err)
+	}
+
+	eventID, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid entry '%s' (%v)", strings.TrimSpace(line), err)
+	}
+
+	retrieved[uint32(device)] = uint32(eventID)
+
+	return nil
+}
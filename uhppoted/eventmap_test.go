@@ -0,0 +1,147 @@
+package uhppoted
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestEventMapStoreAndLoad(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "events.map")
+	m := NewEventMap(file)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		deviceID := uint32(405419890 + i)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := uint32(1); index <= 10; index++ {
+				if err := m.update(deviceID, index, 1, ROLLOVER); err != nil {
+					t.Errorf("Unexpected error updating event map: %v", err)
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if files, _ := filepath.Glob(filepath.Join(filepath.Dir(file), "*.tmp")); len(files) != 0 {
+		t.Errorf("Temporary files not removed: %v", files)
+	}
+
+	reloaded := NewEventMap(file)
+	if err := reloaded.Load(nil); err != nil {
+		t.Fatalf("Unexpected error loading event map: %v", err)
+	}
+
+	if !reflect.DeepEqual(reloaded.retrieved, m.retrieved) {
+		t.Errorf("Incorrectly reloaded event map\n   expected:%v\n   got:     %v", m.retrieved, reloaded.retrieved)
+	}
+}
+
+func TestEventMapLoadLegacyFormat(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "events.map")
+	ioutil.WriteFile(file, []byte("405419896        17\n303986753: 29\n"), 0660)
+
+	m := NewEventMap(file)
+	if err := m.Load(nil); err != nil {
+		t.Fatalf("Unexpected error loading event map: %v", err)
+	}
+
+	if expected := map[uint32]uint32{405419896: 17, 303986753: 29}; !reflect.DeepEqual(m.retrieved, expected) {
+		t.Errorf("Incorrectly loaded event map - expected:%v, got:%v", expected, m.retrieved)
+	}
+}
+
+func TestEventMapLoadCorrupt(t *testing.T) {
+	vector := []string{
+		"# version 3\n405419896        17\n303986753        27\n# checksum 00000000\n",
+		"405419896        17\n3039867!3        27\n",
+	}
+
+	for _, v := range vector {
+		file := filepath.Join(t.TempDir(), "events.map")
+		ioutil.WriteFile(file, []byte(v), 0660)
+
+		m := NewEventMap(file)
+		if err := m.Load(nil); !errors.Is(err, InvalidEventMap) {
+			t.Errorf("Expected 'invalid event map' error for corrupt file, got %v", err)
+		}
+	}
+}
+
+func TestEventMapJournal(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "events.map")
+	journal := filepath.Join(dir, "events.journal")
+
+	m := NewEventMapWithJournal(file, journal)
+	m.update(405419896, 17, 1, ROLLOVER)
+	m.update(303986753, 29, 1, ROLLOVER)
+
+	if bytes, _ := ioutil.ReadFile(journal); len(bytes) != 0 {
+		t.Errorf("Expected empty journal after event map update, got '%s'", string(bytes))
+	}
+
+	// ... simulate crash after journal write but before the event map was updated
+	ioutil.WriteFile(journal, []byte("405419896        18\n405419896        19\n30398"), 0660)
+
+	reloaded := NewEventMapWithJournal(file, journal)
+	if err := reloaded.Load(nil); err != nil {
+		t.Fatalf("Unexpected error loading event map: %v", err)
+	}
+
+	if expected := map[uint32]uint32{405419896: 19, 303986753: 29}; !reflect.DeepEqual(reloaded.retrieved, expected) {
+		t.Errorf("Incorrectly loaded event map - expected:%v, got:%v", expected, reloaded.retrieved)
+	}
+}
+
+func TestEventMapJournalWithoutFile(t *testing.T) {
+	journal := filepath.Join(t.TempDir(), "events.journal")
+
+	m := NewEventMapWithJournal("", journal)
+	m.update(405419896, 17, 1, ROLLOVER)
+	m.update(303986753, 29, 1, ROLLOVER)
+	m.update(405419896, 18, 1, ROLLOVER)
+
+	reloaded := NewEventMapWithJournal("", journal)
+	if err := reloaded.Load(nil); err != nil {
+		t.Fatalf("Unexpected error loading event map: %v", err)
+	}
+
+	if expected := map[uint32]uint32{405419896: 18, 303986753: 29}; !reflect.DeepEqual(reloaded.retrieved, expected) {
+		t.Errorf("Incorrectly loaded event map - expected:%v, got:%v", expected, reloaded.retrieved)
+	}
+}
+
+func TestEventMapUpdateIsMonotonic(t *testing.T) {
+	m := NewEventMap("")
+
+	vector := []struct {
+		index    uint32
+		first    uint32
+		last     uint32
+		expected uint32
+	}{
+		{20, 1, 100, 20},
+		{15, 1, 100, 20}, // stale update from a slower retrieval
+		{95, 90, 10, 95},
+		{5, 90, 10, 5}, // rolled over
+		{98, 90, 10, 5},
+		{50, 40, 60, 50}, // previous index no longer in event buffer
+	}
+
+	for i, v := range vector {
+		if err := m.update(405419896, v.index, v.first, v.last); err != nil {
+			t.Fatalf("Unexpected error updating event map: %v", err)
+		}
+
+		if index, _ := m.get(405419896); index != v.expected {
+			t.Errorf("Incorrect event map index %v - expected:%v, got:%v", i+1, v.expected, index)
+		}
+	}
+}
@@ -1,9 +1,9 @@
 package uhppoted
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"time"
 
 	"github.com/uhppoted/uhppote-core/types"
 )
@@ -17,9 +17,11 @@ type GetEventRangeRequest struct {
 }
 
 type GetEventRangeResponse struct {
-	DeviceID DeviceID    `json:"device-id,omitempty"`
-	Dates    *DateRange  `json:"dates,omitempty"`
-	Events   *EventRange `json:"events,omitempty"`
+	DeviceID DeviceID       `json:"device-id,omitempty"`
+	Dates    *DateRange     `json:"dates,omitempty"`
+	Events   *EventRange    `json:"events,omitempty"`
+	Strategy SearchStrategy `json:"strategy,omitempty"`
+	Retries  uint64         `json:"retries,omitempty"`
 }
 
 type GetEventRequest struct {
@@ -30,6 +32,36 @@ type GetEventRequest struct {
 type GetEventResponse struct {
 	DeviceID DeviceID `json:"device-id"`
 	Event    Event    `json:"event"`
+	Retries  uint64   `json:"retries,omitempty"`
+}
+
+// Request definition for get-events API. The events to retrieve are specified either by
+// event index (From/To) or by date (Start/End), or by the Cursor returned with the previous
+// page. Count is the maximum number of events to return in a page.
+type GetEventsRequest struct {
+	DeviceID DeviceID
+	From     *uint32
+	To       *uint32
+	Start    *types.DateTime
+	End      *types.DateTime
+	Count    int
+	Cursor   *EventCursor
+}
+
+// Response definition for get-events API. Next is nil if there are no more events to be
+// retrieved.
+type GetEventsResponse struct {
+	DeviceID DeviceID     `json:"device-id"`
+	Events   []Event      `json:"events"`
+	Next     *EventCursor `json:"next,omitempty"`
+	Retries  uint64       `json:"retries,omitempty"`
+}
+
+// Continuation cursor for get-events. Identifies the next event to retrieve and the last
+// event in the requested range.
+type EventCursor struct {
+	Next uint32 `json:"next"`
+	Last uint32 `json:"last"`
 }
 
 // Request definition for record-special-events API
@@ -43,6 +75,7 @@ type RecordSpecialEventsResponse struct {
 	DeviceID DeviceID
 	Enable   bool
 	Updated  bool
+	Retries  uint64 `json:"retries,omitempty"`
 }
 
 type Event struct {
@@ -54,11 +87,21 @@ type Event struct {
 	CardNumber uint32         `json:"card-number"`
 	Timestamp  types.DateTime `json:"timestamp"`
 	Reason     uint8          `json:"event-reason"`
+	Info       *EventInfo     `json:"info,omitempty"`
+	DeviceName string         `json:"device-name,omitempty"`
+	DoorName   string         `json:"door-name,omitempty"`
+	Cardholder string         `json:"cardholder,omitempty"`
 }
 
 func (u *UHPPOTED) GetEventRange(request GetEventRangeRequest) (*GetEventRangeResponse, error) {
+	return u.GetEventRangeWithContext(context.Background(), request)
+}
+
+func (u *UHPPOTED) GetEventRangeWithContext(ctx context.Context, request GetEventRangeRequest) (*GetEventRangeResponse, error) {
 	u.debug("get-events", fmt.Sprintf("request  %+v", request))
 
+	u, retries := u.track()
+
 	devices := u.UHPPOTE.DeviceList()
 	device := uint32(request.DeviceID)
 	start := request.Start
@@ -71,29 +114,36 @@ func (u *UHPPOTED) GetEventRange(request GetEventRangeRequest) (*GetEventRangeRe
 		}
 	}
 
+	if err := cancelled(ctx); err != nil {
+		return nil, err
+	}
+
 	f, err := u.UHPPOTE.GetEvent(device, 0)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", InternalServerError, fmt.Errorf("Error getting first event index from %v (%w)", device, err))
+		return nil, internalError("get-event-range", device, fmt.Errorf("Error getting first event index from %v (%w)", device, err))
+	}
+
+	if err := cancelled(ctx); err != nil {
+		return nil, err
 	}
 
 	l, err := u.UHPPOTE.GetEvent(device, 0xffffffff)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", InternalServerError, fmt.Errorf("Error getting last event index from %v (%w)", device, err))
+		return nil, internalError("get-event-range", device, fmt.Errorf("Error getting last event index from %v (%w)", device, err))
 	}
 
 	if f == nil && l != nil {
-		return nil, fmt.Errorf("%w: %v", InternalServerError, fmt.Errorf("Error getting first event index from %v (%w)", device, errors.New("Record not found")))
+		return nil, internalError("get-event-range", device, fmt.Errorf("Error getting first event index from %v (%w)", device, errors.New("Record not found")))
 	} else if f != nil && l == nil {
-		return nil, fmt.Errorf("%w: %v", InternalServerError, fmt.Errorf("Error getting last event index from %v (%w)", device, errors.New("Record not found")))
+		return nil, internalError("get-event-range", device, fmt.Errorf("Error getting last event index from %v (%w)", device, errors.New("Record not found")))
 	}
 
-	// The indexing logic below 'decrements' the index from l(ast) to f(irst) assuming that the on-device event store has
-	// a circular event buffer of size ROLLOVER. The logic assumes the events are ordered by datetime, which is reasonable
-	// but not necessarily true e.g. if the start/end interval includes a significant device time change.
-	var first *types.Event
-	var last *types.Event
+	// The search logic below assumes that the on-device event store is a circular event buffer of size 'rollover' and
+	// that the events are (mostly) ordered by datetime. Where that is not the case (e.g. if the start/end interval
+	// includes a significant device time change) the search falls back to a linear scan of the out of order events.
 	var dates *DateRange
 	var events *EventRange
+	var strategy SearchStrategy
 
 	if f == nil || l == nil {
 		if start != nil || end != nil {
@@ -106,28 +156,11 @@ func (u *UHPPOTED) GetEventRange(request GetEventRangeRequest) (*GetEventRangeRe
 		events = &EventRange{}
 	} else {
 		if start != nil || end != nil {
-			index := EventIndex(l.Index)
-			for {
-				record, err := u.UHPPOTE.GetEvent(device, uint32(index))
-				if err != nil {
-					return nil, fmt.Errorf("%w: %v", InternalServerError, fmt.Errorf("Error getting event for index %v from %v (%w)", index, device, err))
-				}
-
-				if in(record, start, end) {
-					if last == nil {
-						last = record
-					}
-
-					first = record
-				} else if first != nil || last != nil {
-					break
-				}
-
-				if uint32(index) == f.Index {
-					break
-				}
+			s := newSearch(ctx, u, device, rollover, f, l)
 
-				index = index.decrement(rollover)
+			from, to, err := s.locate(start, end)
+			if err != nil {
+				return nil, err
 			}
 
 			dates = &DateRange{
@@ -135,13 +168,17 @@ func (u *UHPPOTED) GetEventRange(request GetEventRangeRequest) (*GetEventRangeRe
 				End:   end,
 			}
 
-			if first != nil && last != nil {
+			if from < to {
+				first := s.index(from)
+				last := s.index(to - 1)
+
 				events = &EventRange{
-					First: &first.Index,
-					Last:  &last.Index,
+					First: &first,
+					Last:  &last,
 				}
 			}
 
+			strategy = s.strategy()
 		} else {
 			events = &EventRange{
 				First: &f.Index,
@@ -154,44 +191,46 @@ func (u *UHPPOTED) GetEventRange(request GetEventRangeRequest) (*GetEventRangeRe
 		DeviceID: DeviceID(device),
 		Dates:    dates,
 		Events:   events,
+		Strategy: strategy,
 	}
 
+	response.Retries = retries()
+
 	u.debug("get-events", fmt.Sprintf("response %+v", response))
 
 	return &response, nil
 }
 
-func in(record *types.Event, start, end *types.DateTime) bool {
-	if start != nil && time.Time(record.Timestamp).Before(time.Time(*start)) {
-		return false
-	}
-
-	if end != nil && time.Time(record.Timestamp).After(time.Time(*end)) {
-		return false
-	}
-
-	return true
+func (u *UHPPOTED) GetEvent(request GetEventRequest) (*GetEventResponse, error) {
+	return u.GetEventWithContext(context.Background(), request)
 }
 
-func (u *UHPPOTED) GetEvent(request GetEventRequest) (*GetEventResponse, error) {
+func (u *UHPPOTED) GetEventWithContext(ctx context.Context, request GetEventRequest) (*GetEventResponse, error) {
 	u.debug("get-events", fmt.Sprintf("request  %+v", request))
 
+	u, retries := u.track()
+
 	device := uint32(request.DeviceID)
 	eventID := request.EventID
 
+	if err := cancelled(ctx); err != nil {
+		return nil, err
+	}
+
 	record, err := u.UHPPOTE.GetEvent(device, eventID)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", InternalServerError, fmt.Errorf("Error getting event for ID %v from %v (%w)", eventID, device, err))
+		return nil, internalError("get-event", device, fmt.Errorf("Error getting event for ID %v from %v (%w)", eventID, device, err))
 	}
 
 	if record == nil {
-		return nil, fmt.Errorf("%w: %v", NotFound, fmt.Errorf("No event record for ID %v for %v", eventID, device))
+		return nil, notFound("get-event", device, fmt.Errorf("No event record for ID %v for %v", eventID, device))
 	}
 
 	if record.Index != eventID {
-		return nil, fmt.Errorf("%w: %v", NotFound, fmt.Errorf("No event record for ID %v for %v", eventID, device))
+		return nil, notFound("get-event", device, fmt.Errorf("No event record for ID %v for %v", eventID, device))
 	}
 
+	enrichment := u.enrich(device, record.Door, record.CardNumber)
 	response := GetEventResponse{
 		DeviceID: DeviceID(record.SerialNumber),
 		Event: Event{
@@ -203,25 +242,205 @@ func (u *UHPPOTED) GetEvent(request GetEventRequest) (*GetEventResponse, error)
 			CardNumber: record.CardNumber,
 			Timestamp:  record.Timestamp,
 			Reason:     record.Reason,
+			Info:       u.decode(record.Type, record.Reason, record.Direction),
+			DeviceName: enrichment.device,
+			DoorName:   enrichment.door,
+			Cardholder: enrichment.cardholder,
 		},
 	}
 
+	response.Retries = retries()
+
 	u.debug("get-event", fmt.Sprintf("response %+v", response))
 
 	return &response, nil
 }
 
+// Retrieves a page of events from a controller, starting from the first event in the requested
+// range (or at the cursor) and stopping at either the end of the range or after 'Count' event
+// indices. Pages may hold fewer than 'Count' events if events are missing or have been overwritten.
+func (u *UHPPOTED) GetEvents(request GetEventsRequest) (*GetEventsResponse, error) {
+	return u.GetEventsWithContext(context.Background(), request)
+}
+
+func (u *UHPPOTED) GetEventsWithContext(ctx context.Context, request GetEventsRequest) (*GetEventsResponse, error) {
+	u.debug("get-events", fmt.Sprintf("request  %+v", request))
+
+	u, retries := u.track()
+
+	devices := u.UHPPOTE.DeviceList()
+	device := uint32(request.DeviceID)
+	count := BATCHSIZE
+	rollover := ROLLOVER
+
+	if request.Count > 0 {
+		count = request.Count
+	}
+
+	if d, ok := devices[device]; ok {
+		if d.RolloverAt() != 0 {
+			rollover = d.RolloverAt()
+		}
+	}
+
+	if (request.From != nil || request.To != nil) && (request.Start != nil || request.End != nil) {
+		return nil, badRequest("get-events", device, fmt.Errorf("Invalid get-events request - specify either event indices or dates, not both"))
+	}
+
+	if err := cancelled(ctx); err != nil {
+		return nil, err
+	}
+
+	f, err := u.UHPPOTE.GetEvent(device, 0)
+	if err != nil {
+		return nil, internalError("get-events", device, fmt.Errorf("Error getting first event index from %v (%w)", device, err))
+	}
+
+	if err := cancelled(ctx); err != nil {
+		return nil, err
+	}
+
+	l, err := u.UHPPOTE.GetEvent(device, 0xffffffff)
+	if err != nil {
+		return nil, internalError("get-events", device, fmt.Errorf("Error getting last event index from %v (%w)", device, err))
+	}
+
+	response := GetEventsResponse{
+		DeviceID: DeviceID(device),
+		Events:   []Event{},
+	}
+
+	if f == nil || l == nil {
+		response.Retries = retries()
+		u.debug("get-events", fmt.Sprintf("response %+v", response))
+		return &response, nil
+	}
+
+	from := EventIndex(f.Index)
+	to := EventIndex(l.Index)
+
+	switch {
+	case request.Cursor != nil:
+		from = EventIndex(request.Cursor.Next)
+		to = EventIndex(request.Cursor.Last)
+
+	case request.Start != nil || request.End != nil:
+		s := newSearch(ctx, u, device, rollover, f, l)
+		p, q, err := s.locate(request.Start, request.End)
+		if err != nil {
+			return nil, err
+		}
+
+		if p >= q {
+			response.Retries = retries()
+			u.debug("get-events", fmt.Sprintf("response %+v", response))
+			return &response, nil
+		}
+
+		from = EventIndex(s.index(p))
+		to = EventIndex(s.index(q - 1))
+
+	default:
+		if request.From != nil {
+			from = EventIndex(*request.From)
+		}
+
+		if request.To != nil {
+			to = EventIndex(*request.To)
+		}
+	}
+
+	// ... events may have been overwritten since the cursor was issued
+	if !from.in(f.Index, l.Index) {
+		from = EventIndex(f.Index)
+	}
+
+	if !to.in(f.Index, l.Index) {
+		to = EventIndex(l.Index)
+	}
+
+	if offset(from, f.Index, rollover) > offset(to, f.Index, rollover) {
+		return nil, badRequest("get-events", device, fmt.Errorf("Invalid get-events request - 'from' index %v is after 'to' index %v", from, to))
+	}
+
+	// ... limits the number of controller requests per page (rather than just the number of events
+	//     returned) so that missing or overwritten events can't turn one page into a scan of the
+	//     entire event buffer
+	index := from
+	fetched := 0
+	for {
+		if fetched >= count {
+			response.Next = &EventCursor{
+				Next: uint32(index),
+				Last: uint32(to),
+			}
+			break
+		}
+
+		if err := cancelled(ctx); err != nil {
+			return nil, err
+		}
+
+		record, err := u.UHPPOTE.GetEvent(device, uint32(index))
+		if err != nil {
+			return nil, internalError("get-events", device, fmt.Errorf("Error getting event for index %v from %v (%w)", index, device, err))
+		}
+
+		fetched++
+
+		if record != nil && record.Index == uint32(index) {
+			enrichment := u.enrich(device, record.Door, record.CardNumber)
+			response.Events = append(response.Events, Event{
+				Index:      record.Index,
+				Type:       record.Type,
+				Granted:    record.Granted,
+				Door:       record.Door,
+				Direction:  record.Direction,
+				CardNumber: record.CardNumber,
+				Timestamp:  record.Timestamp,
+				Reason:     record.Reason,
+				Info:       u.decode(record.Type, record.Reason, record.Direction),
+				DeviceName: enrichment.device,
+				DoorName:   enrichment.door,
+				Cardholder: enrichment.cardholder,
+			})
+		}
+
+		if index == to {
+			break
+		}
+
+		index = index.increment(rollover)
+	}
+
+	response.Retries = retries()
+
+	u.debug("get-events", fmt.Sprintf("response %+v", response))
+
+	return &response, nil
+}
+
 // Unwraps the request and dispatches the corresponding controller command to enable or disable
 // door open, door close and door button press events for the controller.
 func (u *UHPPOTED) RecordSpecialEvents(request RecordSpecialEventsRequest) (*RecordSpecialEventsResponse, error) {
+	return u.RecordSpecialEventsWithContext(context.Background(), request)
+}
+
+func (u *UHPPOTED) RecordSpecialEventsWithContext(ctx context.Context, request RecordSpecialEventsRequest) (*RecordSpecialEventsResponse, error) {
 	u.debug("record-special-events", fmt.Sprintf("request  %+v", request))
 
+	u, retries := u.track()
+
 	device := uint32(request.DeviceID)
 	enable := request.Enable
 
+	if err := cancelled(ctx); err != nil {
+		return nil, err
+	}
+
 	updated, err := u.UHPPOTE.RecordSpecialEvents(device, enable)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", InternalServerError, fmt.Errorf("Error updating 'record special events' flag for %v (%w)", device, err))
+		return nil, internalError("record-special-events", device, fmt.Errorf("Error updating 'record special events' flag for %v (%w)", device, err))
 	}
 
 	response := RecordSpecialEventsResponse{
@@ -230,6 +449,8 @@ func (u *UHPPOTED) RecordSpecialEvents(request RecordSpecialEventsRequest) (*Rec
 		Updated:  updated,
 	}
 
+	response.Retries = retries()
+
 	u.debug("record-special-events", fmt.Sprintf("response %+v", response))
 
 	return &response, nil
@@ -1,9 +1,15 @@
 package uhppoted
 
 import (
+	"errors"
 	"fmt"
+	"net"
 	"reflect"
 	"testing"
+	"time"
+
+	"github.com/uhppoted/uhppote-core/types"
+	"github.com/uhppoted/uhppoted-api/simulator"
 )
 
 func TestIncrementEventIndex(t *testing.T) {
@@ -137,3 +143,314 @@ func TestRecordSpecialEventsWithFail(t *testing.T) {
 		t.Errorf("Incorrected response:\n   expected: %+v\n   got:      %+v\n", expected, *response)
 	}
 }
+
+type counter struct {
+	*simulator.Simulator
+	requests int
+}
+
+func (c *counter) GetEvent(deviceID, index uint32) (*types.Event, error) {
+	c.requests++
+	return c.Simulator.GetEvent(deviceID, index)
+}
+
+func TestGetEventRangeWithBinarySearch(t *testing.T) {
+	base := time.Date(2021, time.March, 1, 8, 0, 0, 0, time.Local)
+	device := simulator.NewDevice(405419896, net.IPv4(192, 168, 1, 125))
+	device.Events.Rollover = 1000
+
+	s := simulator.NewSimulator(nil, device)
+	for i := 0; i < 1500; i++ {
+		s.AddEvent(405419896, types.Event{Type: 1, Timestamp: types.DateTime(base.Add(time.Duration(i) * time.Minute))})
+	}
+
+	u := UHPPOTED{
+		UHPPOTE: &counter{Simulator: s},
+	}
+
+	start := types.DateTime(base.Add(700 * time.Minute))
+	end := types.DateTime(base.Add(1200 * time.Minute))
+
+	response, err := u.GetEventRange(GetEventRangeRequest{DeviceID: 405419896, Start: &start, End: &end})
+	if err != nil {
+		t.Fatalf("Unexpected error getting event range: %v", err)
+	}
+
+	if response.Events == nil || response.Events.First == nil || response.Events.Last == nil {
+		t.Fatalf("Invalid event range - expected:%v-%v, got:%v", 701, 201, response.Events)
+	}
+
+	if *response.Events.First != 701 || *response.Events.Last != 201 {
+		t.Errorf("Incorrect event range - expected:%v-%v, got:%v-%v", 701, 201, *response.Events.First, *response.Events.Last)
+	}
+
+	if response.Strategy != BinarySearch {
+		t.Errorf("Incorrect search strategy - expected:%v, got:%v", BinarySearch, response.Strategy)
+	}
+
+	if N := u.UHPPOTE.(*counter).requests; N > 50 {
+		t.Errorf("Excessive number of get-event requests - expected:<%v, got:%v", 50, N)
+	}
+}
+
+func TestGetEventRangeWithOutOfOrderEvents(t *testing.T) {
+	base := time.Date(2021, time.March, 1, 8, 0, 0, 0, time.Local)
+	device := simulator.NewDevice(405419896, net.IPv4(192, 168, 1, 125))
+
+	s := simulator.NewSimulator(nil, device)
+	for i := 0; i < 100; i++ {
+		timestamp := base.Add(time.Duration(i) * time.Minute)
+		if i >= 40 && i < 60 {
+			timestamp = timestamp.Add(-24 * time.Hour)
+		}
+
+		s.AddEvent(405419896, types.Event{Type: 1, Timestamp: types.DateTime(timestamp)})
+	}
+
+	u := UHPPOTED{
+		UHPPOTE: s,
+	}
+
+	start := types.DateTime(base.Add(30 * time.Minute))
+	end := types.DateTime(base.Add(70 * time.Minute))
+
+	response, err := u.GetEventRange(GetEventRangeRequest{DeviceID: 405419896, Start: &start, End: &end})
+	if err != nil {
+		t.Fatalf("Unexpected error getting event range: %v", err)
+	}
+
+	if response.Events == nil || response.Events.First == nil || response.Events.Last == nil {
+		t.Fatalf("Invalid event range - expected:%v-%v, got:%v", 31, 71, response.Events)
+	}
+
+	if *response.Events.First != 31 || *response.Events.Last != 71 {
+		t.Errorf("Incorrect event range - expected:%v-%v, got:%v-%v", 31, 71, *response.Events.First, *response.Events.Last)
+	}
+
+	if response.Strategy != LinearScan {
+		t.Errorf("Incorrect search strategy - expected:%v, got:%v", LinearScan, response.Strategy)
+	}
+}
+
+func TestGetEventRangeWithLocalOutOfOrderEvents(t *testing.T) {
+	base := time.Date(2021, time.March, 1, 8, 0, 0, 0, time.Local)
+	device := simulator.NewDevice(405419896, net.IPv4(192, 168, 1, 125))
+
+	s := simulator.NewSimulator(nil, device)
+	for i := 0; i < 5000; i++ {
+		timestamp := base.Add(time.Duration(i) * time.Minute)
+		if i >= 2490 && i < 2510 {
+			timestamp = timestamp.Add(-30 * 24 * time.Hour)
+		}
+
+		s.AddEvent(405419896, types.Event{Type: 1, Timestamp: types.DateTime(timestamp)})
+	}
+
+	u := UHPPOTED{
+		UHPPOTE: &counter{Simulator: s},
+	}
+
+	start := types.DateTime(base.Add(1000 * time.Minute))
+	end := types.DateTime(base.Add(4000 * time.Minute))
+
+	response, err := u.GetEventRange(GetEventRangeRequest{DeviceID: 405419896, Start: &start, End: &end})
+	if err != nil {
+		t.Fatalf("Unexpected error getting event range: %v", err)
+	}
+
+	if response.Events == nil || response.Events.First == nil || response.Events.Last == nil {
+		t.Fatalf("Invalid event range - expected:%v-%v, got:%v", 1001, 4001, response.Events)
+	}
+
+	if *response.Events.First != 1001 || *response.Events.Last != 4001 {
+		t.Errorf("Incorrect event range - expected:%v-%v, got:%v-%v", 1001, 4001, *response.Events.First, *response.Events.Last)
+	}
+
+	if response.Strategy != LinearScan {
+		t.Errorf("Incorrect search strategy - expected:%v, got:%v", LinearScan, response.Strategy)
+	}
+
+	// ... only the events around the out of order events should be scanned
+	if N := u.UHPPOTE.(*counter).requests; N > 100 {
+		t.Errorf("Excessive number of get-event requests - expected:<%v, got:%v", 100, N)
+	}
+}
+
+func TestGetEventRangeWithTooManyOutOfOrderEvents(t *testing.T) {
+	base := time.Date(2021, time.March, 1, 8, 0, 0, 0, time.Local)
+	device := simulator.NewDevice(405419896, net.IPv4(192, 168, 1, 125))
+
+	s := simulator.NewSimulator(nil, device)
+	for i := 0; i < 1000; i++ {
+		timestamp := base.Add(time.Duration(i) * time.Minute)
+		if i >= 300 && i < 700 {
+			timestamp = timestamp.Add(-24 * time.Hour)
+		}
+
+		s.AddEvent(405419896, types.Event{Type: 1, Timestamp: types.DateTime(timestamp)})
+	}
+
+	u := UHPPOTED{
+		UHPPOTE: &counter{Simulator: s},
+	}
+
+	start := types.DateTime(base.Add(100 * time.Minute))
+
+	_, err := u.GetEventRange(GetEventRangeRequest{DeviceID: 405419896, Start: &start})
+	if !errors.Is(err, InternalServerError) {
+		t.Errorf("Expected InternalServerError, got:%v", err)
+	}
+
+	if N := u.UHPPOTE.(*counter).requests; N > 2*SCAN_WINDOW+50 {
+		t.Errorf("Excessive number of get-event requests - expected:<%v, got:%v", 2*SCAN_WINDOW+50, N)
+	}
+}
+
+func TestGetEventsWithCursor(t *testing.T) {
+	device := simulator.NewDevice(405419896, net.IPv4(192, 168, 1, 125))
+	device.Events.Rollover = 10
+
+	s := simulator.NewSimulator(nil, device)
+	for i := 0; i < 15; i++ {
+		s.AddEvent(405419896, types.Event{Type: 1, Granted: true, Door: 1, CardNumber: uint32(65537 + i), Reason: 1})
+	}
+
+	u := UHPPOTED{
+		UHPPOTE: s,
+	}
+
+	from := uint32(8)
+	to := uint32(3)
+	request := GetEventsRequest{
+		DeviceID: 405419896,
+		From:     &from,
+		To:       &to,
+		Count:    3,
+	}
+
+	expected := []uint32{8, 9, 10, 1, 2, 3}
+	events := []uint32{}
+	pages := 0
+
+	for {
+		response, err := u.GetEvents(request)
+		if err != nil {
+			t.Fatalf("Unexpected error retrieving events: %v", err)
+		}
+
+		pages++
+		for _, e := range response.Events {
+			events = append(events, e.Index)
+		}
+
+		if response.Next == nil {
+			break
+		}
+
+		request = GetEventsRequest{
+			DeviceID: 405419896,
+			Count:    3,
+			Cursor:   response.Next,
+		}
+	}
+
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("Incorrect events - expected:%v, got:%v", expected, events)
+	}
+
+	if pages != 2 {
+		t.Errorf("Incorrect number of pages - expected:%v, got:%v", 2, pages)
+	}
+}
+
+func TestGetEventsByDate(t *testing.T) {
+	base := time.Date(2021, time.March, 1, 8, 0, 0, 0, time.Local)
+	device := simulator.NewDevice(405419896, net.IPv4(192, 168, 1, 125))
+
+	s := simulator.NewSimulator(nil, device)
+	for i := 0; i < 20; i++ {
+		s.AddEvent(405419896, types.Event{Type: 1, CardNumber: uint32(65537 + i), Timestamp: types.DateTime(base.Add(time.Duration(i) * time.Hour))})
+	}
+
+	u := UHPPOTED{
+		UHPPOTE: s,
+	}
+
+	start := types.DateTime(base.Add(5 * time.Hour))
+	end := types.DateTime(base.Add(7 * time.Hour))
+
+	response, err := u.GetEvents(GetEventsRequest{DeviceID: 405419896, Start: &start, End: &end})
+	if err != nil {
+		t.Fatalf("Unexpected error retrieving events: %v", err)
+	}
+
+	if len(response.Events) != 3 || response.Events[0].Index != 6 || response.Events[2].Index != 8 || response.Events[0].CardNumber != 65542 {
+		t.Errorf("Incorrect events - expected:%v, got:%+v", []uint32{6, 7, 8}, response.Events)
+	}
+
+	if response.Next != nil {
+		t.Errorf("Expected no continuation cursor, got %v", response.Next)
+	}
+}
+
+// Simulates a controller with overwritten events, i.e. every event index returns nil
+type overwritten struct {
+	*simulator.Simulator
+	requests int
+}
+
+func (o *overwritten) GetEvent(deviceID, index uint32) (*types.Event, error) {
+	if index == 0 || index == 0xffffffff {
+		return o.Simulator.GetEvent(deviceID, index)
+	}
+
+	o.requests++
+
+	return nil, nil
+}
+
+func TestGetEventsLimitsRequestsPerPage(t *testing.T) {
+	device := simulator.NewDevice(405419896, net.IPv4(192, 168, 1, 125))
+	s := simulator.NewSimulator(nil, device)
+	for i := 0; i < 100; i++ {
+		s.AddEvent(405419896, types.Event{Type: 1, Granted: true, Door: 1, CardNumber: uint32(65537 + i), Reason: 1})
+	}
+
+	o := overwritten{Simulator: s}
+	u := UHPPOTED{
+		UHPPOTE: &o,
+	}
+
+	response, err := u.GetEvents(GetEventsRequest{DeviceID: 405419896, Count: 5})
+	if err != nil {
+		t.Fatalf("Unexpected error retrieving events: %v", err)
+	}
+
+	if o.requests != 5 {
+		t.Errorf("Incorrect number of 'get-event' requests - expected:%v, got:%v", 5, o.requests)
+	}
+
+	if len(response.Events) != 0 || response.Next == nil || response.Next.Next != 6 {
+		t.Errorf("Incorrect response - got:%+v", response)
+	}
+}
+
+func TestGetEventsWithInvalidRange(t *testing.T) {
+	device := simulator.NewDevice(405419896, net.IPv4(192, 168, 1, 125))
+	s := simulator.NewSimulator(nil, device)
+	for i := 0; i < 10; i++ {
+		s.AddEvent(405419896, types.Event{Type: 1, Granted: true, Door: 1, CardNumber: uint32(65537 + i), Reason: 1})
+	}
+
+	u := UHPPOTED{
+		UHPPOTE: s,
+	}
+
+	from := uint32(8)
+	to := uint32(3)
+
+	_, err := u.GetEvents(GetEventsRequest{DeviceID: 405419896, From: &from, To: &to})
+	if !errors.Is(err, BadRequest) {
+		t.Errorf("Expected BadRequest error, got:%v", err)
+	}
+}
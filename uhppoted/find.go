@@ -1,6 +1,7 @@
 package uhppoted
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"strconv"
@@ -9,6 +10,7 @@ import (
 	"time"
 
 	"github.com/uhppoted/uhppote-core/types"
+	"github.com/uhppoted/uhppoted-api/logging"
 )
 
 // TODO rename Address to IpAddress and use Address for IP:Port
@@ -23,11 +25,22 @@ type GetDevicesRequest struct {
 
 type GetDevicesResponse struct {
 	Devices map[uint32]DeviceSummary `json:"devices"`
+	Retries uint64                   `json:"retries,omitempty"`
 }
 
 func (u *UHPPOTED) GetDevices(request GetDevicesRequest) (*GetDevicesResponse, error) {
+	return u.GetDevicesWithContext(context.Background(), request)
+}
+
+func (u *UHPPOTED) GetDevicesWithContext(ctx context.Context, request GetDevicesRequest) (*GetDevicesResponse, error) {
 	u.debug("get-devices", fmt.Sprintf("request  %+v", request))
 
+	u, retries := u.track()
+
+	if err := cancelled(ctx); err != nil {
+		return nil, err
+	}
+
 	wg := sync.WaitGroup{}
 	list := sync.Map{}
 	devices := u.UHPPOTE.DeviceList()
@@ -38,7 +51,7 @@ func (u *UHPPOTED) GetDevices(request GetDevicesRequest) (*GetDevicesResponse, e
 		go func() {
 			defer wg.Done()
 			if device, err := u.UHPPOTE.GetDevice(deviceID); err != nil {
-				u.warn("find", fmt.Errorf("get-devices: %v %v", deviceID, err))
+				u.warn("find", fmt.Errorf("get-devices: %v %v", deviceID, err), logging.DeviceID(deviceID))
 			} else if device != nil {
 				list.Store(uint32(device.SerialNumber), DeviceSummary{
 					DeviceType: identify(device.SerialNumber),
@@ -67,6 +80,10 @@ func (u *UHPPOTED) GetDevices(request GetDevicesRequest) (*GetDevicesResponse, e
 
 	wg.Wait()
 
+	if err := cancelled(ctx); err != nil {
+		return nil, err
+	}
+
 	response := GetDevicesResponse{
 		Devices: map[uint32]DeviceSummary{},
 	}
@@ -76,6 +93,8 @@ func (u *UHPPOTED) GetDevices(request GetDevicesRequest) (*GetDevicesResponse, e
 		return true
 	})
 
+	response.Retries = retries()
+
 	u.debug("get-devices", fmt.Sprintf("response %+v", response))
 
 	return &response, nil
@@ -96,18 +115,29 @@ type GetDeviceResponse struct {
 	Date       types.Date       `json:"date"`
 	Address    net.UDPAddr      `json:"address"`
 	TimeZone   *time.Location   `json:"timezone,omitempty"`
+	Retries    uint64           `json:"retries,omitempty"`
 }
 
 func (u *UHPPOTED) GetDevice(request GetDeviceRequest) (*GetDeviceResponse, error) {
+	return u.GetDeviceWithContext(context.Background(), request)
+}
+
+func (u *UHPPOTED) GetDeviceWithContext(ctx context.Context, request GetDeviceRequest) (*GetDeviceResponse, error) {
 	u.debug("get-device", fmt.Sprintf("request  %+v", request))
 
+	u, retries := u.track()
+
+	if err := cancelled(ctx); err != nil {
+		return nil, err
+	}
+
 	device, err := u.UHPPOTE.GetDevice(uint32(request.DeviceID))
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", InternalServerError, fmt.Errorf("Error getting device info for %v (%w)", device, err))
+		return nil, internalError("get-device", uint32(request.DeviceID), fmt.Errorf("Error getting device info for %v (%w)", request.DeviceID, err))
 	}
 
 	if device == nil {
-		return nil, fmt.Errorf("%w: %v", NotFound, fmt.Errorf("No device found for device ID %v", device))
+		return nil, notFound("get-device", uint32(request.DeviceID), fmt.Errorf("No device found for device ID %v", request.DeviceID))
 	}
 
 	response := GetDeviceResponse{
@@ -123,6 +153,8 @@ func (u *UHPPOTED) GetDevice(request GetDeviceRequest) (*GetDeviceResponse, erro
 		TimeZone:   device.TimeZone,
 	}
 
+	response.Retries = retries()
+
 	u.debug("get-device", fmt.Sprintf("response %+v", response))
 
 	return &response, nil
@@ -0,0 +1,66 @@
+package uhppoted
+
+import (
+	"context"
+	"os"
+)
+
+// IUHPPOTED is the UHPPOTED API. It is implemented by UHPPOTED and by the middleware chain
+// returned by Chain.
+type IUHPPOTED interface {
+	GetCardRecords(request GetCardRecordsRequest) (*GetCardRecordsResponse, error)
+	GetCardRecordsWithContext(ctx context.Context, request GetCardRecordsRequest) (*GetCardRecordsResponse, error)
+	GetCards(request GetCardsRequest) (*GetCardsResponse, error)
+	GetCardsWithContext(ctx context.Context, request GetCardsRequest) (*GetCardsResponse, error)
+	DeleteCards(request DeleteCardsRequest) (*DeleteCardsResponse, error)
+	DeleteCardsWithContext(ctx context.Context, request DeleteCardsRequest) (*DeleteCardsResponse, error)
+	GetCard(request GetCardRequest) (*GetCardResponse, error)
+	GetCardWithContext(ctx context.Context, request GetCardRequest) (*GetCardResponse, error)
+	PutCard(request PutCardRequest) (*PutCardResponse, error)
+	PutCardWithContext(ctx context.Context, request PutCardRequest) (*PutCardResponse, error)
+	DeleteCard(request DeleteCardRequest) (*DeleteCardResponse, error)
+	DeleteCardWithContext(ctx context.Context, request DeleteCardRequest) (*DeleteCardResponse, error)
+	GetDoorDelay(request GetDoorDelayRequest) (*GetDoorDelayResponse, error)
+	GetDoorDelayWithContext(ctx context.Context, request GetDoorDelayRequest) (*GetDoorDelayResponse, error)
+	SetDoorDelay(request SetDoorDelayRequest) (*SetDoorDelayResponse, error)
+	SetDoorDelayWithContext(ctx context.Context, request SetDoorDelayRequest) (*SetDoorDelayResponse, error)
+	GetDoorControl(request GetDoorControlRequest) (*GetDoorControlResponse, error)
+	GetDoorControlWithContext(ctx context.Context, request GetDoorControlRequest) (*GetDoorControlResponse, error)
+	SetDoorControl(request SetDoorControlRequest) (*SetDoorControlResponse, error)
+	SetDoorControlWithContext(ctx context.Context, request SetDoorControlRequest) (*SetDoorControlResponse, error)
+	OpenDoor(request OpenDoorRequest) (*OpenDoorResponse, error)
+	OpenDoorWithContext(ctx context.Context, request OpenDoorRequest) (*OpenDoorResponse, error)
+	GetEventRange(request GetEventRangeRequest) (*GetEventRangeResponse, error)
+	GetEventRangeWithContext(ctx context.Context, request GetEventRangeRequest) (*GetEventRangeResponse, error)
+	GetEvent(request GetEventRequest) (*GetEventResponse, error)
+	GetEventWithContext(ctx context.Context, request GetEventRequest) (*GetEventResponse, error)
+	GetEvents(request GetEventsRequest) (*GetEventsResponse, error)
+	GetEventsWithContext(ctx context.Context, request GetEventsRequest) (*GetEventsResponse, error)
+	RecordSpecialEvents(request RecordSpecialEventsRequest) (*RecordSpecialEventsResponse, error)
+	RecordSpecialEventsWithContext(ctx context.Context, request RecordSpecialEventsRequest) (*RecordSpecialEventsResponse, error)
+	GetDevices(request GetDevicesRequest) (*GetDevicesResponse, error)
+	GetDevicesWithContext(ctx context.Context, request GetDevicesRequest) (*GetDevicesResponse, error)
+	GetDevice(request GetDeviceRequest) (*GetDeviceResponse, error)
+	GetDeviceWithContext(ctx context.Context, request GetDeviceRequest) (*GetDeviceResponse, error)
+	GetStatus(request GetStatusRequest) (*GetStatusResponse, error)
+	GetStatusWithContext(ctx context.Context, request GetStatusRequest) (*GetStatusResponse, error)
+	GetTime(request GetTimeRequest) (*GetTimeResponse, error)
+	GetTimeWithContext(ctx context.Context, request GetTimeRequest) (*GetTimeResponse, error)
+	SetTime(request SetTimeRequest) (*SetTimeResponse, error)
+	SetTimeWithContext(ctx context.Context, request SetTimeRequest) (*SetTimeResponse, error)
+	GetTimeProfiles(request GetTimeProfilesRequest) (*GetTimeProfilesResponse, error)
+	GetTimeProfilesWithContext(ctx context.Context, request GetTimeProfilesRequest) (*GetTimeProfilesResponse, error)
+	PutTimeProfiles(request PutTimeProfilesRequest) (*PutTimeProfilesResponse, error)
+	PutTimeProfilesWithContext(ctx context.Context, request PutTimeProfilesRequest) (*PutTimeProfilesResponse, error)
+	GetTimeProfile(request GetTimeProfileRequest) (*GetTimeProfileResponse, error)
+	GetTimeProfileWithContext(ctx context.Context, request GetTimeProfileRequest) (*GetTimeProfileResponse, error)
+	PutTimeProfile(request PutTimeProfileRequest) (*PutTimeProfileResponse, error)
+	PutTimeProfileWithContext(ctx context.Context, request PutTimeProfileRequest) (*PutTimeProfileResponse, error)
+	ClearTimeProfiles(request ClearTimeProfilesRequest) (*ClearTimeProfilesResponse, error)
+	ClearTimeProfilesWithContext(ctx context.Context, request ClearTimeProfilesRequest) (*ClearTimeProfilesResponse, error)
+	SynchronizeTime(request SynchronizeTimeRequest) (*SynchronizeTimeResponse, error)
+	SynchronizeTimeWithContext(ctx context.Context, request SynchronizeTimeRequest) (*SynchronizeTimeResponse, error)
+	Listen(handler EventHandler, received *EventMap, q chan os.Signal)
+}
+
+var _ IUHPPOTED = &UHPPOTED{}
@@ -1,24 +1,14 @@
 package uhppoted
 
 import (
-	"bufio"
 	"fmt"
 	"github.com/uhppoted/uhppote-core/types"
-	"io/ioutil"
-	"log"
+	"github.com/uhppoted/uhppoted-api/logging"
 	"os"
-	"regexp"
-	"strconv"
-	"strings"
 	"sync"
 	"time"
 )
 
-type EventMap struct {
-	file      string
-	retrieved map[uint32]uint32
-}
-
 type ListenEvent struct {
 	DeviceID   DeviceID       `json:"device-id"`
 	EventID    uint32         `json:"event-id"`
@@ -29,6 +19,10 @@ type ListenEvent struct {
 	CardNumber uint32         `json:"card-number"`
 	Timestamp  types.DateTime `json:"timestamp"`
 	Reason     uint8          `json:"event-reason"`
+	Info       *EventInfo     `json:"info,omitempty"`
+	DeviceName string         `json:"device-name,omitempty"`
+	DoorName   string         `json:"door-name,omitempty"`
+	Cardholder string         `json:"cardholder,omitempty"`
 }
 
 type EventMessage struct {
@@ -81,17 +75,17 @@ func (u *UHPPOTED) Listen(handler EventHandler, received *EventMap, q chan os.Si
 }
 
 func (u *UHPPOTED) retrieve(deviceID uint32, received *EventMap, handler EventHandler) {
-	if index, ok := received.retrieved[deviceID]; ok {
-		u.info("listen", fmt.Sprintf("Fetching unretrieved events for device ID %v", deviceID))
+	if index, ok := received.get(deviceID); ok {
+		u.info("listen", fmt.Sprintf("Fetching unretrieved events for device ID %v", deviceID), logging.DeviceID(deviceID))
 
 		event, err := u.UHPPOTE.GetEvent(deviceID, 0xffffffff)
 		if err != nil {
-			u.warn("listen", fmt.Errorf("Unable to retrieve events for device ID %v (%w)", deviceID, err))
+			u.warn("listen", fmt.Errorf("Unable to retrieve events for device ID %v (%w)", deviceID, err), logging.DeviceID(deviceID))
 			return
 		}
 
 		if event.Index == uint32(index) {
-			u.info("listen", fmt.Sprintf("No unretrieved events for device ID %v", deviceID))
+			u.info("listen", fmt.Sprintf("No unretrieved events for device ID %v", deviceID), logging.DeviceID(deviceID))
 			return
 		}
 
@@ -106,10 +100,9 @@ func (u *UHPPOTED) retrieve(deviceID uint32, received *EventMap, handler EventHa
 		from := EventIndex(index)
 		to := EventIndex(event.Index)
 
-		if retrieved := u.fetch(deviceID, from.increment(rollover), to, handler); retrieved != 0 {
-			received.retrieved[deviceID] = retrieved
-			if err := received.store(); err != nil {
-				u.warn("listen", err)
+		if retrieved, buffer := u.fetch(deviceID, from.increment(rollover), to, handler); retrieved != 0 {
+			if err := received.update(deviceID, retrieved, buffer[0], buffer[1]); err != nil {
+				u.warn("listen", err, logging.DeviceID(deviceID))
 			}
 		}
 	}
@@ -173,20 +166,22 @@ func (u *UHPPOTED) onEvent(e *types.Status, received *EventMap, handler EventHan
 	last := EventIndex(e.Event.Index)
 	first := EventIndex(e.Event.Index)
 
-	retrieved, ok := received.retrieved[deviceID]
+	retrieved, ok := received.get(deviceID)
 	if ok && retrieved != uint32(last) {
 		first = EventIndex(retrieved)
 	}
 
-	if eventID := u.fetch(deviceID, first, last, handler); eventID != 0 {
-		received.retrieved[deviceID] = eventID
-		if err := received.store(); err != nil {
-			u.warn("listen", err)
+	if eventID, buffer := u.fetch(deviceID, first, last, handler); eventID != 0 {
+		if err := received.update(deviceID, eventID, buffer[0], buffer[1]); err != nil {
+			u.warn("listen", err, logging.DeviceID(deviceID))
 		}
 	}
 }
 
-func (u *UHPPOTED) fetch(deviceID uint32, from, to EventIndex, handler EventHandler) (retrieved uint32) {
+// Retrieves and dispatches the events in [from,to], returning the index of the last event
+// acknowledged by the handler (0 if none) and the [first,last] event indices of the device
+// event buffer.
+func (u *UHPPOTED) fetch(deviceID uint32, from, to EventIndex, handler EventHandler) (retrieved uint32, buffer [2]uint32) {
 	devices := u.UHPPOTE.DeviceList()
 	batchSize := BATCHSIZE
 	rollover := ROLLOVER
@@ -203,38 +198,30 @@ func (u *UHPPOTED) fetch(deviceID uint32, from, to EventIndex, handler EventHand
 
 	first, err := u.UHPPOTE.GetEvent(deviceID, 0)
 	if err != nil {
-		u.warn("listen", fmt.Errorf("Failed to retrieve 'first' event for device %d (%w)", deviceID, err))
+		u.warn("listen", fmt.Errorf("Failed to retrieve 'first' event for device %d (%w)", deviceID, err), logging.DeviceID(deviceID))
 		return
 	} else if first == nil {
-		u.warn("listen", fmt.Errorf("No 'first' event record returned for device %d", deviceID))
+		u.warn("listen", fmt.Errorf("No 'first' event record returned for device %d", deviceID), logging.DeviceID(deviceID))
 		return
 	}
 
 	last, err := u.UHPPOTE.GetEvent(deviceID, 0xffffffff)
 	if err != nil {
-		u.warn("listen", fmt.Errorf("Failed to retrieve 'last' event for device %d (%w)", deviceID, err))
+		u.warn("listen", fmt.Errorf("Failed to retrieve 'last' event for device %d (%w)", deviceID, err), logging.DeviceID(deviceID))
 		return
 	} else if first == nil {
-		u.warn("listen", fmt.Errorf("No 'last' event record returned for device %d", deviceID))
+		u.warn("listen", fmt.Errorf("No 'last' event record returned for device %d", deviceID), logging.DeviceID(deviceID))
 		return
 	}
 
-	if last.Index >= first.Index {
-		if uint32(from) < first.Index || uint32(from) > last.Index {
-			from = EventIndex(first.Index)
-		}
+	buffer = [2]uint32{first.Index, last.Index}
 
-		if uint32(to) < first.Index || uint32(to) > last.Index {
-			to = EventIndex(last.Index)
-		}
-	} else {
-		if uint32(from) < first.Index && uint32(from) > last.Index {
-			from = EventIndex(first.Index)
-		}
+	if !from.in(first.Index, last.Index) {
+		from = EventIndex(first.Index)
+	}
 
-		if uint32(to) < first.Index && uint32(to) > last.Index {
-			to = EventIndex(last.Index)
-		}
+	if !to.in(first.Index, last.Index) {
+		to = EventIndex(last.Index)
 	}
 
 	count := 0
@@ -245,14 +232,18 @@ func (u *UHPPOTED) fetch(deviceID uint32, from, to EventIndex, handler EventHand
 			return
 		}
 
+		// NTS: stop rather than skip an event that could not be retrieved so that the event map
+		//      is not advanced past an undelivered event
 		record, err := u.UHPPOTE.GetEvent(deviceID, uint32(index))
 		if err != nil {
-			u.warn("listen", fmt.Errorf("Failed to retrieve event for device %d, ID %d (%w)", deviceID, index, err))
+			u.warn("listen", fmt.Errorf("Failed to retrieve event for device %d, ID %d (%w)", deviceID, index, err), logging.DeviceID(deviceID))
+			break
 		} else if record == nil {
-			u.warn("listen", fmt.Errorf("No event record for device %d, ID %d", deviceID, index))
+			u.warn("listen", fmt.Errorf("No event record for device %d, ID %d", deviceID, index), logging.DeviceID(deviceID))
 		} else if record.Index != uint32(index) {
-			u.warn("listen", fmt.Errorf("No event record for device %d, ID %d", deviceID, index))
+			u.warn("listen", fmt.Errorf("No event record for device %d, ID %d", deviceID, index), logging.DeviceID(deviceID))
 		} else {
+			enrichment := u.enrich(uint32(record.SerialNumber), record.Door, record.CardNumber)
 			message := EventMessage{
 				Event: ListenEvent{
 					DeviceID:   DeviceID(record.SerialNumber),
@@ -264,10 +255,14 @@ func (u *UHPPOTED) fetch(deviceID uint32, from, to EventIndex, handler EventHand
 					CardNumber: record.CardNumber,
 					Timestamp:  record.Timestamp,
 					Reason:     record.Reason,
+					Info:       u.decode(record.Type, record.Reason, record.Direction),
+					DeviceName: enrichment.device,
+					DoorName:   enrichment.door,
+					Cardholder: enrichment.cardholder,
 				},
 			}
 
-			u.debug("listen", fmt.Sprintf("event %v", message))
+			u.debug("listen", fmt.Sprintf("event %v", message), logging.DeviceID(deviceID), logging.Door(record.Door), logging.Card(record.CardNumber))
 			if !handler(message) {
 				break
 			}
@@ -284,71 +279,3 @@ func (u *UHPPOTED) fetch(deviceID uint32, from, to EventIndex, handler EventHand
 
 	return
 }
-
-func NewEventMap(file string) *EventMap {
-	return &EventMap{
-		file:      file,
-		retrieved: map[uint32]uint32{},
-	}
-}
-
-func (m *EventMap) Load(log *log.Logger) error {
-	if m.file == "" {
-		return nil
-	}
-
-	f, err := os.Open(m.file)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil
-		}
-
-		return err
-	}
-
-	defer f.Close()
-
-	re := regexp.MustCompile(`^\s*(.*?)(?::\s*|\s*=\s*|\s+)(\S.*)\s*`)
-	s := bufio.NewScanner(f)
-	for s.Scan() {
-		match := re.FindStringSubmatch(s.Text())
-		if len(match) == 3 {
-			key := strings.TrimSpace(match[1])
-			value := strings.TrimSpace(match[2])
-
-			if device, err := strconv.ParseUint(key, 10, 32); err != nil {
-				log.Printf("WARN: Error parsing event map entry '%s': %v", s.Text(), err)
-			} else if eventID, err := strconv.ParseUint(value, 10, 32); err != nil {
-				log.Printf("WARN: Error parsing event map entry '%s': %v", s.Text(), err)
-			} else {
-				m.retrieved[uint32(device)] = uint32(eventID)
-			}
-		}
-	}
-
-	return s.Err()
-}
-
-func (m *EventMap) store() error {
-	if m.file == "" || IsDevNull(m.file) {
-		return nil
-	}
-
-	f, err := ioutil.TempFile(os.TempDir(), "uhppoted*.tmp")
-	if err != nil {
-		return err
-	}
-
-	defer os.Remove(f.Name())
-
-	for key, value := range m.retrieved {
-		if _, err := fmt.Fprintf(f, "%-16d %v\n", key, value); err != nil {
-			f.Close()
-			return err
-		}
-	}
-
-	f.Close()
-
-	return os.Rename(f.Name(), m.file)
-}
@@ -0,0 +1,86 @@
+package uhppoted
+
+import (
+	"net"
+	"os"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/uhppoted/uhppote-core/types"
+	"github.com/uhppoted/uhppoted-api/simulator"
+)
+
+func TestListenWithSimulator(t *testing.T) {
+	listen := net.UDPAddr{IP: net.IPv4(192, 168, 1, 100), Port: 60001}
+	device := simulator.NewDevice(405419896, net.IPv4(192, 168, 1, 125))
+	device.Listener = listen
+	device.Events.Rollover = 10
+
+	s := simulator.NewSimulator(&listen, device)
+	for i := 0; i < 12; i++ {
+		s.AddEvent(405419896, types.Event{Type: 1, Granted: true, Door: 1, CardNumber: 8165538, Reason: 1})
+	}
+
+	guard := sync.Mutex{}
+	received := []uint32{}
+	handler := func(e EventMessage) bool {
+		guard.Lock()
+		defer guard.Unlock()
+
+		for _, v := range received {
+			if v == e.Event.EventID {
+				return true
+			}
+		}
+
+		received = append(received, e.Event.EventID)
+		return true
+	}
+
+	u := UHPPOTED{
+		UHPPOTE: s,
+	}
+
+	retrieved := NewEventMap("")
+	retrieved.retrieved[405419896] = 9
+
+	q := make(chan os.Signal)
+	go func() {
+		u.Listen(handler, retrieved, q)
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+
+	if _, err := s.Swipe(405419896, 8165538, 1, 1); err != nil {
+		t.Fatalf("Unexpected error simulating card swipe: %v", err)
+	}
+
+	timeout := time.After(2500 * time.Millisecond)
+loop:
+	for {
+		select {
+		case <-timeout:
+			break loop
+		case <-time.After(50 * time.Millisecond):
+			guard.Lock()
+			N := len(received)
+			guard.Unlock()
+			if N >= 4 {
+				break loop
+			}
+		}
+	}
+
+	close(q)
+
+	expected := []uint32{10, 1, 2, 3}
+
+	guard.Lock()
+	defer guard.Unlock()
+
+	if !reflect.DeepEqual(received, expected) {
+		t.Errorf("Incorrect events received - expected:%v, got:%v", expected, received)
+	}
+}
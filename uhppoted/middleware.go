@@ -0,0 +1,627 @@
+package uhppoted
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// Handler invokes a UHPPOTED operation. The request is the typed request (e.g. GetCardsRequest)
+// and the response is the corresponding typed response (e.g. *GetCardsResponse). 'op' is the
+// operation name e.g. "get-cards".
+type Handler func(ctx context.Context, op string, request interface{}) (interface{}, error)
+
+// Middleware wraps a Handler to add behaviour (e.g. audit logging, authorization or caching)
+// around every UHPPOTED operation. A middleware may modify the request, short-circuit the call
+// by returning its own response (which must be of the same type as the operation response)
+// or modify the response and error returned by the next handler.
+type Middleware func(next Handler) Handler
+
+type chain struct {
+	uhppoted   IUHPPOTED
+	middleware []Middleware
+}
+
+// Returns an IUHPPOTED that invokes the middleware, outermost first, around each operation
+// of the wrapped IUHPPOTED. Listen is passed through unchanged.
+func Chain(u IUHPPOTED, middleware ...Middleware) IUHPPOTED {
+	return &chain{
+		uhppoted:   u,
+		middleware: append([]Middleware{}, middleware...),
+	}
+}
+
+func (c *chain) invoke(ctx context.Context, op string, request interface{}, f Handler) (interface{}, error) {
+	h := f
+	for i := len(c.middleware) - 1; i >= 0; i-- {
+		h = c.middleware[i](h)
+	}
+
+	return h(ctx, op, request)
+}
+
+// A middleware that replaces the request with a different type is a programming error but is
+// reported as an internal error rather than a panic.
+func invalidRequest(op string, expected, request interface{}) error {
+	return &Error{Op: op, Code: CodeInternalServerError, Err: fmt.Errorf("invalid request type - expected %T, got %T", expected, request)}
+}
+
+func invalidResponse(op string, expected, response interface{}) error {
+	return &Error{Op: op, Code: CodeInternalServerError, Err: fmt.Errorf("invalid response type - expected %T, got %T", expected, response)}
+}
+
+func (c *chain) Listen(handler EventHandler, received *EventMap, q chan os.Signal) {
+	c.uhppoted.Listen(handler, received, q)
+}
+
+func (c *chain) GetCardRecords(request GetCardRecordsRequest) (*GetCardRecordsResponse, error) {
+	return c.GetCardRecordsWithContext(context.Background(), request)
+}
+
+func (c *chain) GetCardRecordsWithContext(ctx context.Context, request GetCardRecordsRequest) (*GetCardRecordsResponse, error) {
+	response, err := c.invoke(ctx, "get-card-records", request, func(ctx context.Context, op string, rq interface{}) (interface{}, error) {
+		if v, ok := rq.(GetCardRecordsRequest); ok {
+			return c.uhppoted.GetCardRecordsWithContext(ctx, v)
+		}
+
+		return nil, invalidRequest(op, GetCardRecordsRequest{}, rq)
+	})
+
+	if r, ok := response.(*GetCardRecordsResponse); ok {
+		return r, err
+	} else if err != nil {
+		return nil, err
+	}
+
+	return nil, invalidResponse("get-card-records", &GetCardRecordsResponse{}, response)
+}
+
+func (c *chain) GetCards(request GetCardsRequest) (*GetCardsResponse, error) {
+	return c.GetCardsWithContext(context.Background(), request)
+}
+
+func (c *chain) GetCardsWithContext(ctx context.Context, request GetCardsRequest) (*GetCardsResponse, error) {
+	response, err := c.invoke(ctx, "get-cards", request, func(ctx context.Context, op string, rq interface{}) (interface{}, error) {
+		if v, ok := rq.(GetCardsRequest); ok {
+			return c.uhppoted.GetCardsWithContext(ctx, v)
+		}
+
+		return nil, invalidRequest(op, GetCardsRequest{}, rq)
+	})
+
+	if r, ok := response.(*GetCardsResponse); ok {
+		return r, err
+	} else if err != nil {
+		return nil, err
+	}
+
+	return nil, invalidResponse("get-cards", &GetCardsResponse{}, response)
+}
+
+func (c *chain) DeleteCards(request DeleteCardsRequest) (*DeleteCardsResponse, error) {
+	return c.DeleteCardsWithContext(context.Background(), request)
+}
+
+func (c *chain) DeleteCardsWithContext(ctx context.Context, request DeleteCardsRequest) (*DeleteCardsResponse, error) {
+	response, err := c.invoke(ctx, "delete-cards", request, func(ctx context.Context, op string, rq interface{}) (interface{}, error) {
+		if v, ok := rq.(DeleteCardsRequest); ok {
+			return c.uhppoted.DeleteCardsWithContext(ctx, v)
+		}
+
+		return nil, invalidRequest(op, DeleteCardsRequest{}, rq)
+	})
+
+	if r, ok := response.(*DeleteCardsResponse); ok {
+		return r, err
+	} else if err != nil {
+		return nil, err
+	}
+
+	return nil, invalidResponse("delete-cards", &DeleteCardsResponse{}, response)
+}
+
+func (c *chain) GetCard(request GetCardRequest) (*GetCardResponse, error) {
+	return c.GetCardWithContext(context.Background(), request)
+}
+
+func (c *chain) GetCardWithContext(ctx context.Context, request GetCardRequest) (*GetCardResponse, error) {
+	response, err := c.invoke(ctx, "get-card", request, func(ctx context.Context, op string, rq interface{}) (interface{}, error) {
+		if v, ok := rq.(GetCardRequest); ok {
+			return c.uhppoted.GetCardWithContext(ctx, v)
+		}
+
+		return nil, invalidRequest(op, GetCardRequest{}, rq)
+	})
+
+	if r, ok := response.(*GetCardResponse); ok {
+		return r, err
+	} else if err != nil {
+		return nil, err
+	}
+
+	return nil, invalidResponse("get-card", &GetCardResponse{}, response)
+}
+
+func (c *chain) PutCard(request PutCardRequest) (*PutCardResponse, error) {
+	return c.PutCardWithContext(context.Background(), request)
+}
+
+func (c *chain) PutCardWithContext(ctx context.Context, request PutCardRequest) (*PutCardResponse, error) {
+	response, err := c.invoke(ctx, "put-card", request, func(ctx context.Context, op string, rq interface{}) (interface{}, error) {
+		if v, ok := rq.(PutCardRequest); ok {
+			return c.uhppoted.PutCardWithContext(ctx, v)
+		}
+
+		return nil, invalidRequest(op, PutCardRequest{}, rq)
+	})
+
+	if r, ok := response.(*PutCardResponse); ok {
+		return r, err
+	} else if err != nil {
+		return nil, err
+	}
+
+	return nil, invalidResponse("put-card", &PutCardResponse{}, response)
+}
+
+func (c *chain) DeleteCard(request DeleteCardRequest) (*DeleteCardResponse, error) {
+	return c.DeleteCardWithContext(context.Background(), request)
+}
+
+func (c *chain) DeleteCardWithContext(ctx context.Context, request DeleteCardRequest) (*DeleteCardResponse, error) {
+	response, err := c.invoke(ctx, "delete-card", request, func(ctx context.Context, op string, rq interface{}) (interface{}, error) {
+		if v, ok := rq.(DeleteCardRequest); ok {
+			return c.uhppoted.DeleteCardWithContext(ctx, v)
+		}
+
+		return nil, invalidRequest(op, DeleteCardRequest{}, rq)
+	})
+
+	if r, ok := response.(*DeleteCardResponse); ok {
+		return r, err
+	} else if err != nil {
+		return nil, err
+	}
+
+	return nil, invalidResponse("delete-card", &DeleteCardResponse{}, response)
+}
+
+func (c *chain) GetDoorDelay(request GetDoorDelayRequest) (*GetDoorDelayResponse, error) {
+	return c.GetDoorDelayWithContext(context.Background(), request)
+}
+
+func (c *chain) GetDoorDelayWithContext(ctx context.Context, request GetDoorDelayRequest) (*GetDoorDelayResponse, error) {
+	response, err := c.invoke(ctx, "get-door-delay", request, func(ctx context.Context, op string, rq interface{}) (interface{}, error) {
+		if v, ok := rq.(GetDoorDelayRequest); ok {
+			return c.uhppoted.GetDoorDelayWithContext(ctx, v)
+		}
+
+		return nil, invalidRequest(op, GetDoorDelayRequest{}, rq)
+	})
+
+	if r, ok := response.(*GetDoorDelayResponse); ok {
+		return r, err
+	} else if err != nil {
+		return nil, err
+	}
+
+	return nil, invalidResponse("get-door-delay", &GetDoorDelayResponse{}, response)
+}
+
+func (c *chain) SetDoorDelay(request SetDoorDelayRequest) (*SetDoorDelayResponse, error) {
+	return c.SetDoorDelayWithContext(context.Background(), request)
+}
+
+func (c *chain) SetDoorDelayWithContext(ctx context.Context, request SetDoorDelayRequest) (*SetDoorDelayResponse, error) {
+	response, err := c.invoke(ctx, "set-door-delay", request, func(ctx context.Context, op string, rq interface{}) (interface{}, error) {
+		if v, ok := rq.(SetDoorDelayRequest); ok {
+			return c.uhppoted.SetDoorDelayWithContext(ctx, v)
+		}
+
+		return nil, invalidRequest(op, SetDoorDelayRequest{}, rq)
+	})
+
+	if r, ok := response.(*SetDoorDelayResponse); ok {
+		return r, err
+	} else if err != nil {
+		return nil, err
+	}
+
+	return nil, invalidResponse("set-door-delay", &SetDoorDelayResponse{}, response)
+}
+
+func (c *chain) GetDoorControl(request GetDoorControlRequest) (*GetDoorControlResponse, error) {
+	return c.GetDoorControlWithContext(context.Background(), request)
+}
+
+func (c *chain) GetDoorControlWithContext(ctx context.Context, request GetDoorControlRequest) (*GetDoorControlResponse, error) {
+	response, err := c.invoke(ctx, "get-door-control", request, func(ctx context.Context, op string, rq interface{}) (interface{}, error) {
+		if v, ok := rq.(GetDoorControlRequest); ok {
+			return c.uhppoted.GetDoorControlWithContext(ctx, v)
+		}
+
+		return nil, invalidRequest(op, GetDoorControlRequest{}, rq)
+	})
+
+	if r, ok := response.(*GetDoorControlResponse); ok {
+		return r, err
+	} else if err != nil {
+		return nil, err
+	}
+
+	return nil, invalidResponse("get-door-control", &GetDoorControlResponse{}, response)
+}
+
+func (c *chain) SetDoorControl(request SetDoorControlRequest) (*SetDoorControlResponse, error) {
+	return c.SetDoorControlWithContext(context.Background(), request)
+}
+
+func (c *chain) SetDoorControlWithContext(ctx context.Context, request SetDoorControlRequest) (*SetDoorControlResponse, error) {
+	response, err := c.invoke(ctx, "set-door-control", request, func(ctx context.Context, op string, rq interface{}) (interface{}, error) {
+		if v, ok := rq.(SetDoorControlRequest); ok {
+			return c.uhppoted.SetDoorControlWithContext(ctx, v)
+		}
+
+		return nil, invalidRequest(op, SetDoorControlRequest{}, rq)
+	})
+
+	if r, ok := response.(*SetDoorControlResponse); ok {
+		return r, err
+	} else if err != nil {
+		return nil, err
+	}
+
+	return nil, invalidResponse("set-door-control", &SetDoorControlResponse{}, response)
+}
+
+func (c *chain) OpenDoor(request OpenDoorRequest) (*OpenDoorResponse, error) {
+	return c.OpenDoorWithContext(context.Background(), request)
+}
+
+func (c *chain) OpenDoorWithContext(ctx context.Context, request OpenDoorRequest) (*OpenDoorResponse, error) {
+	response, err := c.invoke(ctx, "open-door", request, func(ctx context.Context, op string, rq interface{}) (interface{}, error) {
+		if v, ok := rq.(OpenDoorRequest); ok {
+			return c.uhppoted.OpenDoorWithContext(ctx, v)
+		}
+
+		return nil, invalidRequest(op, OpenDoorRequest{}, rq)
+	})
+
+	if r, ok := response.(*OpenDoorResponse); ok {
+		return r, err
+	} else if err != nil {
+		return nil, err
+	}
+
+	return nil, invalidResponse("open-door", &OpenDoorResponse{}, response)
+}
+
+func (c *chain) GetEventRange(request GetEventRangeRequest) (*GetEventRangeResponse, error) {
+	return c.GetEventRangeWithContext(context.Background(), request)
+}
+
+func (c *chain) GetEventRangeWithContext(ctx context.Context, request GetEventRangeRequest) (*GetEventRangeResponse, error) {
+	response, err := c.invoke(ctx, "get-event-range", request, func(ctx context.Context, op string, rq interface{}) (interface{}, error) {
+		if v, ok := rq.(GetEventRangeRequest); ok {
+			return c.uhppoted.GetEventRangeWithContext(ctx, v)
+		}
+
+		return nil, invalidRequest(op, GetEventRangeRequest{}, rq)
+	})
+
+	if r, ok := response.(*GetEventRangeResponse); ok {
+		return r, err
+	} else if err != nil {
+		return nil, err
+	}
+
+	return nil, invalidResponse("get-event-range", &GetEventRangeResponse{}, response)
+}
+
+func (c *chain) GetEvent(request GetEventRequest) (*GetEventResponse, error) {
+	return c.GetEventWithContext(context.Background(), request)
+}
+
+func (c *chain) GetEventWithContext(ctx context.Context, request GetEventRequest) (*GetEventResponse, error) {
+	response, err := c.invoke(ctx, "get-event", request, func(ctx context.Context, op string, rq interface{}) (interface{}, error) {
+		if v, ok := rq.(GetEventRequest); ok {
+			return c.uhppoted.GetEventWithContext(ctx, v)
+		}
+
+		return nil, invalidRequest(op, GetEventRequest{}, rq)
+	})
+
+	if r, ok := response.(*GetEventResponse); ok {
+		return r, err
+	} else if err != nil {
+		return nil, err
+	}
+
+	return nil, invalidResponse("get-event", &GetEventResponse{}, response)
+}
+
+func (c *chain) GetEvents(request GetEventsRequest) (*GetEventsResponse, error) {
+	return c.GetEventsWithContext(context.Background(), request)
+}
+
+func (c *chain) GetEventsWithContext(ctx context.Context, request GetEventsRequest) (*GetEventsResponse, error) {
+	response, err := c.invoke(ctx, "get-events", request, func(ctx context.Context, op string, rq interface{}) (interface{}, error) {
+		if v, ok := rq.(GetEventsRequest); ok {
+			return c.uhppoted.GetEventsWithContext(ctx, v)
+		}
+
+		return nil, invalidRequest(op, GetEventsRequest{}, rq)
+	})
+
+	if r, ok := response.(*GetEventsResponse); ok {
+		return r, err
+	} else if err != nil {
+		return nil, err
+	}
+
+	return nil, invalidResponse("get-events", &GetEventsResponse{}, response)
+}
+
+func (c *chain) RecordSpecialEvents(request RecordSpecialEventsRequest) (*RecordSpecialEventsResponse, error) {
+	return c.RecordSpecialEventsWithContext(context.Background(), request)
+}
+
+func (c *chain) RecordSpecialEventsWithContext(ctx context.Context, request RecordSpecialEventsRequest) (*RecordSpecialEventsResponse, error) {
+	response, err := c.invoke(ctx, "record-special-events", request, func(ctx context.Context, op string, rq interface{}) (interface{}, error) {
+		if v, ok := rq.(RecordSpecialEventsRequest); ok {
+			return c.uhppoted.RecordSpecialEventsWithContext(ctx, v)
+		}
+
+		return nil, invalidRequest(op, RecordSpecialEventsRequest{}, rq)
+	})
+
+	if r, ok := response.(*RecordSpecialEventsResponse); ok {
+		return r, err
+	} else if err != nil {
+		return nil, err
+	}
+
+	return nil, invalidResponse("record-special-events", &RecordSpecialEventsResponse{}, response)
+}
+
+func (c *chain) GetDevices(request GetDevicesRequest) (*GetDevicesResponse, error) {
+	return c.GetDevicesWithContext(context.Background(), request)
+}
+
+func (c *chain) GetDevicesWithContext(ctx context.Context, request GetDevicesRequest) (*GetDevicesResponse, error) {
+	response, err := c.invoke(ctx, "get-devices", request, func(ctx context.Context, op string, rq interface{}) (interface{}, error) {
+		if v, ok := rq.(GetDevicesRequest); ok {
+			return c.uhppoted.GetDevicesWithContext(ctx, v)
+		}
+
+		return nil, invalidRequest(op, GetDevicesRequest{}, rq)
+	})
+
+	if r, ok := response.(*GetDevicesResponse); ok {
+		return r, err
+	} else if err != nil {
+		return nil, err
+	}
+
+	return nil, invalidResponse("get-devices", &GetDevicesResponse{}, response)
+}
+
+func (c *chain) GetDevice(request GetDeviceRequest) (*GetDeviceResponse, error) {
+	return c.GetDeviceWithContext(context.Background(), request)
+}
+
+func (c *chain) GetDeviceWithContext(ctx context.Context, request GetDeviceRequest) (*GetDeviceResponse, error) {
+	response, err := c.invoke(ctx, "get-device", request, func(ctx context.Context, op string, rq interface{}) (interface{}, error) {
+		if v, ok := rq.(GetDeviceRequest); ok {
+			return c.uhppoted.GetDeviceWithContext(ctx, v)
+		}
+
+		return nil, invalidRequest(op, GetDeviceRequest{}, rq)
+	})
+
+	if r, ok := response.(*GetDeviceResponse); ok {
+		return r, err
+	} else if err != nil {
+		return nil, err
+	}
+
+	return nil, invalidResponse("get-device", &GetDeviceResponse{}, response)
+}
+
+func (c *chain) GetStatus(request GetStatusRequest) (*GetStatusResponse, error) {
+	return c.GetStatusWithContext(context.Background(), request)
+}
+
+func (c *chain) GetStatusWithContext(ctx context.Context, request GetStatusRequest) (*GetStatusResponse, error) {
+	response, err := c.invoke(ctx, "get-status", request, func(ctx context.Context, op string, rq interface{}) (interface{}, error) {
+		if v, ok := rq.(GetStatusRequest); ok {
+			return c.uhppoted.GetStatusWithContext(ctx, v)
+		}
+
+		return nil, invalidRequest(op, GetStatusRequest{}, rq)
+	})
+
+	if r, ok := response.(*GetStatusResponse); ok {
+		return r, err
+	} else if err != nil {
+		return nil, err
+	}
+
+	return nil, invalidResponse("get-status", &GetStatusResponse{}, response)
+}
+
+func (c *chain) GetTime(request GetTimeRequest) (*GetTimeResponse, error) {
+	return c.GetTimeWithContext(context.Background(), request)
+}
+
+func (c *chain) GetTimeWithContext(ctx context.Context, request GetTimeRequest) (*GetTimeResponse, error) {
+	response, err := c.invoke(ctx, "get-time", request, func(ctx context.Context, op string, rq interface{}) (interface{}, error) {
+		if v, ok := rq.(GetTimeRequest); ok {
+			return c.uhppoted.GetTimeWithContext(ctx, v)
+		}
+
+		return nil, invalidRequest(op, GetTimeRequest{}, rq)
+	})
+
+	if r, ok := response.(*GetTimeResponse); ok {
+		return r, err
+	} else if err != nil {
+		return nil, err
+	}
+
+	return nil, invalidResponse("get-time", &GetTimeResponse{}, response)
+}
+
+func (c *chain) SetTime(request SetTimeRequest) (*SetTimeResponse, error) {
+	return c.SetTimeWithContext(context.Background(), request)
+}
+
+func (c *chain) SetTimeWithContext(ctx context.Context, request SetTimeRequest) (*SetTimeResponse, error) {
+	response, err := c.invoke(ctx, "set-time", request, func(ctx context.Context, op string, rq interface{}) (interface{}, error) {
+		if v, ok := rq.(SetTimeRequest); ok {
+			return c.uhppoted.SetTimeWithContext(ctx, v)
+		}
+
+		return nil, invalidRequest(op, SetTimeRequest{}, rq)
+	})
+
+	if r, ok := response.(*SetTimeResponse); ok {
+		return r, err
+	} else if err != nil {
+		return nil, err
+	}
+
+	return nil, invalidResponse("set-time", &SetTimeResponse{}, response)
+}
+
+func (c *chain) GetTimeProfiles(request GetTimeProfilesRequest) (*GetTimeProfilesResponse, error) {
+	return c.GetTimeProfilesWithContext(context.Background(), request)
+}
+
+func (c *chain) GetTimeProfilesWithContext(ctx context.Context, request GetTimeProfilesRequest) (*GetTimeProfilesResponse, error) {
+	response, err := c.invoke(ctx, "get-time-profiles", request, func(ctx context.Context, op string, rq interface{}) (interface{}, error) {
+		if v, ok := rq.(GetTimeProfilesRequest); ok {
+			return c.uhppoted.GetTimeProfilesWithContext(ctx, v)
+		}
+
+		return nil, invalidRequest(op, GetTimeProfilesRequest{}, rq)
+	})
+
+	if r, ok := response.(*GetTimeProfilesResponse); ok {
+		return r, err
+	} else if err != nil {
+		return nil, err
+	}
+
+	return nil, invalidResponse("get-time-profiles", &GetTimeProfilesResponse{}, response)
+}
+
+func (c *chain) PutTimeProfiles(request PutTimeProfilesRequest) (*PutTimeProfilesResponse, error) {
+	return c.PutTimeProfilesWithContext(context.Background(), request)
+}
+
+func (c *chain) PutTimeProfilesWithContext(ctx context.Context, request PutTimeProfilesRequest) (*PutTimeProfilesResponse, error) {
+	response, err := c.invoke(ctx, "put-time-profiles", request, func(ctx context.Context, op string, rq interface{}) (interface{}, error) {
+		if v, ok := rq.(PutTimeProfilesRequest); ok {
+			return c.uhppoted.PutTimeProfilesWithContext(ctx, v)
+		}
+
+		return nil, invalidRequest(op, PutTimeProfilesRequest{}, rq)
+	})
+
+	if r, ok := response.(*PutTimeProfilesResponse); ok {
+		return r, err
+	} else if err != nil {
+		return nil, err
+	}
+
+	return nil, invalidResponse("put-time-profiles", &PutTimeProfilesResponse{}, response)
+}
+
+func (c *chain) GetTimeProfile(request GetTimeProfileRequest) (*GetTimeProfileResponse, error) {
+	return c.GetTimeProfileWithContext(context.Background(), request)
+}
+
+func (c *chain) GetTimeProfileWithContext(ctx context.Context, request GetTimeProfileRequest) (*GetTimeProfileResponse, error) {
+	response, err := c.invoke(ctx, "get-time-profile", request, func(ctx context.Context, op string, rq interface{}) (interface{}, error) {
+		if v, ok := rq.(GetTimeProfileRequest); ok {
+			return c.uhppoted.GetTimeProfileWithContext(ctx, v)
+		}
+
+		return nil, invalidRequest(op, GetTimeProfileRequest{}, rq)
+	})
+
+	if r, ok := response.(*GetTimeProfileResponse); ok {
+		return r, err
+	} else if err != nil {
+		return nil, err
+	}
+
+	return nil, invalidResponse("get-time-profile", &GetTimeProfileResponse{}, response)
+}
+
+func (c *chain) PutTimeProfile(request PutTimeProfileRequest) (*PutTimeProfileResponse, error) {
+	return c.PutTimeProfileWithContext(context.Background(), request)
+}
+
+func (c *chain) PutTimeProfileWithContext(ctx context.Context, request PutTimeProfileRequest) (*PutTimeProfileResponse, error) {
+	response, err := c.invoke(ctx, "put-time-profile", request, func(ctx context.Context, op string, rq interface{}) (interface{}, error) {
+		if v, ok := rq.(PutTimeProfileRequest); ok {
+			return c.uhppoted.PutTimeProfileWithContext(ctx, v)
+		}
+
+		return nil, invalidRequest(op, PutTimeProfileRequest{}, rq)
+	})
+
+	if r, ok := response.(*PutTimeProfileResponse); ok {
+		return r, err
+	} else if err != nil {
+		return nil, err
+	}
+
+	return nil, invalidResponse("put-time-profile", &PutTimeProfileResponse{}, response)
+}
+
+func (c *chain) ClearTimeProfiles(request ClearTimeProfilesRequest) (*ClearTimeProfilesResponse, error) {
+	return c.ClearTimeProfilesWithContext(context.Background(), request)
+}
+
+func (c *chain) ClearTimeProfilesWithContext(ctx context.Context, request ClearTimeProfilesRequest) (*ClearTimeProfilesResponse, error) {
+	response, err := c.invoke(ctx, "clear-time-profiles", request, func(ctx context.Context, op string, rq interface{}) (interface{}, error) {
+		if v, ok := rq.(ClearTimeProfilesRequest); ok {
+			return c.uhppoted.ClearTimeProfilesWithContext(ctx, v)
+		}
+
+		return nil, invalidRequest(op, ClearTimeProfilesRequest{}, rq)
+	})
+
+	if r, ok := response.(*ClearTimeProfilesResponse); ok {
+		return r, err
+	} else if err != nil {
+		return nil, err
+	}
+
+	return nil, invalidResponse("clear-time-profiles", &ClearTimeProfilesResponse{}, response)
+}
+
+func (c *chain) SynchronizeTime(request SynchronizeTimeRequest) (*SynchronizeTimeResponse, error) {
+	return c.SynchronizeTimeWithContext(context.Background(), request)
+}
+
+func (c *chain) SynchronizeTimeWithContext(ctx context.Context, request SynchronizeTimeRequest) (*SynchronizeTimeResponse, error) {
+	response, err := c.invoke(ctx, "synchronize-time", request, func(ctx context.Context, op string, rq interface{}) (interface{}, error) {
+		if v, ok := rq.(SynchronizeTimeRequest); ok {
+			return c.uhppoted.SynchronizeTimeWithContext(ctx, v)
+		}
+
+		return nil, invalidRequest(op, SynchronizeTimeRequest{}, rq)
+	})
+
+	if r, ok := response.(*SynchronizeTimeResponse); ok {
+		return r, err
+	} else if err != nil {
+		return nil, err
+	}
+
+	return nil, invalidResponse("synchronize-time", &SynchronizeTimeResponse{}, response)
+}
@@ -0,0 +1,150 @@
+package uhppoted
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/uhppoted/uhppote-core/types"
+	"github.com/uhppoted/uhppoted-api/simulator"
+)
+
+func TestMiddlewareChain(t *testing.T) {
+	s := simulator.NewSimulator(nil, simulator.NewDevice(405419896, net.IPv4(192, 168, 1, 125)))
+	u := UHPPOTED{
+		UHPPOTE: s,
+	}
+
+	calls := []string{}
+	trace := func(tag string) Middleware {
+		return func(next Handler) Handler {
+			return func(ctx context.Context, op string, request interface{}) (interface{}, error) {
+				calls = append(calls, tag+":"+op)
+				response, err := next(ctx, op, request)
+				calls = append(calls, tag+":done")
+				return response, err
+			}
+		}
+	}
+
+	var rq interface{}
+	var rsp interface{}
+	audit := func(next Handler) Handler {
+		return func(ctx context.Context, op string, request interface{}) (interface{}, error) {
+			rq = request
+			response, err := next(ctx, op, request)
+			rsp = response
+			return response, err
+		}
+	}
+
+	c := Chain(&u, trace("A"), trace("B"), audit)
+
+	response, err := c.GetStatus(GetStatusRequest{DeviceID: 405419896})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{"A:get-status", "B:get-status", "B:done", "A:done"}
+	if !reflect.DeepEqual(calls, expected) {
+		t.Errorf("Incorrect middleware order - expected:%v, got:%v", expected, calls)
+	}
+
+	if _, ok := rq.(GetStatusRequest); !ok {
+		t.Errorf("Incorrect request type - expected:%T, got:%T", GetStatusRequest{}, rq)
+	}
+
+	if rsp != response {
+		t.Errorf("Incorrect response - expected:%v, got:%v", response, rsp)
+	}
+}
+
+func TestMiddlewareShortCircuit(t *testing.T) {
+	s := simulator.NewSimulator(nil, simulator.NewDevice(405419896, net.IPv4(192, 168, 1, 125)))
+	s.Inject(simulator.Fault{Type: simulator.Timeout, Operation: "put-card"})
+
+	u := UHPPOTED{
+		UHPPOTE: s,
+	}
+
+	dryrun := func(next Handler) Handler {
+		return func(ctx context.Context, op string, request interface{}) (interface{}, error) {
+			if rq, ok := request.(PutCardRequest); ok {
+				return &PutCardResponse{DeviceID: rq.DeviceID, Card: rq.Card}, nil
+			}
+
+			return next(ctx, op, request)
+		}
+	}
+
+	c := Chain(&u, dryrun)
+	card := types.Card{CardNumber: 8165538}
+
+	response, err := c.PutCard(PutCardRequest{DeviceID: 405419896, Card: card})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if response == nil || response.DeviceID != 405419896 || response.Card.CardNumber != 8165538 {
+		t.Errorf("Incorrect response - got:%+v", response)
+	}
+}
+
+func TestMiddlewareWithInvalidRequest(t *testing.T) {
+	s := simulator.NewSimulator(nil, simulator.NewDevice(405419896, net.IPv4(192, 168, 1, 125)))
+	u := UHPPOTED{
+		UHPPOTE: s,
+	}
+
+	rewrite := func(next Handler) Handler {
+		return func(ctx context.Context, op string, request interface{}) (interface{}, error) {
+			return next(ctx, op, GetDeviceRequest{DeviceID: 405419896})
+		}
+	}
+
+	c := Chain(&u, rewrite)
+
+	response, err := c.GetStatus(GetStatusRequest{DeviceID: 405419896})
+	if err == nil {
+		t.Fatalf("Expected error, got:%v", err)
+	}
+
+	if !errors.Is(err, InternalServerError) {
+		t.Errorf("Incorrect error - expected:%v, got:%v", InternalServerError, err)
+	}
+
+	if response != nil {
+		t.Errorf("Unexpected response - got:%+v", response)
+	}
+}
+
+func TestMiddlewareWithInvalidResponse(t *testing.T) {
+	s := simulator.NewSimulator(nil, simulator.NewDevice(405419896, net.IPv4(192, 168, 1, 125)))
+	u := UHPPOTED{
+		UHPPOTE: s,
+	}
+
+	rewrite := func(next Handler) Handler {
+		return func(ctx context.Context, op string, request interface{}) (interface{}, error) {
+			return &GetDeviceResponse{DeviceID: 405419896}, nil
+		}
+	}
+
+	c := Chain(&u, rewrite)
+
+	response, err := c.GetStatus(GetStatusRequest{DeviceID: 405419896})
+	if err == nil {
+		t.Fatalf("Expected error, got:%v", err)
+	}
+
+	var e *Error
+	if !errors.As(err, &e) || e.Code != CodeInternalServerError || e.Op != "get-status" {
+		t.Errorf("Incorrect error - expected:%v, got:%#v", CodeInternalServerError, err)
+	}
+
+	if response != nil {
+		t.Errorf("Unexpected response - got:%+v", response)
+	}
+}
@@ -0,0 +1,238 @@
+package uhppoted
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/uhppoted/uhppote-core/types"
+)
+
+type SearchStrategy string
+
+const (
+	// Event range located using only a binary search of the event buffer.
+	BinarySearch SearchStrategy = "binary-search"
+
+	// Binary search found out of order event timestamps (e.g. after a device time change) and
+	// fell back to a linear scan for at least part of the event buffer.
+	LinearScan SearchStrategy = "linear-scan"
+)
+
+// Maximum distance (in events) either side of an out of order event that the binary search
+// scans for an in order event before giving up.
+const SCAN_WINDOW = 64
+
+// Bisects the on-device circular event buffer, treating it as a list of 'size' events starting
+// at event index 'first'. Positions are offsets from the first event so that the search does
+// not need to care about where the buffer rolls over.
+type search struct {
+	ctx      context.Context
+	u        *UHPPOTED
+	device   uint32
+	first    uint32
+	size     uint32
+	rollover uint32
+	cache    map[uint32]*types.Event
+	linear   bool
+}
+
+func newSearch(ctx context.Context, u *UHPPOTED, device uint32, rollover uint32, first, last *types.Event) *search {
+	size := (last.Index+rollover-first.Index)%rollover + 1
+
+	return &search{
+		ctx:      ctx,
+		u:        u,
+		device:   device,
+		first:    first.Index,
+		size:     size,
+		rollover: rollover,
+		cache: map[uint32]*types.Event{
+			0:        first,
+			size - 1: last,
+		},
+	}
+}
+
+func (s *search) strategy() SearchStrategy {
+	if s.linear {
+		return LinearScan
+	}
+
+	return BinarySearch
+}
+
+func (s *search) index(position uint32) uint32 {
+	return (s.first-1+position)%s.rollover + 1
+}
+
+// Returns the event at the position in the event buffer, or nil if the device does not have an
+// event record for the corresponding index.
+func (s *search) get(position uint32) (*types.Event, error) {
+	if e, ok := s.cache[position]; ok {
+		return e, nil
+	}
+
+	if err := cancelled(s.ctx); err != nil {
+		return nil, err
+	}
+
+	index := s.index(position)
+	record, err := s.u.UHPPOTE.GetEvent(s.device, index)
+	if err != nil {
+		return nil, internalError("get-events", s.device, fmt.Errorf("Error getting event for index %v from %v (%w)", index, s.device, err))
+	}
+
+	if record != nil && record.Index != index {
+		record = nil
+	}
+
+	s.cache[position] = record
+
+	return record, nil
+}
+
+// Returns the positions [from,to) of the events with timestamps between start and end
+// (inclusive). A nil start or end is unbounded.
+func (s *search) locate(start, end *types.DateTime) (uint32, uint32, error) {
+	from := uint32(0)
+	to := s.size
+
+	if start != nil {
+		p, err := s.bisect(func(e *types.Event) bool { return !time.Time(e.Timestamp).Before(time.Time(*start)) })
+		if err != nil {
+			return 0, 0, err
+		}
+
+		from = p
+	}
+
+	if end != nil {
+		p, err := s.bisect(func(e *types.Event) bool { return time.Time(e.Timestamp).After(time.Time(*end)) })
+		if err != nil {
+			return 0, 0, err
+		}
+
+		to = p
+	}
+
+	return from, to, nil
+}
+
+// Returns the position of the first event for which f is true, assuming that f is false for
+// all events before that position and true for all events after it. Returns 'size' if there
+// is no such event.
+//
+// Any probe with a timestamp outside the timestamps of the current bounds means the events
+// are not ordered by time in that part of the buffer, in which case the search scans the
+// events around the probe for the nearest in order event and resumes the binary search from
+// there. The search fails if there is no in order event within SCAN_WINDOW of the probe
+// (unless that covers all the events between the bounds).
+func (s *search) bisect(f func(*types.Event) bool) (uint32, error) {
+	lo := uint32(0)
+	hi := s.size - 1
+
+	first, err := s.get(lo)
+	if err != nil {
+		return 0, err
+	} else if f(first) {
+		return lo, nil
+	}
+
+	last, err := s.get(hi)
+	if err != nil {
+		return 0, err
+	} else if !f(last) {
+		return s.size, nil
+	}
+
+	for hi-lo > 1 {
+		mid := lo + (hi-lo)/2
+		record, err := s.get(mid)
+		if err != nil {
+			return 0, err
+		}
+
+		if record == nil || before(record, first) || before(last, record) {
+			p, r, err := s.nearest(lo, hi, mid, first, last)
+			if err != nil {
+				return 0, err
+			} else if r == nil && mid-lo <= SCAN_WINDOW && hi-mid <= SCAN_WINDOW {
+				return s.scan(lo+1, hi, f)
+			} else if r == nil {
+				return 0, internalError("get-events", s.device, fmt.Errorf("No in order events within %v events of index %v for %v", SCAN_WINDOW, s.index(mid), s.device))
+			}
+
+			mid, record = p, r
+		}
+
+		if f(record) {
+			hi = mid
+			last = record
+		} else {
+			lo = mid
+			first = record
+		}
+	}
+
+	return hi, nil
+}
+
+// Linear scan outwards from the position for the nearest event in (lo,hi) with a timestamp
+// between the timestamps of the first and last events. Returns a nil event if there is no such
+// event within SCAN_WINDOW of the position.
+func (s *search) nearest(lo, hi, position uint32, first, last *types.Event) (uint32, *types.Event, error) {
+	s.u.debug("get-events", fmt.Sprintf("out of order events around %v for %v, scanning for nearest in order event", s.index(position), s.device))
+
+	s.linear = true
+
+	inorder := func(p uint32) (*types.Event, error) {
+		record, err := s.get(p)
+		if err != nil || record == nil || before(record, first) || before(last, record) {
+			return nil, err
+		}
+
+		return record, nil
+	}
+
+	for d := uint32(1); d <= SCAN_WINDOW; d++ {
+		if position-lo > d {
+			if record, err := inorder(position - d); err != nil || record != nil {
+				return position - d, record, err
+			}
+		}
+
+		if hi-position > d {
+			if record, err := inorder(position + d); err != nil || record != nil {
+				return position + d, record, err
+			}
+		}
+	}
+
+	return 0, nil, nil
+}
+
+// Linear scan for the first event in [from,to) for which f is true. Returns 'to' if there is
+// no such event.
+func (s *search) scan(from, to uint32, f func(*types.Event) bool) (uint32, error) {
+	s.u.debug("get-events", fmt.Sprintf("out of order events in range %v-%v for %v, falling back to linear scan", s.index(from), s.index(to), s.device))
+
+	s.linear = true
+
+	for p := from; p < to; p++ {
+		record, err := s.get(p)
+		if err != nil {
+			return 0, err
+		}
+
+		if record != nil && f(record) {
+			return p, nil
+		}
+	}
+
+	return to, nil
+}
+
+func before(p, q *types.Event) bool {
+	return time.Time(p.Timestamp).Before(time.Time(q.Timestamp))
+}
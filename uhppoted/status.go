@@ -1,6 +1,7 @@
 package uhppoted
 
 import (
+	"context"
 	"fmt"
 	"github.com/uhppoted/uhppote-core/types"
 )
@@ -26,6 +27,10 @@ type StatusEvent struct {
 	CardNumber uint32          `json:"card-number"`
 	Timestamp  *types.DateTime `json:"timestamp,omitempty"`
 	Reason     uint8           `json:"reason"`
+	Info       *EventInfo      `json:"info,omitempty"`
+	DeviceName string          `json:"device-name,omitempty"`
+	DoorName   string          `json:"door-name,omitempty"`
+	Cardholder string          `json:"cardholder,omitempty"`
 }
 
 type GetStatusRequest struct {
@@ -35,15 +40,26 @@ type GetStatusRequest struct {
 type GetStatusResponse struct {
 	DeviceID DeviceID `json:"device-id"`
 	Status   Status   `json:"status"`
+	Retries  uint64   `json:"retries,omitempty"`
 }
 
 func (u *UHPPOTED) GetStatus(request GetStatusRequest) (*GetStatusResponse, error) {
+	return u.GetStatusWithContext(context.Background(), request)
+}
+
+func (u *UHPPOTED) GetStatusWithContext(ctx context.Context, request GetStatusRequest) (*GetStatusResponse, error) {
 	u.debug("get-status", fmt.Sprintf("request  %+v", request))
 
+	u, retries := u.track()
+
 	device := uint32(request.DeviceID)
+	if err := cancelled(ctx); err != nil {
+		return nil, err
+	}
+
 	status, err := u.UHPPOTE.GetStatus(device)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", InternalServerError, fmt.Errorf("Error retrieving status for %v (%w)", device, err))
+		return nil, internalError("get-status", device, fmt.Errorf("Error retrieving status for %v (%w)", device, err))
 	}
 
 	response := GetStatusResponse{
@@ -61,6 +77,7 @@ func (u *UHPPOTED) GetStatus(request GetStatusRequest) (*GetStatusResponse, erro
 	}
 
 	if status.Event != nil {
+		enrichment := u.enrich(device, status.Event.Door, status.Event.CardNumber)
 		response.Status.Event = &StatusEvent{
 			Index:      status.Event.Index,
 			Type:       status.Event.Type,
@@ -70,9 +87,15 @@ func (u *UHPPOTED) GetStatus(request GetStatusRequest) (*GetStatusResponse, erro
 			CardNumber: status.Event.CardNumber,
 			Timestamp:  status.Event.Timestamp,
 			Reason:     status.Event.Reason,
+			Info:       u.decode(status.Event.Type, status.Event.Reason, status.Event.Direction),
+			DeviceName: enrichment.device,
+			DoorName:   enrichment.door,
+			Cardholder: enrichment.cardholder,
 		}
 	}
 
+	response.Retries = retries()
+
 	u.debug("get-status", fmt.Sprintf("response %+v", response))
 
 	return &response, nil
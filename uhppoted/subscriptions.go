@@ -0,0 +1,346 @@
+package uhppoted
+
+import (
+	"sync"
+	"time"
+
+	"github.com/uhppoted/uhppote-core/types"
+)
+
+const SUBSCRIPTION_QUEUE_SIZE = 256
+
+type SubscriptionID uint64
+
+// Declarative event filter for a subscription. Empty lists and nil fields match all events.
+// The time of day window [Start,End] wraps around midnight if End is before Start.
+type Filter struct {
+	Devices []uint32
+	Doors   []uint8
+	Cards   []uint32
+	Granted *bool
+	Types   []uint8
+	Start   *types.HHmm
+	End     *types.HHmm
+}
+
+// DeliveryPolicy for a subscriber. QueueSize is the subscriber queue size (defaults to
+// SUBSCRIPTION_QUEUE_SIZE). An event for a subscriber with a full queue waits for up to Wait
+// for space in the queue (indefinitely if Block is set) before it is dropped. Synchronous
+// subscribers bypass the queue - the event is passed directly to the handler and is rejected
+// if the handler returns false.
+type DeliveryPolicy struct {
+	QueueSize   int
+	Wait        time.Duration
+	Block       bool
+	Synchronous bool
+}
+
+// Dispatches events from Listen to any number of subscribers. Each subscriber has its own
+// queue and delivery goroutine so that a slow subscriber cannot stall the other subscribers
+// (unless it has a Block or Wait delivery policy). An event that could not be queued for a
+// subscriber is counted as dropped but does not hold back event retrieval. Only an event
+// rejected by a Synchronous subscriber makes the Listen handler return false, so that the
+// event is not marked as retrieved and is delivered again - but only to the subscribers that
+// have not already accepted it.
+type Subscriptions struct {
+	subscribers map[SubscriptionID]*subscriber
+	next        SubscriptionID
+	guard       sync.RWMutex
+}
+
+type subscriber struct {
+	filter   Filter
+	handler  EventHandler
+	delivery DeliveryPolicy
+	queue    chan EventMessage
+	stop     chan struct{}
+	done     chan struct{}
+	sending  sync.RWMutex
+	dropped  uint64
+	accepted map[DeviceID]uint32
+	guard    sync.Mutex
+}
+
+func NewSubscriptions() *Subscriptions {
+	return &Subscriptions{
+		subscribers: map[SubscriptionID]*subscriber{},
+	}
+}
+
+// Registers a handler for the events that match the filter. A queue size of 0 uses the
+// default SUBSCRIPTION_QUEUE_SIZE. Events for a subscriber with a full queue are dropped
+// immediately.
+func (s *Subscriptions) Subscribe(filter Filter, handler EventHandler, queueSize int) SubscriptionID {
+	return s.SubscribeWithDelivery(filter, handler, DeliveryPolicy{QueueSize: queueSize})
+}
+
+// Registers a handler for the events that match the filter, with a delivery policy for
+// events that arrive while the subscriber queue is full.
+func (s *Subscriptions) SubscribeWithDelivery(filter Filter, handler EventHandler, delivery DeliveryPolicy) SubscriptionID {
+	if delivery.QueueSize <= 0 {
+		delivery.QueueSize = SUBSCRIPTION_QUEUE_SIZE
+	}
+
+	sub := subscriber{
+		filter:   filter,
+		handler:  handler,
+		delivery: delivery,
+		queue:    make(chan EventMessage, delivery.QueueSize),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+		accepted: map[DeviceID]uint32{},
+	}
+
+	s.guard.Lock()
+	s.next++
+	id := s.next
+	s.subscribers[id] = &sub
+	s.guard.Unlock()
+
+	go sub.run()
+
+	return id
+}
+
+// Removes the subscription. Events already queued for the subscriber are still delivered.
+func (s *Subscriptions) Unsubscribe(id SubscriptionID) {
+	s.guard.Lock()
+	sub, ok := s.subscribers[id]
+	delete(s.subscribers, id)
+	s.guard.Unlock()
+
+	if ok {
+		sub.close()
+	}
+}
+
+// Unsubscribes all subscribers and waits for the queued events to be delivered.
+func (s *Subscriptions) Close() {
+	s.guard.Lock()
+	subscribers := s.subscribers
+	s.subscribers = map[SubscriptionID]*subscriber{}
+	s.guard.Unlock()
+
+	for _, sub := range subscribers {
+		sub.close()
+	}
+
+	for _, sub := range subscribers {
+		<-sub.done
+	}
+}
+
+// Returns the number of events that were not delivered to the subscriber, i.e. discarded because
+// the subscriber queue was full or rejected by a synchronous subscriber.
+func (s *Subscriptions) Dropped(id SubscriptionID) uint64 {
+	s.guard.RLock()
+	sub, ok := s.subscribers[id]
+	s.guard.RUnlock()
+
+	if ok {
+		sub.guard.Lock()
+		defer sub.guard.Unlock()
+
+		return sub.dropped
+	}
+
+	return 0
+}
+
+// Returns an EventHandler for Listen that dispatches events to the matching subscribers. The
+// handler returns false if the event was rejected by any of the matching synchronous subscribers.
+func (s *Subscriptions) Handler() EventHandler {
+	return func(message EventMessage) bool {
+		return s.dispatch(message)
+	}
+}
+
+func (s *Subscriptions) dispatch(message EventMessage) bool {
+	s.guard.RLock()
+	matched := []*subscriber{}
+	skipped := []*subscriber{}
+	for _, sub := range s.subscribers {
+		if sub.filter.match(message.Event) {
+			if sub.isAccepted(message.Event) {
+				skipped = append(skipped, sub)
+			} else {
+				matched = append(matched, sub)
+			}
+		}
+	}
+	s.guard.RUnlock()
+
+	var wg sync.WaitGroup
+	sent := make([]bool, len(matched))
+
+	for i, sub := range matched {
+		ix := i
+		subscriber := sub
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sent[ix] = subscriber.send(message)
+		}()
+	}
+
+	wg.Wait()
+
+	delivered := true
+	for i, sub := range matched {
+		if !sent[i] {
+			sub.guard.Lock()
+			sub.dropped++
+			sub.guard.Unlock()
+
+			if sub.delivery.Synchronous {
+				delivered = false
+			}
+		}
+	}
+
+	// ... remember which subscribers accepted an event that will be delivered again so that
+	//     only the subscribers that rejected it get it again
+	for i, sub := range matched {
+		sub.accept(message.Event, sent[i] && !delivered)
+	}
+
+	for _, sub := range skipped {
+		sub.accept(message.Event, !delivered)
+	}
+
+	return delivered
+}
+
+// Queues the event for the subscriber, waiting for space in the queue according to the delivery
+// policy. Returns false if the event was dropped. An event for a subscriber that has been
+// unsubscribed is discarded but not counted as dropped.
+func (sub *subscriber) send(message EventMessage) bool {
+	sub.sending.RLock()
+	defer sub.sending.RUnlock()
+
+	select {
+	case <-sub.stop:
+		return true
+	default:
+	}
+
+	if sub.delivery.Synchronous {
+		return sub.handler(message)
+	}
+
+	select {
+	case sub.queue <- message:
+		return true
+	default:
+	}
+
+	var timeout <-chan time.Time
+	switch {
+	case sub.delivery.Block:
+	case sub.delivery.Wait > 0:
+		timer := time.NewTimer(sub.delivery.Wait)
+		defer timer.Stop()
+		timeout = timer.C
+	default:
+		return false
+	}
+
+	select {
+	case sub.queue <- message:
+		return true
+	case <-sub.stop:
+		return true
+	case <-timeout:
+		return false
+	}
+}
+
+// Stops queueing events for the subscriber (releasing any blocked senders) and closes the queue
+// once there are no senders so that the queued events are delivered.
+func (sub *subscriber) close() {
+	close(sub.stop)
+
+	sub.sending.Lock()
+	close(sub.queue)
+	sub.sending.Unlock()
+}
+
+func (sub *subscriber) isAccepted(e ListenEvent) bool {
+	sub.guard.Lock()
+	defer sub.guard.Unlock()
+
+	eventID, ok := sub.accepted[e.DeviceID]
+
+	return ok && eventID == e.EventID
+}
+
+func (sub *subscriber) accept(e ListenEvent, pending bool) {
+	sub.guard.Lock()
+	defer sub.guard.Unlock()
+
+	if pending {
+		sub.accepted[e.DeviceID] = e.EventID
+	} else {
+		delete(sub.accepted, e.DeviceID)
+	}
+}
+
+func (sub *subscriber) run() {
+	defer close(sub.done)
+
+	for message := range sub.queue {
+		sub.handler(message)
+	}
+}
+
+func (f Filter) match(e ListenEvent) bool {
+	if len(f.Devices) > 0 && !contains(len(f.Devices), func(i int) bool { return f.Devices[i] == uint32(e.DeviceID) }) {
+		return false
+	}
+
+	if len(f.Doors) > 0 && !contains(len(f.Doors), func(i int) bool { return f.Doors[i] == e.Door }) {
+		return false
+	}
+
+	if len(f.Cards) > 0 && !contains(len(f.Cards), func(i int) bool { return f.Cards[i] == e.CardNumber }) {
+		return false
+	}
+
+	if f.Granted != nil && e.Granted != *f.Granted {
+		return false
+	}
+
+	if len(f.Types) > 0 && !contains(len(f.Types), func(i int) bool { return f.Types[i] == e.Type }) {
+		return false
+	}
+
+	if f.Start != nil || f.End != nil {
+		t := types.HHmmFromTime(time.Time(e.Timestamp))
+
+		switch {
+		case f.Start != nil && f.End != nil && f.End.Before(*f.Start):
+			if t.Before(*f.Start) && t.After(*f.End) {
+				return false
+			}
+
+		case f.Start != nil && t.Before(*f.Start):
+			return false
+
+		case f.End != nil && t.After(*f.End):
+			return false
+		}
+	}
+
+	return true
+}
+
+func contains(N int, f func(int) bool) bool {
+	for i := 0; i < N; i++ {
+		if f(i) {
+			return true
+		}
+	}
+
+	return false
+}
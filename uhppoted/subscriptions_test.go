@@ -0,0 +1,224 @@
+package uhppoted
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/uhppoted/uhppote-core/types"
+)
+
+func TestFilter(t *testing.T) {
+	granted := true
+	start := types.NewHHmm(8, 30)
+	end := types.NewHHmm(17, 0)
+	night := types.NewHHmm(22, 0)
+	morning := types.NewHHmm(6, 0)
+
+	event := ListenEvent{
+		DeviceID:   405419896,
+		EventID:    17,
+		Type:       1,
+		Granted:    true,
+		Door:       3,
+		CardNumber: 8165538,
+		Timestamp:  types.DateTime(time.Date(2021, time.March, 1, 12, 30, 0, 0, time.Local)),
+		Reason:     1,
+	}
+
+	vector := []struct {
+		filter   Filter
+		expected bool
+	}{
+		{Filter{}, true},
+		{Filter{Devices: []uint32{303986753, 405419896}}, true},
+		{Filter{Devices: []uint32{303986753}}, false},
+		{Filter{Doors: []uint8{1, 2}}, false},
+		{Filter{Cards: []uint32{8165538}}, true},
+		{Filter{Granted: &granted}, true},
+		{Filter{Types: []uint8{2, 3}}, false},
+		{Filter{Start: &start, End: &end}, true},
+		{Filter{Start: &night, End: &morning}, false},
+		{Filter{End: &start}, false},
+	}
+
+	for i, v := range vector {
+		if match := v.filter.match(event); match != v.expected {
+			t.Errorf("Incorrect filter match %v - expected:%v, got:%v", i+1, v.expected, match)
+		}
+	}
+}
+
+func TestSubscriptionsWithSlowSubscriber(t *testing.T) {
+	s := NewSubscriptions()
+
+	guard := sync.Mutex{}
+	received := []uint32{}
+	blocked := make(chan struct{})
+
+	fast := s.Subscribe(Filter{Doors: []uint8{1}}, func(m EventMessage) bool {
+		guard.Lock()
+		defer guard.Unlock()
+
+		received = append(received, m.Event.EventID)
+		return true
+	}, 0)
+
+	slow := s.Subscribe(Filter{}, func(m EventMessage) bool {
+		<-blocked
+		return true
+	}, 1)
+
+	handler := s.Handler()
+	handled := 0
+	for i := 1; i <= 10; i++ {
+		if handler(EventMessage{Event: ListenEvent{DeviceID: 405419896, EventID: uint32(i), Door: uint8(1 + i%2)}}) {
+			handled++
+		}
+	}
+
+	// ... events dropped for a slow subscriber must not hold back event retrieval
+	if handled != 10 {
+		t.Errorf("Incorrect number of events handled - expected:%v, got:%v", 10, handled)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+
+	guard.Lock()
+	if expected := []uint32{2, 4, 6, 8, 10}; !reflect.DeepEqual(received, expected) {
+		t.Errorf("Incorrect events delivered to subscriber - expected:%v, got:%v", expected, received)
+	}
+	guard.Unlock()
+
+	// ... 1 event queued and (possibly) 1 event blocked in the handler
+	if dropped := s.Dropped(slow); dropped < 8 || dropped > 9 {
+		t.Errorf("Incorrect dropped count for slow subscriber - expected:%v, got:%v", "8 or 9", dropped)
+	}
+
+	if dropped := s.Dropped(fast); dropped != 0 {
+		t.Errorf("Incorrect dropped count for subscriber - expected:%v, got:%v", 0, dropped)
+	}
+
+	close(blocked)
+	s.Close()
+}
+
+func TestSubscriptionsWithBoundedWait(t *testing.T) {
+	s := NewSubscriptions()
+	blocked := make(chan struct{})
+
+	id := s.SubscribeWithDelivery(Filter{}, func(m EventMessage) bool {
+		<-blocked
+		return true
+	}, DeliveryPolicy{QueueSize: 1, Wait: 10 * time.Millisecond})
+
+	handler := s.Handler()
+	handler(EventMessage{Event: ListenEvent{EventID: 1}})
+	handler(EventMessage{Event: ListenEvent{EventID: 2}})
+
+	start := time.Now()
+	handler(EventMessage{Event: ListenEvent{EventID: 3}})
+
+	if dt := time.Since(start); dt < 10*time.Millisecond {
+		t.Errorf("Event dropped without waiting for delivery - waited:%v", dt)
+	}
+
+	if dropped := s.Dropped(id); dropped != 1 {
+		t.Errorf("Incorrect dropped count for subscriber - expected:%v, got:%v", 1, dropped)
+	}
+
+	close(blocked)
+
+	handler(EventMessage{Event: ListenEvent{EventID: 4}})
+
+	if dropped := s.Dropped(id); dropped != 1 {
+		t.Errorf("Event not delivered once subscriber queue has space - dropped:%v", dropped)
+	}
+
+	s.Close()
+}
+
+func TestSubscriptionsWithBlockingDelivery(t *testing.T) {
+	s := NewSubscriptions()
+
+	guard := sync.Mutex{}
+	received := []uint32{}
+
+	id := s.SubscribeWithDelivery(Filter{}, func(m EventMessage) bool {
+		time.Sleep(time.Millisecond)
+
+		guard.Lock()
+		defer guard.Unlock()
+
+		received = append(received, m.Event.EventID)
+		return true
+	}, DeliveryPolicy{QueueSize: 1, Block: true})
+
+	handler := s.Handler()
+	for i := 1; i <= 10; i++ {
+		if !handler(EventMessage{Event: ListenEvent{EventID: uint32(i)}}) {
+			t.Errorf("Event %v not delivered to blocking subscriber", i)
+		}
+	}
+
+	if dropped := s.Dropped(id); dropped != 0 {
+		t.Errorf("Incorrect dropped count for blocking subscriber - expected:%v, got:%v", 0, dropped)
+	}
+
+	s.Close()
+
+	guard.Lock()
+	if len(received) != 10 {
+		t.Errorf("Incorrect number of events delivered - expected:%v, got:%v", 10, len(received))
+	}
+	guard.Unlock()
+}
+
+func TestSubscriptionsWithRejectedEvent(t *testing.T) {
+	s := NewSubscriptions()
+
+	guard := sync.Mutex{}
+	received := []uint32{}
+	rejected := 0
+
+	s.SubscribeWithDelivery(Filter{}, func(m EventMessage) bool {
+		guard.Lock()
+		defer guard.Unlock()
+
+		received = append(received, m.Event.EventID)
+		return true
+	}, DeliveryPolicy{Synchronous: true})
+
+	s.SubscribeWithDelivery(Filter{}, func(m EventMessage) bool {
+		guard.Lock()
+		defer guard.Unlock()
+
+		if m.Event.EventID == 2 && rejected == 0 {
+			rejected++
+			return false
+		}
+
+		return true
+	}, DeliveryPolicy{Synchronous: true})
+
+	handler := s.Handler()
+	events := []uint32{1, 2, 2, 3}
+	acknowledged := []bool{}
+	for _, eventID := range events {
+		acknowledged = append(acknowledged, handler(EventMessage{Event: ListenEvent{DeviceID: 405419896, EventID: eventID}}))
+	}
+
+	if expected := []bool{true, false, true, true}; !reflect.DeepEqual(acknowledged, expected) {
+		t.Errorf("Incorrect acknowledgements - expected:%v, got:%v", expected, acknowledged)
+	}
+
+	// ... redelivered event should only be delivered to the subscriber that rejected it
+	guard.Lock()
+	if expected := []uint32{1, 2, 3}; !reflect.DeepEqual(received, expected) {
+		t.Errorf("Incorrect events delivered to subscriber - expected:%v, got:%v", expected, received)
+	}
+	guard.Unlock()
+
+	s.Close()
+}
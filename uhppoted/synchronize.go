@@ -0,0 +1,193 @@
+package uhppoted
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/uhppoted/uhppote-core/types"
+	"github.com/uhppoted/uhppoted-api/internal/wallclock"
+	"github.com/uhppoted/uhppoted-api/logging"
+)
+
+// SynchronizeTimeRequest sets the controllers to the current time in their configured time zones.
+// Devices defaults to all the controllers in the device list and controllers with a clock
+// within Tolerance of the current time are left unchanged.
+type SynchronizeTimeRequest struct {
+	Devices   []DeviceID
+	Tolerance time.Duration
+}
+
+type SynchronizeTimeResponse struct {
+	Devices []TimeDrift `json:"devices"`
+	Retries uint64      `json:"retries,omitempty"`
+}
+
+// TimeDrift reports the controller clock offset from the current time in the controller time
+// zone before the synchronization, and the controller time afterwards.
+type TimeDrift struct {
+	DeviceID     DeviceID       `json:"device-id"`
+	TimeZone     string         `json:"timezone"`
+	DateTime     types.DateTime `json:"date-time"`
+	Drift        time.Duration  `json:"drift"`
+	Synchronized bool           `json:"synchronized"`
+	Error        string         `json:"error,omitempty"`
+}
+
+func (u *UHPPOTED) SynchronizeTime(request SynchronizeTimeRequest) (*SynchronizeTimeResponse, error) {
+	return u.SynchronizeTimeWithContext(context.Background(), request)
+}
+
+func (u *UHPPOTED) SynchronizeTimeWithContext(ctx context.Context, request SynchronizeTimeRequest) (*SynchronizeTimeResponse, error) {
+	u.debug("synchronize-time", fmt.Sprintf("request  %+v", request))
+
+	u, retries := u.track()
+
+	devices := u.UHPPOTE.DeviceList()
+	list := []uint32{}
+
+	if len(request.Devices) > 0 {
+		for _, id := range request.Devices {
+			list = append(list, uint32(id))
+		}
+	} else {
+		for id := range devices {
+			list = append(list, id)
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i] < list[j] })
+
+	response := SynchronizeTimeResponse{
+		Devices: []TimeDrift{},
+	}
+
+	for _, id := range list {
+		if err := cancelled(ctx); err != nil {
+			return nil, err
+		}
+
+		tz := time.Local
+		if d, ok := devices[id]; ok && d.TimeZone != nil {
+			tz = d.TimeZone
+		}
+
+		drift, err := u.synchronize(id, tz, request.Tolerance)
+		if err != nil {
+			u.warn("synchronize-time", err, logging.DeviceID(id))
+			drift.Error = err.Error()
+		} else if drift.Synchronized {
+			u.info("synchronize-time", fmt.Sprintf("synchronized time (drift %v)", drift.Drift), logging.DeviceID(id))
+		}
+
+		response.Devices = append(response.Devices, drift)
+	}
+
+	response.Retries = retries()
+
+	u.debug("synchronize-time", fmt.Sprintf("response %+v", response))
+
+	return &response, nil
+}
+
+// Synchronizes the controller times every 'interval' and immediately after a daylight savings (or
+// other UTC offset) transition in any of the controller time zones, until the context is cancelled.
+// The result of each synchronization is passed to 'f'. Returns a BadRequest error if the
+// interval is not positive.
+func (u *UHPPOTED) SynchronizeTimeOnSchedule(ctx context.Context, interval time.Duration, request SynchronizeTimeRequest, f func(*SynchronizeTimeResponse, error)) error {
+	if interval <= 0 {
+		return badRequest("synchronize-time", 0, fmt.Errorf("Invalid synchronization interval (%v)", interval))
+	}
+
+	for {
+		response, err := u.SynchronizeTimeWithContext(ctx, request)
+		if f != nil {
+			f(response, err)
+		}
+
+		now := time.Now()
+		next := now.Add(interval)
+		for _, d := range u.UHPPOTE.DeviceList() {
+			if d.TimeZone != nil {
+				if t, ok := nextTransition(d.TimeZone, now, next); ok {
+					next = t.Add(time.Second)
+				}
+			}
+		}
+
+		timer := time.NewTimer(time.Until(next))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+
+		case <-timer.C:
+		}
+	}
+}
+
+// The controller clock has no time zone and uhppote-core decodes the controller date/time as
+// local time, so the drift is calculated by comparing the controller 'wall clock' with the
+// current time in the controller time zone.
+func (u *UHPPOTED) synchronize(deviceID uint32, tz *time.Location, tolerance time.Duration) (TimeDrift, error) {
+	drift := TimeDrift{
+		DeviceID: DeviceID(deviceID),
+		TimeZone: tz.String(),
+	}
+
+	current, err := u.UHPPOTE.GetTime(deviceID)
+	if err != nil {
+		return drift, internalError("synchronize-time", deviceID, fmt.Errorf("Error getting time for %v (%w)", deviceID, err))
+	}
+
+	now := time.Now().In(tz)
+	drift.DateTime = current.DateTime
+	drift.Drift = wallclock.In(time.Time(current.DateTime), tz).Sub(now).Round(time.Second)
+
+	if drift.Drift <= tolerance && drift.Drift >= -tolerance {
+		return drift, nil
+	}
+
+	result, err := u.UHPPOTE.SetTime(deviceID, time.Now().In(tz))
+	if err != nil {
+		return drift, internalError("synchronize-time", deviceID, fmt.Errorf("Error setting time for %v (%w)", deviceID, err))
+	}
+
+	drift.DateTime = result.DateTime
+	drift.Synchronized = true
+
+	return drift, nil
+}
+
+// Returns the first change in UTC offset for the location in the interval (from, until], to
+// the nearest second.
+func nextTransition(tz *time.Location, from, until time.Time) (time.Time, bool) {
+	_, offset := from.In(tz).Zone()
+
+	for t := from; t.Before(until); {
+		next := t.Add(time.Hour)
+		if next.After(until) {
+			next = until
+		}
+
+		if _, o := next.In(tz).Zone(); o != offset {
+			lo, hi := t, next
+			for hi.Sub(lo) > time.Second {
+				mid := lo.Add(hi.Sub(lo) / 2)
+				if _, o := mid.In(tz).Zone(); o != offset {
+					hi = mid
+				} else {
+					lo = mid
+				}
+			}
+
+			return hi, true
+		}
+
+		t = next
+	}
+
+	return time.Time{}, false
+}
@@ -0,0 +1,102 @@
+package uhppoted
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/uhppoted/uhppoted-api/internal/wallclock"
+	"github.com/uhppoted/uhppoted-api/simulator"
+)
+
+func TestSynchronizeTime(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skipf("Time zone database not available (%v)", err)
+	}
+
+	d1 := simulator.NewDevice(405419896, net.IPv4(192, 168, 1, 125))
+	d1.TimeZone = "Asia/Tokyo"
+	d2 := simulator.NewDevice(303986753, net.IPv4(192, 168, 1, 126))
+	d3 := simulator.NewDevice(201020304, net.IPv4(192, 168, 1, 127))
+
+	s := simulator.NewSimulator(nil, d1, d2, d3)
+	s.SetTime(405419896, time.Now().In(tokyo).Add(5*time.Minute))
+	s.SetTime(303986753, time.Now().Add(2*time.Second))
+	s.Inject(simulator.Fault{Type: simulator.Timeout, DeviceID: 201020304, Operation: "get-time"})
+
+	u := UHPPOTED{
+		UHPPOTE: s,
+	}
+
+	response, err := u.SynchronizeTime(SynchronizeTimeRequest{Tolerance: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(response.Devices) != 3 {
+		t.Fatalf("Incorrect number of devices - expected:%v, got:%v", 3, len(response.Devices))
+	}
+
+	drifts := map[DeviceID]TimeDrift{}
+	for _, d := range response.Devices {
+		drifts[d.DeviceID] = d
+	}
+
+	if d := drifts[405419896]; !d.Synchronized || d.TimeZone != "Asia/Tokyo" || d.Drift < 298*time.Second || d.Drift > 302*time.Second {
+		t.Errorf("Incorrect drift for %v - got:%+v", 405419896, d)
+	}
+
+	if d := drifts[303986753]; d.Synchronized || d.Error != "" {
+		t.Errorf("Incorrect drift for %v - got:%+v", 303986753, d)
+	}
+
+	if d := drifts[201020304]; d.Synchronized || d.Error == "" {
+		t.Errorf("Incorrect drift for %v - got:%+v", 201020304, d)
+	}
+
+	// ... controller clock should now be the 'wall clock' time in Tokyo
+	current, _ := s.GetTime(405419896)
+	if dt := wallclock.In(time.Time(current.DateTime), tokyo).Sub(time.Now()); dt < -2*time.Second || dt > 2*time.Second {
+		t.Errorf("Controller time not synchronized to time zone - offset:%v", dt)
+	}
+}
+
+func TestNextTransition(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("Time zone database not available (%v)", err)
+	}
+
+	from := time.Date(2021, time.March, 13, 12, 0, 0, 0, time.UTC)
+	expected := time.Date(2021, time.March, 14, 7, 0, 0, 0, time.UTC)
+
+	if next, ok := nextTransition(ny, from, from.Add(48*time.Hour)); !ok || !next.Equal(expected) {
+		t.Errorf("Incorrect DST transition - expected:%v, got:%v", expected, next)
+	}
+
+	if next, ok := nextTransition(ny, from, from.Add(12*time.Hour)); ok {
+		t.Errorf("Unexpected DST transition - got:%v", next)
+	}
+
+	if next, ok := nextTransition(time.UTC, from, from.Add(48*time.Hour)); ok {
+		t.Errorf("Unexpected DST transition - got:%v", next)
+	}
+}
+
+func TestSynchronizeTimeOnScheduleWithInvalidInterval(t *testing.T) {
+	s := simulator.NewSimulator(nil, simulator.NewDevice(405419896, net.IPv4(192, 168, 1, 125)))
+	u := UHPPOTED{
+		UHPPOTE: s,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	err := u.SynchronizeTimeOnSchedule(ctx, 0, SynchronizeTimeRequest{}, nil)
+	if err == nil || !errors.Is(err, BadRequest) {
+		t.Errorf("Expected BadRequest error, got:%v", err)
+	}
+}
@@ -1,6 +1,7 @@
 package uhppoted
 
 import (
+	"context"
 	"fmt"
 	"github.com/uhppoted/uhppote-core/types"
 	"time"
@@ -13,15 +14,26 @@ type GetTimeRequest struct {
 type GetTimeResponse struct {
 	DeviceID DeviceID       `json:"device-id"`
 	DateTime types.DateTime `json:"date-time"`
+	Retries  uint64         `json:"retries,omitempty"`
 }
 
 func (u *UHPPOTED) GetTime(request GetTimeRequest) (*GetTimeResponse, error) {
+	return u.GetTimeWithContext(context.Background(), request)
+}
+
+func (u *UHPPOTED) GetTimeWithContext(ctx context.Context, request GetTimeRequest) (*GetTimeResponse, error) {
 	u.debug("get-time", fmt.Sprintf("request  %+v", request))
 
+	u, retries := u.track()
+
 	device := uint32(request.DeviceID)
+	if err := cancelled(ctx); err != nil {
+		return nil, err
+	}
+
 	result, err := u.UHPPOTE.GetTime(device)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", InternalServerError, fmt.Errorf("Error getting time for %v (%w)", device, err))
+		return nil, internalError("get-time", device, fmt.Errorf("Error getting time for %v (%w)", device, err))
 	}
 
 	response := GetTimeResponse{
@@ -29,6 +41,8 @@ func (u *UHPPOTED) GetTime(request GetTimeRequest) (*GetTimeResponse, error) {
 		DateTime: result.DateTime,
 	}
 
+	response.Retries = retries()
+
 	u.debug("get-time", fmt.Sprintf("response %+v", response))
 
 	return &response, nil
@@ -42,15 +56,26 @@ type SetTimeRequest struct {
 type SetTimeResponse struct {
 	DeviceID DeviceID       `json:"device-id"`
 	DateTime types.DateTime `json:"date-time"`
+	Retries  uint64         `json:"retries,omitempty"`
 }
 
 func (u *UHPPOTED) SetTime(request SetTimeRequest) (*SetTimeResponse, error) {
+	return u.SetTimeWithContext(context.Background(), request)
+}
+
+func (u *UHPPOTED) SetTimeWithContext(ctx context.Context, request SetTimeRequest) (*SetTimeResponse, error) {
 	u.debug("set-time", fmt.Sprintf("request  %+v", request))
 
+	u, retries := u.track()
+
 	device := uint32(request.DeviceID)
+	if err := cancelled(ctx); err != nil {
+		return nil, err
+	}
+
 	result, err := u.UHPPOTE.SetTime(device, time.Time(request.DateTime))
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", InternalServerError, fmt.Errorf("Error setting time for %v (%w)", device, err))
+		return nil, internalError("set-time", device, fmt.Errorf("Error setting time for %v (%w)", device, err))
 	}
 
 	response := SetTimeResponse{
@@ -58,6 +83,8 @@ func (u *UHPPOTED) SetTime(request SetTimeRequest) (*SetTimeResponse, error) {
 		DateTime: result.DateTime,
 	}
 
+	response.Retries = retries()
+
 	u.debug("set-time", fmt.Sprintf("response %+v", response))
 
 	return &response, nil
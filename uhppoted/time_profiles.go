@@ -1,8 +1,9 @@
 package uhppoted
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"net/http"
 	"reflect"
 
 	"github.com/uhppoted/uhppote-core/types"
@@ -17,11 +18,18 @@ type GetTimeProfilesRequest struct {
 type GetTimeProfilesResponse struct {
 	DeviceID DeviceID            `json:"device-id"`
 	Profiles []types.TimeProfile `json:"profiles"`
+	Retries  uint64              `json:"retries,omitempty"`
 }
 
 func (u *UHPPOTED) GetTimeProfiles(request GetTimeProfilesRequest) (*GetTimeProfilesResponse, error) {
+	return u.GetTimeProfilesWithContext(context.Background(), request)
+}
+
+func (u *UHPPOTED) GetTimeProfilesWithContext(ctx context.Context, request GetTimeProfilesRequest) (*GetTimeProfilesResponse, error) {
 	u.debug("get-time-profiles", fmt.Sprintf("request  %+v", request))
 
+	u, retries := u.track()
+
 	deviceID := request.DeviceID
 	from := 2
 	to := 254
@@ -37,9 +45,13 @@ func (u *UHPPOTED) GetTimeProfiles(request GetTimeProfilesRequest) (*GetTimeProf
 	profiles := []types.TimeProfile{}
 
 	for i := from; i <= to; i++ {
+		if err := cancelled(ctx); err != nil {
+			return nil, err
+		}
+
 		profile, err := u.UHPPOTE.GetTimeProfile(deviceID, uint8(i))
 		if err != nil {
-			return nil, fmt.Errorf("%w: %v", InternalServerError, fmt.Errorf("Error retrieving time profile %v from %v (%w)", i, deviceID, err))
+			return nil, internalError("get-time-profiles", deviceID, fmt.Errorf("Error retrieving time profile %v from %v (%w)", i, deviceID, err)).withProfile(uint8(i))
 		}
 
 		if profile != nil {
@@ -52,6 +64,8 @@ func (u *UHPPOTED) GetTimeProfiles(request GetTimeProfilesRequest) (*GetTimeProf
 		Profiles: profiles,
 	}
 
+	response.Retries = retries()
+
 	u.debug("get-time-profiles", fmt.Sprintf("response %+v", response))
 
 	return &response, nil
@@ -65,11 +79,18 @@ type PutTimeProfilesRequest struct {
 type PutTimeProfilesResponse struct {
 	DeviceID DeviceID `json:"device-id"`
 	Warnings []error  `json:"warnings"`
+	Retries  uint64   `json:"retries,omitempty"`
+}
+
+func (u *UHPPOTED) PutTimeProfiles(request PutTimeProfilesRequest) (*PutTimeProfilesResponse, error) {
+	return u.PutTimeProfilesWithContext(context.Background(), request)
 }
 
-func (u *UHPPOTED) PutTimeProfiles(request PutTimeProfilesRequest) (*PutTimeProfilesResponse, int, error) {
+func (u *UHPPOTED) PutTimeProfilesWithContext(ctx context.Context, request PutTimeProfilesRequest) (*PutTimeProfilesResponse, error) {
 	u.debug("put-time-profiles", fmt.Sprintf("request  %+v", request))
 
+	u, retries := u.track()
+
 	deviceID := request.DeviceID
 	profiles := request.Profiles
 
@@ -80,7 +101,7 @@ func (u *UHPPOTED) PutTimeProfiles(request PutTimeProfilesRequest) (*PutTimeProf
 	for i, profile := range profiles {
 		if index, ok := set[profile.ID]; ok {
 			if !reflect.DeepEqual(profile, profiles[index-1]) {
-				return nil, http.StatusBadRequest, fmt.Errorf("Profile %v has more than one definition (records %v and %v)", profile.ID, index, i+1)
+				return nil, badRequest("put-time-profiles", deviceID, fmt.Errorf("Profile %v has more than one definition (records %v and %v)", profile.ID, index, i+1)).withProfile(profile.ID)
 			}
 
 			prewarn = append(prewarn, fmt.Errorf("Profile %-3v is defined twice (records %v and %v)", profile.ID, index, i+1))
@@ -114,8 +135,12 @@ func (u *UHPPOTED) PutTimeProfiles(request PutTimeProfilesRequest) (*PutTimeProf
 
 			// verify linked profile exists
 			if linked := profile.LinkedProfileID; linked != 0 {
+				if err := cancelled(ctx); err != nil {
+					return nil, err
+				}
+
 				if p, err := u.UHPPOTE.GetTimeProfile(deviceID, linked); err != nil {
-					return nil, http.StatusInternalServerError, err
+					return nil, internalError("put-time-profiles", deviceID, fmt.Errorf("Error retrieving time profile %v from %v (%w)", linked, deviceID, err)).withProfile(profile.ID)
 				} else if p == nil {
 					warnings = append(warnings, fmt.Errorf("profile %-3v: linked time profile %v is not defined", profile.ID, linked))
 					continue
@@ -123,14 +148,20 @@ func (u *UHPPOTED) PutTimeProfiles(request PutTimeProfilesRequest) (*PutTimeProf
 			}
 
 			// check for circular references
-			if err := circularReference(u, deviceID, profile); err != nil {
+			if err := circularReference(ctx, u, deviceID, profile); errors.Is(err, Cancelled) || errors.Is(err, InternalServerError) {
+				return nil, err
+			} else if err != nil {
 				warnings = append(warnings, fmt.Errorf("profile %-3v: %v", profile.ID, err))
 				continue
 			}
 
 			// good to go!
+			if err := cancelled(ctx); err != nil {
+				return nil, err
+			}
+
 			if ok, err := u.UHPPOTE.SetTimeProfile(deviceID, profile); err != nil {
-				return nil, http.StatusInternalServerError, err
+				return nil, internalError("put-time-profiles", deviceID, fmt.Errorf("Error writing time profile %v to %v (%w)", profile.ID, deviceID, err)).withProfile(profile.ID)
 			} else if !ok {
 				warnings = append(warnings, fmt.Errorf("%v: could not create time profile %v", deviceID, profile.ID))
 			} else {
@@ -152,9 +183,11 @@ func (u *UHPPOTED) PutTimeProfiles(request PutTimeProfilesRequest) (*PutTimeProf
 		Warnings: warnings,
 	}
 
+	response.Retries = retries()
+
 	u.debug("put-time-profiles", fmt.Sprintf("response %+v", response))
 
-	return &response, http.StatusOK, nil
+	return &response, nil
 }
 
 type GetTimeProfileRequest struct {
@@ -165,21 +198,32 @@ type GetTimeProfileRequest struct {
 type GetTimeProfileResponse struct {
 	DeviceID    DeviceID          `json:"device-id"`
 	TimeProfile types.TimeProfile `json:"time-profile"`
+	Retries     uint64            `json:"retries,omitempty"`
 }
 
 func (u *UHPPOTED) GetTimeProfile(request GetTimeProfileRequest) (*GetTimeProfileResponse, error) {
+	return u.GetTimeProfileWithContext(context.Background(), request)
+}
+
+func (u *UHPPOTED) GetTimeProfileWithContext(ctx context.Context, request GetTimeProfileRequest) (*GetTimeProfileResponse, error) {
 	u.debug("get-time-profile", fmt.Sprintf("request  %+v", request))
 
+	u, retries := u.track()
+
 	deviceID := request.DeviceID
 	profileID := request.ProfileID
 
+	if err := cancelled(ctx); err != nil {
+		return nil, err
+	}
+
 	profile, err := u.UHPPOTE.GetTimeProfile(deviceID, profileID)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", InternalServerError, fmt.Errorf("Error retrieving time profile %v from %v (%w)", profileID, deviceID, err))
+		return nil, internalError("get-time-profile", deviceID, fmt.Errorf("Error retrieving time profile %v from %v (%w)", profileID, deviceID, err)).withProfile(profileID)
 	}
 
 	if profile == nil {
-		return nil, fmt.Errorf("%w: %v", NotFound, fmt.Errorf("Error retrieving time profile %v from %v", profileID, deviceID))
+		return nil, notFound("get-time-profile", deviceID, fmt.Errorf("Error retrieving time profile %v from %v", profileID, deviceID)).withProfile(profileID)
 	}
 
 	response := GetTimeProfileResponse{
@@ -187,6 +231,8 @@ func (u *UHPPOTED) GetTimeProfile(request GetTimeProfileRequest) (*GetTimeProfil
 		TimeProfile: *profile,
 	}
 
+	response.Retries = retries()
+
 	u.debug("get-time-profile", fmt.Sprintf("response %+v", response))
 
 	return &response, nil
@@ -200,41 +246,56 @@ type PutTimeProfileRequest struct {
 type PutTimeProfileResponse struct {
 	DeviceID    DeviceID          `json:"device-id"`
 	TimeProfile types.TimeProfile `json:"time-profile"`
+	Retries     uint64            `json:"retries,omitempty"`
 }
 
 func (u *UHPPOTED) PutTimeProfile(request PutTimeProfileRequest) (*PutTimeProfileResponse, error) {
+	return u.PutTimeProfileWithContext(context.Background(), request)
+}
+
+func (u *UHPPOTED) PutTimeProfileWithContext(ctx context.Context, request PutTimeProfileRequest) (*PutTimeProfileResponse, error) {
 	u.debug("put-time-profile", fmt.Sprintf("request  %+v", request))
 
+	u, retries := u.track()
+
 	deviceID := request.DeviceID
 	profile := request.TimeProfile
 	linked := profile.LinkedProfileID
 
 	if profile.ID < 2 || profile.ID > 254 {
-		return nil, fmt.Errorf("Invalid time profile ID (%v) - valid range is [1..254]", profile.ID)
+		return nil, badRequest("put-time-profile", deviceID, fmt.Errorf("Invalid time profile ID (%v) - valid range is [1..254]", profile.ID)).withProfile(profile.ID)
 	}
 
 	if linked != 0 {
 		if linked == profile.ID {
-			return nil, fmt.Errorf("Link to self creates circular reference")
+			return nil, badRequest("put-time-profile", deviceID, fmt.Errorf("Link to self creates circular reference")).withProfile(profile.ID)
 		}
 
-		if p, err := u.UHPPOTE.GetTimeProfile(deviceID, linked); err != nil {
+		if err := cancelled(ctx); err != nil {
 			return nil, err
+		}
+
+		if p, err := u.UHPPOTE.GetTimeProfile(deviceID, linked); err != nil {
+			return nil, internalError("put-time-profile", deviceID, fmt.Errorf("Error retrieving time profile %v from %v (%w)", linked, deviceID, err)).withProfile(profile.ID)
 		} else if p == nil {
-			return nil, fmt.Errorf("Linked time profile %v is not defined", linked)
+			return nil, badRequest("put-time-profile", deviceID, fmt.Errorf("Linked time profile %v is not defined", linked)).withProfile(profile.ID)
 		}
 
 		profiles := map[uint8]bool{profile.ID: true}
 		links := []uint8{profile.ID}
 		for l := linked; l != 0; {
-			if p, err := u.UHPPOTE.GetTimeProfile(deviceID, l); err != nil {
+			if err := cancelled(ctx); err != nil {
 				return nil, err
+			}
+
+			if p, err := u.UHPPOTE.GetTimeProfile(deviceID, l); err != nil {
+				return nil, internalError("put-time-profile", deviceID, fmt.Errorf("Error retrieving time profile %v from %v (%w)", l, deviceID, err)).withProfile(profile.ID)
 			} else if p == nil {
-				return nil, fmt.Errorf("Linked time profile %v is not defined", l)
+				return nil, badRequest("put-time-profile", deviceID, fmt.Errorf("Linked time profile %v is not defined", l)).withProfile(profile.ID)
 			} else {
 				links = append(links, p.ID)
 				if profiles[p.ID] {
-					return nil, fmt.Errorf("Linking to time profile %v creates a circular reference (%v)", linked, links)
+					return nil, badRequest("put-time-profile", deviceID, fmt.Errorf("Linking to time profile %v creates a circular reference (%v)", linked, links)).withProfile(profile.ID)
 				}
 
 				profiles[p.ID] = true
@@ -243,13 +304,17 @@ func (u *UHPPOTED) PutTimeProfile(request PutTimeProfileRequest) (*PutTimeProfil
 		}
 	}
 
+	if err := cancelled(ctx); err != nil {
+		return nil, err
+	}
+
 	ok, err := u.UHPPOTE.SetTimeProfile(deviceID, profile)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", InternalServerError, fmt.Errorf("Error writing time profile %v to %v (%w)", profile.ID, deviceID, err))
+		return nil, internalError("put-time-profile", deviceID, fmt.Errorf("Error writing time profile %v to %v (%w)", profile.ID, deviceID, err)).withProfile(profile.ID)
 	}
 
 	if !ok {
-		return nil, fmt.Errorf("%w: %v", InternalServerError, fmt.Errorf("Failed to write time profile %v to %v", profile.ID, deviceID))
+		return nil, internalError("put-time-profile", deviceID, fmt.Errorf("Failed to write time profile %v to %v", profile.ID, deviceID)).withProfile(profile.ID).permanent()
 	}
 
 	response := PutTimeProfileResponse{
@@ -257,6 +322,8 @@ func (u *UHPPOTED) PutTimeProfile(request PutTimeProfileRequest) (*PutTimeProfil
 		TimeProfile: profile,
 	}
 
+	response.Retries = retries()
+
 	u.debug("put-time-profile", fmt.Sprintf("response %+v", response))
 
 	return &response, nil
@@ -269,16 +336,27 @@ type ClearTimeProfilesRequest struct {
 type ClearTimeProfilesResponse struct {
 	DeviceID DeviceID `json:"device-id"`
 	Cleared  bool     `json:"cleared"`
+	Retries  uint64   `json:"retries,omitempty"`
 }
 
 func (u *UHPPOTED) ClearTimeProfiles(request ClearTimeProfilesRequest) (*ClearTimeProfilesResponse, error) {
+	return u.ClearTimeProfilesWithContext(context.Background(), request)
+}
+
+func (u *UHPPOTED) ClearTimeProfilesWithContext(ctx context.Context, request ClearTimeProfilesRequest) (*ClearTimeProfilesResponse, error) {
 	u.debug("clear-time-profiles", fmt.Sprintf("request  %+v", request))
 
+	u, retries := u.track()
+
 	deviceID := request.DeviceID
 
+	if err := cancelled(ctx); err != nil {
+		return nil, err
+	}
+
 	cleared, err := u.UHPPOTE.ClearTimeProfiles(deviceID)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", InternalServerError, fmt.Errorf("Error clearing time profiles from %v (%w)", deviceID, err))
+		return nil, internalError("clear-time-profiles", deviceID, fmt.Errorf("Error clearing time profiles from %v (%w)", deviceID, err))
 	}
 
 	response := ClearTimeProfilesResponse{
@@ -286,6 +364,8 @@ func (u *UHPPOTED) ClearTimeProfiles(request ClearTimeProfilesRequest) (*ClearTi
 		Cleared:  cleared,
 	}
 
+	response.Retries = retries()
+
 	u.debug("clear-time-profiles", fmt.Sprintf("response %+v", response))
 
 	return &response, nil
@@ -315,14 +395,18 @@ func validateTimeProfile(profile types.TimeProfile) error {
 	return nil
 }
 
-func circularReference(u *UHPPOTED, deviceID uint32, profile types.TimeProfile) error {
+func circularReference(ctx context.Context, u *UHPPOTED, deviceID uint32, profile types.TimeProfile) error {
 	if linked := profile.LinkedProfileID; linked != 0 {
 		profiles := map[uint8]bool{profile.ID: true}
 		chain := []uint8{profile.ID}
 
 		for l := linked; l != 0; {
-			if p, err := u.UHPPOTE.GetTimeProfile(deviceID, l); err != nil {
+			if err := cancelled(ctx); err != nil {
 				return err
+			}
+
+			if p, err := u.UHPPOTE.GetTimeProfile(deviceID, l); err != nil {
+				return internalError("put-time-profiles", deviceID, fmt.Errorf("Error retrieving time profile %v from %v (%w)", l, deviceID, err)).withProfile(profile.ID)
 			} else if p == nil {
 				return fmt.Errorf("linked time profile %v is not defined", l)
 			} else {
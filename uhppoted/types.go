@@ -85,3 +85,24 @@ func (index EventIndex) decrement(rollover uint32) EventIndex {
 
 	return EventIndex(ix)
 }
+
+// Returns the number of increments from 'first' to the event index in an event buffer that
+// wraps around at 'rollover'.
+func offset(index EventIndex, first, rollover uint32) uint32 {
+	if ix := uint32(index); ix >= first {
+		return ix - first
+	} else {
+		return rollover - first + ix
+	}
+}
+
+// Returns true if the index lies in the circular event buffer range [first,last].
+func (index EventIndex) in(first, last uint32) bool {
+	ix := uint32(index)
+
+	if last >= first {
+		return ix >= first && ix <= last
+	}
+
+	return ix >= first || ix <= last
+}
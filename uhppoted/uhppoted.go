@@ -1,11 +1,14 @@
 package uhppoted
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"log"
 	"net/http"
 
 	"github.com/uhppoted/uhppote-core/uhppote"
+	"github.com/uhppoted/uhppoted-api/logging"
 )
 
 const (
@@ -21,28 +24,100 @@ var (
 	NotFound            = errors.New("Not Found")
 	Unauthorized        = errors.New("Not Authorized")
 	InternalServerError = errors.New("INTERNAL SERVER ERROR")
+	Cancelled           = errors.New("Cancelled")
 )
 
 type UHPPOTED struct {
 	UHPPOTE         uhppote.IUHPPOTE
 	ListenBatchSize int
+	DecodeEvents    bool
+	EnrichEvents    bool
+	Cardholders     CardholderLookup
 	Log             *log.Logger
+	Logger          logging.Logger // structured logger (takes precedence over Log)
 }
 
-func (u *UHPPOTED) debug(tag string, msg interface{}) {
-	if u != nil && u.Log != nil {
+// RetryTracker is implemented by an IUHPPOTE that retries failed requests (e.g. retry.Retry).
+// Track returns an IUHPPOTE that counts the retries made through it, which is used to report
+// the number of retries for a request in the response.
+type RetryTracker interface {
+	Track() TrackedUHPPOTE
+}
+
+type TrackedUHPPOTE interface {
+	uhppote.IUHPPOTE
+	Retries() uint64
+}
+
+type tracked struct {
+	uhppote.IUHPPOTE
+	retries func() uint64
+}
+
+func (t *tracked) Retries() uint64 {
+	return t.retries()
+}
+
+// Returns a TrackedUHPPOTE for an IUHPPOTE that wraps a RetryTracker (e.g. a scheduler or
+// cache wrapped around a retry.Retry), so that the wrapper can pass retry tracking through. A
+// wrapper around an IUHPPOTE that is not a RetryTracker should pass Untracked as the retries
+// function.
+func Tracked(u uhppote.IUHPPOTE, retries func() uint64) TrackedUHPPOTE {
+	return &tracked{
+		IUHPPOTE: u,
+		retries:  retries,
+	}
+}
+
+// Retry count function for an IUHPPOTE that does not retry requests.
+func Untracked() uint64 {
+	return 0
+}
+
+// Returns a copy of the UHPPOTED that counts the retries for a single request along with the
+// function that returns the retry count (always 0 if the IUHPPOTE is not a RetryTracker).
+func (u *UHPPOTED) track() (*UHPPOTED, func() uint64) {
+	if t, ok := u.UHPPOTE.(RetryTracker); ok {
+		tracked := t.Track()
+		v := *u
+		v.UHPPOTE = tracked
+
+		return &v, tracked.Retries
+	}
+
+	return u, func() uint64 { return 0 }
+}
+
+func (u *UHPPOTED) debug(tag string, msg interface{}, fields ...logging.Field) {
+	if u != nil && u.Logger != nil {
+		u.Logger.Debug(fmt.Sprintf("%v", msg), append([]logging.Field{logging.Operation(tag)}, fields...)...)
+	} else if u != nil && u.Log != nil {
 		u.Log.Printf("DEBUG %-12s %v", tag, msg)
 	}
 }
 
-func (u *UHPPOTED) info(tag string, msg interface{}) {
-	if u != nil && u.Log != nil {
+func (u *UHPPOTED) info(tag string, msg interface{}, fields ...logging.Field) {
+	if u != nil && u.Logger != nil {
+		u.Logger.Info(fmt.Sprintf("%v", msg), append([]logging.Field{logging.Operation(tag)}, fields...)...)
+	} else if u != nil && u.Log != nil {
 		u.Log.Printf("INFO  %-12s %v", tag, msg)
 	}
 }
 
-func (u *UHPPOTED) warn(tag string, err error) {
-	if u != nil && u.Log != nil {
+func (u *UHPPOTED) warn(tag string, err error, fields ...logging.Field) {
+	if u != nil && u.Logger != nil {
+		u.Logger.Warn(fmt.Sprintf("%v", err), append([]logging.Field{logging.Operation(tag)}, fields...)...)
+	} else if u != nil && u.Log != nil {
 		u.Log.Printf("WARN  %-12s %v", tag, err)
 	}
 }
+
+// Returns a Cancelled error wrapping the context error if the context has been cancelled
+// or has timed out, nil otherwise.
+func cancelled(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return &Error{Code: CodeCancelled, Err: err}
+	}
+
+	return nil
+}
@@ -0,0 +1,286 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/uhppoted/uhppoted-api/logging"
+	"github.com/uhppoted/uhppoted-api/uhppoted"
+)
+
+type Format string
+
+const (
+	JSON        Format = "json"
+	CloudEvents Format = "cloudevents"
+)
+
+// HTTP header for the hex encoded HMAC-SHA256 signature of the request body.
+const SignatureHeader = "X-Uhppoted-Signature"
+
+const (
+	TIMEOUT    = 10 * time.Second
+	BACKOFF    = 1 * time.Second
+	QUEUE_SIZE = 256
+)
+
+// Webhook configuration. HMAC is the hex encoded signing key (as for the MQTT HMAC key) -
+// requests are not signed if it is blank. Events that could not be posted to an endpoint
+// after all retries are written to the DLQ dead-letter queue (if configured).
+type Config struct {
+	Endpoints []string
+	Format    Format
+	HMAC      string
+	Timeout   time.Duration
+	Retries   int
+	Backoff   time.Duration
+	QueueSize int
+	DLQ       *uhppoted.DeadLetterQueue
+}
+
+// Event handler that POSTs events to the configured HTTP endpoints. Events are queued and
+// delivered by a background goroutine so that a slow endpoint does not block Listen. Events
+// are acknowledged to Listen once queued, so without a dead-letter queue an event that
+// cannot be posted is logged and discarded.
+type Webhook struct {
+	config  Config
+	key     []byte
+	client  *http.Client
+	queue   chan uhppoted.EventMessage
+	done    chan struct{}
+	closed  bool
+	guard   sync.Mutex
+	log     logging.Logger
+	dropped uint64
+}
+
+type cloudEvent struct {
+	SpecVersion     string                `json:"specversion"`
+	Type            string                `json:"type"`
+	Source          string                `json:"source"`
+	ID              string                `json:"id"`
+	Time            string                `json:"time"`
+	DataContentType string                `json:"datacontenttype"`
+	Data            uhppoted.EventMessage `json:"data"`
+}
+
+func NewWebhook(config Config, log *log.Logger) (*Webhook, error) {
+	return NewWebhookWithLogger(config, logging.NewLogAdapter(log, logging.DEBUG))
+}
+
+// Creates a Webhook that logs delivery errors to a structured logger.
+func NewWebhookWithLogger(config Config, log logging.Logger) (*Webhook, error) {
+	if config.Format == "" {
+		config.Format = JSON
+	}
+
+	if config.Format != JSON && config.Format != CloudEvents {
+		return nil, fmt.Errorf("invalid webhook format '%v'", config.Format)
+	}
+
+	if config.Timeout <= 0 {
+		config.Timeout = TIMEOUT
+	}
+
+	if config.Retries < 0 {
+		config.Retries = 0
+	}
+
+	if config.Backoff <= 0 {
+		config.Backoff = BACKOFF
+	}
+
+	if config.QueueSize <= 0 {
+		config.QueueSize = QUEUE_SIZE
+	}
+
+	key := []byte(nil)
+	if config.HMAC != "" {
+		if k, err := hex.DecodeString(config.HMAC); err != nil {
+			return nil, fmt.Errorf("invalid webhook HMAC key (%v)", err)
+		} else {
+			key = k
+		}
+	}
+
+	w := Webhook{
+		config: config,
+		key:    key,
+		client: &http.Client{Timeout: config.Timeout},
+		queue:  make(chan uhppoted.EventMessage, config.QueueSize),
+		done:   make(chan struct{}),
+		log:    log,
+	}
+
+	go w.run()
+
+	return &w, nil
+}
+
+// Returns an EventHandler for Listen. The handler returns false if the queue is full (or the
+// webhook has been closed) so that Listen does not mark the event as retrieved.
+func (w *Webhook) Handler() uhppoted.EventHandler {
+	return func(message uhppoted.EventMessage) bool {
+		w.guard.Lock()
+		defer w.guard.Unlock()
+
+		if w.closed {
+			return false
+		}
+
+		select {
+		case w.queue <- message:
+			return true
+		default:
+			w.dropped++
+			w.warn(fmt.Errorf("queue full - event %v:%v not queued", message.Event.DeviceID, message.Event.EventID))
+			return false
+		}
+	}
+}
+
+// Returns the number of events rejected because the queue was full.
+func (w *Webhook) Dropped() uint64 {
+	w.guard.Lock()
+	defer w.guard.Unlock()
+
+	return w.dropped
+}
+
+// Stops accepting events and waits for the queued events to be posted.
+func (w *Webhook) Close() {
+	w.guard.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.queue)
+	}
+	w.guard.Unlock()
+
+	<-w.done
+}
+
+func (w *Webhook) run() {
+	defer close(w.done)
+
+	for message := range w.queue {
+		for _, endpoint := range w.config.Endpoints {
+			if err := w.post(endpoint, message); err != nil {
+				w.warn(err)
+				w.deadletter(endpoint, message, err)
+			}
+		}
+	}
+}
+
+func (w *Webhook) post(endpoint string, message uhppoted.EventMessage) error {
+	body, contentType, err := w.format(message)
+	if err != nil {
+		return err
+	}
+
+	backoff := w.config.Backoff
+	for attempt := 0; ; attempt++ {
+		err = w.send(endpoint, body, contentType)
+		if err == nil || attempt >= w.config.Retries {
+			break
+		}
+
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+
+	if err != nil {
+		return fmt.Errorf("%v: failed to post event %v:%v (%w)", endpoint, message.Event.DeviceID, message.Event.EventID, err)
+	}
+
+	return nil
+}
+
+func (w *Webhook) deadletter(endpoint string, message uhppoted.EventMessage, err error) {
+	if w.config.DLQ == nil {
+		return
+	}
+
+	letter := uhppoted.DeadLetter{
+		Subscriber: fmt.Sprintf("webhook:%v", endpoint),
+		Event:      message,
+		Attempts:   w.config.Retries + 1,
+		Error:      fmt.Sprintf("%v", errors.Unwrap(err)),
+		Timestamp:  time.Now(),
+	}
+
+	if err := w.config.DLQ.Put(letter); err != nil {
+		w.warn(fmt.Errorf("%v: error writing event %v:%v to dead-letter queue (%w)", endpoint, message.Event.DeviceID, message.Event.EventID, err))
+	}
+}
+
+func (w *Webhook) send(endpoint string, body []byte, contentType string) error {
+	rq, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	rq.Header.Set("Content-Type", contentType)
+	if w.key != nil {
+		rq.Header.Set(SignatureHeader, Sign(w.key, body))
+	}
+
+	response, err := w.client.Do(rq)
+	if err != nil {
+		return err
+	}
+
+	response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("%v", response.Status)
+	}
+
+	return nil
+}
+
+func (w *Webhook) format(message uhppoted.EventMessage) ([]byte, string, error) {
+	if w.config.Format == CloudEvents {
+		e := message.Event
+		event := cloudEvent{
+			SpecVersion:     "1.0",
+			Type:            "com.github.uhppoted.event",
+			Source:          fmt.Sprintf("uhppoted/%v", e.DeviceID),
+			ID:              fmt.Sprintf("%v-%v", e.DeviceID, e.EventID),
+			Time:            time.Time(e.Timestamp).Format(time.RFC3339),
+			DataContentType: "application/json",
+			Data:            message,
+		}
+
+		bytes, err := json.Marshal(event)
+
+		return bytes, "application/cloudevents+json", err
+	}
+
+	bytes, err := json.Marshal(message)
+
+	return bytes, "application/json", err
+}
+
+// Returns the hex encoded HMAC-SHA256 signature of the body.
+func Sign(key []byte, body []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (w *Webhook) warn(err error) {
+	if w.log != nil {
+		w.log.Warn(fmt.Sprintf("%v", err), logging.Operation("webhook"))
+	}
+}
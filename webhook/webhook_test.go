@@ -0,0 +1,199 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/uhppoted/uhppote-core/types"
+	"github.com/uhppoted/uhppoted-api/uhppoted"
+)
+
+var message = uhppoted.EventMessage{
+	Event: uhppoted.ListenEvent{
+		DeviceID:   405419896,
+		EventID:    17,
+		Type:       1,
+		Granted:    true,
+		Door:       1,
+		Direction:  1,
+		CardNumber: 8165538,
+		Timestamp:  types.DateTime(time.Date(2021, time.March, 1, 12, 30, 45, 0, time.UTC)),
+		Reason:     1,
+	},
+}
+
+type server struct {
+	requests []*http.Request
+	bodies   [][]byte
+	fail     int
+	guard    sync.Mutex
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.guard.Lock()
+	defer s.guard.Unlock()
+
+	body, _ := ioutil.ReadAll(r.Body)
+
+	if s.fail > 0 {
+		s.fail--
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	s.requests = append(s.requests, r)
+	s.bodies = append(s.bodies, body)
+}
+
+func TestWebhookJSONWithHMAC(t *testing.T) {
+	s := server{fail: 2}
+	srv := httptest.NewServer(&s)
+	defer srv.Close()
+
+	key := "c2f9e6d7a1b34c58"
+	w, err := NewWebhook(Config{Endpoints: []string{srv.URL}, HMAC: key, Retries: 2, Backoff: time.Millisecond}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error creating webhook: %v", err)
+	}
+
+	if !w.Handler()(message) {
+		t.Errorf("Expected event to be queued")
+	}
+
+	w.Close()
+
+	if len(s.requests) != 1 {
+		t.Fatalf("Expected 1 successful request, got %v", len(s.requests))
+	}
+
+	rq := s.requests[0]
+	body := s.bodies[0]
+	expected := `{"event":{"device-id":405419896,"event-id":17,"event-type":1,"access-granted":true,"door-id":1,"direction":1,"card-number":8165538,"timestamp":"2021-03-01 12:30:45","event-reason":1}}`
+
+	if string(body) != expected {
+		t.Errorf("Incorrect request body\n   expected:%v\n   got:     %v", expected, string(body))
+	}
+
+	if ct := rq.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Incorrect content type - expected:%v, got:%v", "application/json", ct)
+	}
+
+	if signature := rq.Header.Get(SignatureHeader); signature != Sign([]byte{0xc2, 0xf9, 0xe6, 0xd7, 0xa1, 0xb3, 0x4c, 0x58}, body) {
+		t.Errorf("Invalid HMAC signature '%v'", signature)
+	}
+}
+
+func TestWebhookCloudEvents(t *testing.T) {
+	s := server{}
+	srv := httptest.NewServer(&s)
+	defer srv.Close()
+
+	w, err := NewWebhook(Config{Endpoints: []string{srv.URL, srv.URL}, Format: CloudEvents}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error creating webhook: %v", err)
+	}
+
+	w.Handler()(message)
+	w.Close()
+
+	if len(s.requests) != 2 {
+		t.Fatalf("Expected 2 requests, got %v", len(s.requests))
+	}
+
+	if ct := s.requests[0].Header.Get("Content-Type"); ct != "application/cloudevents+json" {
+		t.Errorf("Incorrect content type - expected:%v, got:%v", "application/cloudevents+json", ct)
+	}
+
+	if signature := s.requests[0].Header.Get(SignatureHeader); signature != "" {
+		t.Errorf("Expected unsigned request, got signature '%v'", signature)
+	}
+
+	event := cloudEvent{}
+	if err := json.Unmarshal(s.bodies[0], &event); err != nil {
+		t.Fatalf("Invalid CloudEvents body (%v)", err)
+	}
+
+	if event.SpecVersion != "1.0" || event.ID != "405419896-17" || event.Source != "uhppoted/405419896" || event.Time != "2021-03-01T12:30:45Z" || event.Data.Event.CardNumber != 8165538 {
+		t.Errorf("Incorrect CloudEvents event: %+v", event)
+	}
+}
+
+func TestWebhookTimeoutAndQueue(t *testing.T) {
+	blocked := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-blocked
+	}))
+
+	defer srv.Close()
+	defer close(blocked)
+
+	w, err := NewWebhook(Config{Endpoints: []string{srv.URL}, Timeout: 50 * time.Millisecond, QueueSize: 1}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error creating webhook: %v", err)
+	}
+
+	handler := w.Handler()
+	queued := 0
+	for i := 0; i < 5; i++ {
+		if handler(message) {
+			queued++
+		}
+	}
+
+	if queued < 1 || queued > 2 {
+		t.Errorf("Expected bounded queue to accept 1 or 2 events, accepted %v", queued)
+	}
+
+	if w.Dropped() != uint64(5-queued) {
+		t.Errorf("Incorrect dropped count - expected:%v, got:%v", 5-queued, w.Dropped())
+	}
+
+	start := time.Now()
+	w.Close()
+
+	if dt := time.Since(start); dt > 2*time.Second {
+		t.Errorf("Request timeout not applied (%v)", dt)
+	}
+}
+
+func TestWebhookDeadLetters(t *testing.T) {
+	s := server{fail: 10}
+	srv := httptest.NewServer(&s)
+	defer srv.Close()
+
+	file := filepath.Join(t.TempDir(), "dlq.json")
+	dlq, err := uhppoted.NewDeadLetterQueue(file)
+	if err != nil {
+		t.Fatalf("Unexpected error creating dead-letter queue: %v", err)
+	}
+
+	w, err := NewWebhook(Config{Endpoints: []string{srv.URL}, Retries: 1, Backoff: time.Millisecond, DLQ: dlq}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error creating webhook: %v", err)
+	}
+
+	if !w.Handler()(message) {
+		t.Errorf("Expected event to be queued")
+	}
+
+	w.Close()
+
+	if dlq, err = uhppoted.NewDeadLetterQueue(file); err != nil {
+		t.Fatalf("Unexpected error reloading dead-letter queue: %v", err)
+	}
+
+	letters := dlq.List()
+	if len(letters) != 1 {
+		t.Fatalf("Incorrect number of dead letters - expected:%v, got:%v", 1, len(letters))
+	}
+
+	if letters[0].Subscriber != "webhook:"+srv.URL || letters[0].Attempts != 2 || letters[0].Event.Event.EventID != 17 {
+		t.Errorf("Incorrect dead letter - got:%+v", letters[0])
+	}
+}